@@ -5,9 +5,17 @@ import (
 	"net/http"
 	"os"
 
+	"auth-service/internal/apispec"
+	"auth-service/internal/config"
 	"auth-service/internal/handlers"
-
-	"github.com/gorilla/mux"
+	"auth-service/internal/keys"
+	"auth-service/internal/tokens"
+	"blog/pkg/apierror"
+	"blog/pkg/health"
+	"blog/pkg/metrics"
+	"blog/pkg/openapi"
+	"blog/pkg/server"
+	"blog/pkg/serviceauth"
 )
 
 func loggingMiddleware(next http.Handler) http.Handler {
@@ -23,31 +31,95 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func readyHandler(w http.ResponseWriter, r *http.Request) {
-	// Readiness Probe: сервис готов обслуживать запросы
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Ready"))
+// loadKeyRing загружает ключи подписи из каталога JWT_KEYS_DIR.
+// Временный ключ в памяти используется, только если это явно разрешено JWT_EPHEMERAL_KEY.
+func loadKeyRing(cfg config.JWT) (*keys.KeyRing, error) {
+	if cfg.EphemeralKey {
+		log.Println("WARNING: JWT_EPHEMERAL_KEY is set, signing tokens with an in-memory key. " +
+			"Tokens become invalid on restart and are not accepted by other instances; never use this in production")
+		return keys.Ephemeral()
+	}
+	return keys.Load(cfg.KeysDir, cfg.ActiveKID)
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
 
 func main() {
-	r := mux.NewRouter()
+	// Генерация нового ключа для ротации: auth-service keygen <dir>
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		dir := getEnv("JWT_KEYS_DIR", ".")
+		if len(os.Args) > 2 {
+			dir = os.Args[2]
+		}
+		kid, err := keys.Generate(dir)
+		if err != nil {
+			log.Fatalf("Failed to generate key: %v", err)
+		}
+		log.Printf("Generated signing key %s in %s", kid, dir)
+		return
+	}
 
-	// Добавляем middleware для логирования
-	r.Use(loggingMiddleware)
+	// Настройки проверяются при старте: без обязательных значений сервис не запускается
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	log.Printf("Effective config:\n%s", cfg)
 
-	// Endpoints для Probes
-	r.HandleFunc("/health", healthHandler).Methods("GET")
-	r.HandleFunc("/ready", readyHandler).Methods("GET")
+	// OpenAPI-документ проверяется при старте: сервис с битым документом не запускается
+	doc, err := apispec.Load()
+	if err != nil {
+		log.Fatalf("Failed to load OpenAPI document: %v", err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		log.Fatalf("Failed to build OpenAPI validator: %v", err)
+	}
+	validator.ValidateResponses = cfg.OpenAPI.ValidateResponses
 
-	// Auth Endpoints
-	r.HandleFunc("/login", handlers.Login()).Methods("POST")
-	r.HandleFunc("/register", handlers.RegisterUser()).Methods("POST")
+	ring, err := loadKeyRing(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	log.Printf("Signing tokens with key %s", ring.ActiveKID())
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8081"
+	tokenService := &tokens.Service{
+		Keys:     ring,
+		Issuer:   cfg.JWT.Issuer,
+		Audience: cfg.JWT.Audience,
 	}
 
-	log.Printf("Auth Service running on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	// Подпись запросов к другим сервисам; исходящие запросы попадают в метрики
+	serviceKeys, err := serviceauth.ParseKeyRing("auth-service", cfg.ServiceAuth.Keys, cfg.ServiceAuth.ActiveKeyID)
+	if err != nil {
+		log.Fatalf("Failed to load service keys: %v", err)
+	}
+	serviceClient := serviceauth.NewClient(serviceKeys, metrics.Transport(nil))
+
+	// Readiness Probe: при остановке /ready первым начинает отвечать 503.
+	// Без users_service вход и регистрация невозможны, поэтому он критичная зависимость.
+	ready := &server.Readiness{Checks: health.NewChecker(
+		health.Check{Name: "users_service", Critical: true, Run: health.HTTP(http.DefaultClient, cfg.UsersServiceURL+"/health")},
+	)}
+	r := routes{
+		spec:   openapi.Handler(doc),
+		ready:  ready,
+		keys:   ring,
+		tokens: tokenService,
+		users:  handlers.UsersService{URL: cfg.UsersServiceURL, Client: serviceClient},
+	}.router()
+
+	// Метрики запросов считаются снаружи, чтобы в них попадали и отклонённые проверкой OpenAPI
+	handler := metrics.Middleware(metrics.MuxRoute(r), apierror.WithRequestID(validator.Middleware(r)))
+	srv := server.New(":"+cfg.Port, handler, cfg.Server)
+	log.Printf("Auth Service running on port %s", cfg.Port)
+	if err := server.Run(srv, ready, cfg.Server); err != nil {
+		log.Fatalf("Auth Service stopped with error: %v", err)
+	}
+	log.Println("Auth Service stopped")
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+
+	"auth-service/internal/handlers"
+	"auth-service/internal/keys"
+	"auth-service/internal/middlewares"
+	"auth-service/internal/tokens"
+	"blog/pkg/apierror"
+	"blog/pkg/metrics"
+	"blog/pkg/server"
+
+	"github.com/gorilla/mux"
+)
+
+// routes — зависимости обработчиков сервиса
+type routes struct {
+	// spec отдаёт OpenAPI-документ
+	spec   http.Handler
+	ready  *server.Readiness
+	keys   *keys.KeyRing
+	tokens *tokens.Service
+	users  handlers.UsersService
+}
+
+// router регистрирует все маршруты сервиса. routes_test.go сверяет их с OpenAPI-документом.
+func (d routes) router() *mux.Router {
+	r := mux.NewRouter()
+
+	// Добавляем middleware для логирования
+	r.Use(loggingMiddleware)
+	// Ошибки маршрутизации тоже в формате problem+json
+	r.NotFoundHandler = apierror.NotFoundHandler()
+	r.MethodNotAllowedHandler = apierror.MethodNotAllowedHandler()
+
+	// Endpoints для Probes
+	r.HandleFunc("/health", healthHandler).Methods("GET")
+	r.HandleFunc("/ready", d.ready.Handler()).Methods("GET")
+	r.HandleFunc("/health/details", d.ready.DetailsHandler()).Methods("GET")
+	r.Handle("/openapi.json", d.spec).Methods("GET")
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Публичные ключи для проверки JWT
+	r.HandleFunc("/.well-known/jwks.json", handlers.JWKS(d.keys)).Methods("GET")
+
+	// Auth Endpoints
+	r.HandleFunc("/login", handlers.Login(d.tokens, d.users)).Methods("POST")
+	r.HandleFunc("/register", handlers.RegisterUser(d.users)).Methods("POST")
+	r.HandleFunc("/login/2fa", handlers.LoginTwoFactor(d.tokens, d.users)).Methods("POST")
+
+	// Подключение двухфакторной аутентификации (требует JWT)
+	twoFactor := r.PathPrefix("/2fa").Subrouter()
+	twoFactor.Use(middlewares.AuthMiddleware(d.tokens))
+	twoFactor.HandleFunc("/enroll", handlers.EnrollTwoFactor(d.users)).Methods("POST")
+	twoFactor.HandleFunc("/confirm", handlers.ConfirmTwoFactor(d.users)).Methods("POST")
+
+	// Персональные токены доступа (требует JWT)
+	accessTokens := r.PathPrefix("/tokens").Subrouter()
+	accessTokens.Use(middlewares.AuthMiddleware(d.tokens))
+	accessTokens.HandleFunc("", handlers.CreateAccessToken(d.users)).Methods("POST")
+	accessTokens.HandleFunc("", handlers.ListAccessTokens(d.users)).Methods("GET")
+	accessTokens.HandleFunc("/{tokenId:[0-9]+}", handlers.RevokeAccessToken(d.users)).Methods("DELETE")
+	return r
+}
@@ -0,0 +1,185 @@
+// Package config собирает настройки auth_service из окружения и необязательного YAML-файла
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog/pkg/envconfig"
+	"blog/pkg/server"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config — настройки auth_service
+type Config struct {
+	Port   string        `yaml:"port"`
+	Server server.Config `yaml:"server"`
+	// UsersServiceURL — адрес users_service, в котором проверяются учётные данные
+	UsersServiceURL string      `yaml:"users_service_url"`
+	OpenAPI         OpenAPI     `yaml:"openapi"`
+	JWT             JWT         `yaml:"jwt"`
+	ServiceAuth     ServiceAuth `yaml:"service_auth"`
+}
+
+// OpenAPI — проверка запросов и ответов по OpenAPI-документу
+type OpenAPI struct {
+	// ValidateResponses нужна в тестовых окружениях, в production она только тратит память
+	ValidateResponses bool `yaml:"validate_responses"`
+}
+
+// JWT — выпуск токенов доступа
+type JWT struct {
+	// KeysDir — каталог ключей подписи
+	KeysDir   string `yaml:"keys_dir"`
+	ActiveKID string `yaml:"active_kid"`
+	Issuer    string `yaml:"issuer"`
+	Audience  string `yaml:"audience"`
+	// EphemeralKey разрешает запуск без KeysDir с ключом в памяти. Только для локальной разработки:
+	// после перезапуска выпущенные токены становятся недействительными, а экземпляры не видят ключи друг друга.
+	EphemeralKey bool `yaml:"ephemeral_key"`
+}
+
+// ServiceAuth — ключи подписи запросов между сервисами
+type ServiceAuth struct {
+	// Keys — набор ключей вида "kid1:service1:secret1,kid2:service2:secret2"
+	Keys        string `yaml:"keys"`
+	ActiveKeyID string `yaml:"active_key_id"`
+}
+
+func defaults() *Config {
+	return &Config{
+		Port: "8081",
+		Server: server.Config{
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    64 << 10,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		JWT: JWT{
+			Issuer:   "auth-service",
+			Audience: "blog-api",
+		},
+	}
+}
+
+// Load собирает настройки: значения по умолчанию, затем YAML-файл из CONFIG_FILE, если он задан,
+// затем переменные окружения. Любую переменную KEY можно заменить на KEY_FILE с путём к файлу,
+// в котором лежит значение (так передаются секреты Docker и Kubernetes).
+func Load() (*Config, error) {
+	cfg := defaults()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, fmt.Errorf("invalid auth-service config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) applyEnv() error {
+	var errs []error
+	for key, dst := range map[string]*string{
+		"PORT":                  &c.Port,
+		"USERS_SERVICE_URL":     &c.UsersServiceURL,
+		"JWT_KEYS_DIR":          &c.JWT.KeysDir,
+		"JWT_ACTIVE_KID":        &c.JWT.ActiveKID,
+		"JWT_ISSUER":            &c.JWT.Issuer,
+		"JWT_AUDIENCE":          &c.JWT.Audience,
+		"SERVICE_KEYS":          &c.ServiceAuth.Keys,
+		"SERVICE_ACTIVE_KEY_ID": &c.ServiceAuth.ActiveKeyID,
+	} {
+		if err := envconfig.Lookup(key, dst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for key, dst := range map[string]*time.Duration{
+		"HTTP_READ_TIMEOUT":        &c.Server.ReadTimeout,
+		"HTTP_READ_HEADER_TIMEOUT": &c.Server.ReadHeaderTimeout,
+		"HTTP_WRITE_TIMEOUT":       &c.Server.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        &c.Server.IdleTimeout,
+		"SHUTDOWN_DRAIN_DELAY":     &c.Server.DrainDelay,
+		"SHUTDOWN_TIMEOUT":         &c.Server.ShutdownTimeout,
+	} {
+		if err := envconfig.LookupDuration(key, dst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := envconfig.LookupInt("HTTP_MAX_HEADER_BYTES", &c.Server.MaxHeaderBytes); err != nil {
+		errs = append(errs, err)
+	}
+	if err := envconfig.LookupBool("OPENAPI_VALIDATE_RESPONSES", &c.OpenAPI.ValidateResponses); err != nil {
+		errs = append(errs, err)
+	}
+	if err := envconfig.LookupBool("JWT_EPHEMERAL_KEY", &c.JWT.EphemeralKey); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT: %q is not a valid port", c.Port))
+	}
+	errs = append(errs, c.Server.Validate()...)
+	c.UsersServiceURL = strings.TrimRight(c.UsersServiceURL, "/")
+	if err := envconfig.CheckURL("USERS_SERVICE_URL", c.UsersServiceURL); err != nil {
+		errs = append(errs, err)
+	}
+	if c.JWT.Issuer == "" {
+		errs = append(errs, errors.New("JWT_ISSUER is required"))
+	}
+	if c.JWT.Audience == "" {
+		errs = append(errs, errors.New("JWT_AUDIENCE is required"))
+	}
+	switch {
+	case c.JWT.EphemeralKey && c.JWT.KeysDir != "":
+		errs = append(errs, errors.New("JWT_EPHEMERAL_KEY and JWT_KEYS_DIR are mutually exclusive"))
+	case c.JWT.EphemeralKey && c.JWT.ActiveKID != "":
+		errs = append(errs, errors.New("JWT_ACTIVE_KID requires JWT_KEYS_DIR"))
+	case !c.JWT.EphemeralKey && c.JWT.KeysDir == "":
+		errs = append(errs, errors.New("JWT_KEYS_DIR is required (set JWT_EPHEMERAL_KEY=true for local development only)"))
+	}
+	if c.ServiceAuth.Keys == "" {
+		errs = append(errs, errors.New("SERVICE_KEYS is required"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid auth-service config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Redacted возвращает копию настроек со скрытыми секретами
+func (c Config) Redacted() Config {
+	c.UsersServiceURL = envconfig.RedactURL(c.UsersServiceURL)
+	c.ServiceAuth.Keys = envconfig.Redact(c.ServiceAuth.Keys)
+	return c
+}
+
+// String возвращает действующие настройки в YAML без секретов, чтобы их можно было вывести в лог при старте
+func (c Config) String() string {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("failed to encode config: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"auth-service/internal/apispec"
+	"blog/pkg/openapi"
+)
+
+// serve пропускает запрос через проверку OpenAPI-документа так же, как cmd/main.go,
+// и дополнительно проверяет ответ: расхождение обработчика с документом роняет тест кодом 500
+func serve(t *testing.T, handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	doc, err := apispec.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+
+	if req.ContentLength > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	validator.Middleware(handler).ServeHTTP(w, req)
+	return w
+}
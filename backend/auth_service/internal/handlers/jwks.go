@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"auth-service/internal/keys"
+)
+
+// JWKS публикует публичные ключи для проверки JWT другими сервисами
+func JWKS(ring *keys.KeyRing) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(ring.JWKS())
+	}
+}
@@ -1,15 +1,17 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"os"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"auth-service/internal/metrics"
+	"auth-service/internal/tokens"
+	"blog/pkg/apierror"
+
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // LoginRequest представляет данные для запроса входа
@@ -18,8 +20,8 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
-// Login обрабатывает вход пользователя
-func Login() http.HandlerFunc {
+// Login обрабатывает вход пользователя; учётные данные проверяет users_service
+func Login(tokenService *tokens.Service, users UsersService) http.HandlerFunc {
 	// Инициализация логгера
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
@@ -31,89 +33,96 @@ func Login() http.HandlerFunc {
 		var req LoginRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			logger.WithError(err).Warn("Auth-Service: Invalid request payload")
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid request payload")
 			return
 		}
 
 		if req.Email == "" || req.Password == "" {
 			logger.Warn("Auth-Service: Email and password are required")
-			http.Error(w, "Email and password are required", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Email and password are required")
 			return
 		}
 
-		// Получение URL сервиса пользователей
-		userServiceURL := os.Getenv("USERS_SERVICE_URL")
-		if userServiceURL == "" {
-			logger.Error("Auth-Service: Users service URL is not configured")
-			http.Error(w, "Users service URL is not configured", http.StatusInternalServerError)
+		// Проверка учётных данных в сервисе пользователей: хэш пароля не покидает users_service
+		reqBody, err := json.Marshal(req)
+		if err != nil {
+			logger.WithError(err).Error("Auth-Service: Failed to encode request payload")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to encode request")
 			return
 		}
 
-		// Запрос к сервису пользователей
-		resp, err := http.Get(fmt.Sprintf("%s/api/users/by_email?email=%s", userServiceURL, req.Email))
+		resp, err := users.Client.Post(users.URL+"/internal/credentials/verify", "application/json", bytes.NewReader(reqBody))
 		if err != nil {
 			logger.WithFields(logrus.Fields{
 				"email":       req.Email,
-				"service_url": userServiceURL,
+				"service_url": users.URL,
 				"error":       err.Error(),
-			}).Error("Auth-Service: Error during user fetch")
-			http.Error(w, "Invalid email or password", http.StatusForbidden)
+			}).Error("Auth-Service: Error during credentials verification")
+			metrics.Logins.WithLabelValues("error").Inc()
+			apierror.Write(w, r, http.StatusForbidden, apierror.CodeInvalidCredentials, "Invalid email or password")
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			logger.WithField("status_code", resp.StatusCode).Warn("Auth-Service: Invalid email or password")
-			http.Error(w, "Invalid email or password", http.StatusForbidden)
+			logger.WithFields(logrus.Fields{
+				"email":       req.Email,
+				"status_code": resp.StatusCode,
+			}).Warn("Auth-Service: Invalid email or password")
+			metrics.Logins.WithLabelValues("failure").Inc()
+			apierror.Write(w, r, http.StatusForbidden, apierror.CodeInvalidCredentials, "Invalid email or password")
 			return
 		}
 
 		var user struct {
-			ID           int    `json:"id"`
-			Username     string `json:"username"`
-			Email        string `json:"email"`
-			PasswordHash string `json:"password_hash"`
+			ID          int    `json:"id"`
+			Username    string `json:"username"`
+			Email       string `json:"email"`
+			Role        string `json:"role"`
+			TOTPEnabled bool   `json:"totp_enabled"`
 		}
 		if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
 			logger.WithError(err).Error("Auth-Service: Failed to parse user data")
-			http.Error(w, "Failed to parse user data", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to parse user data")
 			return
 		}
 
-		// Проверка пароля
-		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-			logger.WithField("email", req.Email).Warn("Auth-Service: Invalid email or password")
-			http.Error(w, "Invalid email or password", http.StatusForbidden)
+		// Включена двухфакторная аутентификация: выдаём токен второго шага вместо JWT
+		if user.TOTPEnabled {
+			challenge, err := tokenService.IssueChallenge(user.ID, user.Username, user.Email, user.Role)
+			if err != nil {
+				logger.WithError(err).Error("Auth-Service: Failed to generate challenge token")
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to generate token")
+				return
+			}
+
+			logger.WithField("user_id", user.ID).Info("Auth-Service: Two-factor authentication required")
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message":             "Two-factor authentication required",
+				"two_factor_required": true,
+				"challenge_token":     challenge,
+				"expires_in":          int(tokens.ChallengeTokenTTL.Seconds()),
+			})
 			return
 		}
 
 		// Генерация JWT токена
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-			"user_id": user.ID,
-			"email":   user.Email,
-			"exp":     time.Now().Add(72 * time.Hour).Unix(),
-		})
-
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			logger.Error("Auth-Service: JWT_SECRET is not configured")
-			http.Error(w, "JWT_SECRET is not configured", http.StatusInternalServerError)
-			return
-		}
-
-		tokenStr, err := token.SignedString([]byte(secret))
+		tokenStr, err := tokenService.IssueAccess(user.ID, user.Email, user.Role)
 		if err != nil {
 			logger.WithError(err).Error("Auth-Service: Failed to generate token")
-			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to generate token")
 			return
 		}
 
 		// Успешный ответ
+		metrics.Logins.WithLabelValues("success").Inc()
 		logger.WithFields(logrus.Fields{
 			"user_id":   user.ID,
 			"username":  user.Username,
 			"email":     user.Email,
-			"token_exp": time.Now().Add(72 * time.Hour).Format(time.RFC3339),
+			"token_exp": time.Now().Add(tokens.AccessTokenTTL).Format(time.RFC3339),
 		}).Info("Auth-Service: Login successful")
 
 		w.Header().Set("Content-Type", "application/json")
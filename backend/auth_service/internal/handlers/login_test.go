@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"auth-service/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLoginCountsUnavailableUsersService(t *testing.T) {
+	// Сервер сразу закрывается: запрос к users_service завершится ошибкой соединения
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	before := testutil.ToFloat64(metrics.Logins.WithLabelValues("error"))
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"alice@example.com","password":"correct-horse"}`))
+	w := serve(t, Login(nil, UsersService{URL: upstream.URL, Client: upstream.Client()}), req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("ожидался код %d, получен %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+	if got := testutil.ToFloat64(metrics.Logins.WithLabelValues("error")) - before; got != 1 {
+		t.Errorf("ошибка соединения с users_service должна учитываться в blog_logins_total{result=\"error\"}, прибавилось %v", got)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"auth-service/internal/middlewares"
+	"blog/pkg/apierror"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// UsersService — адрес users_service и клиент, подписывающий запросы к нему
+type UsersService struct {
+	URL    string
+	Client *http.Client
+}
+
+// proxyForUser перенаправляет запрос текущего пользователя в users_service.
+// path строит путь в users_service по ID пользователя из JWT и переменным маршрута.
+func proxyForUser(users UsersService, path func(userID int, vars map[string]string) string) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(os.Stdout)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(int)
+		if !ok {
+			apierror.Error(w, r, http.StatusUnauthorized, "User not authorized")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		token, _ := r.Context().Value(middlewares.TokenKey).(string)
+		target := path(userID, mux.Vars(r))
+		status, respBody, err := callUsersService(users, r.Method, target, body, token)
+		if err != nil {
+			logger.WithError(err).Error("Auth-Service: Failed to reach users service")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to process request")
+			return
+		}
+
+		logger.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"method":      r.Method,
+			"path":        target,
+			"status_code": status,
+		}).Info("Auth-Service: Request forwarded to users service")
+
+		// Ошибки users_service отдаются в формате problem+json с идентификатором этого запроса
+		if status >= 400 {
+			apierror.Relay(w, r, status, respBody, http.StatusText(status))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(respBody)
+	}
+}
+
+// callUsersService выполняет запрос к users_service и возвращает статус и тело ответа
+func callUsersService(users UsersService, method, path string, body []byte, token string) (int, []byte, error) {
+	req, err := http.NewRequest(method, users.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := users.Client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to call users service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read users service response: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
@@ -3,11 +3,13 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
-	"log"
+	"io"
 	"net/http"
 	"os"
 	"strings"
 
+	"blog/pkg/apierror"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,8 +20,8 @@ type RegisterRequest struct {
 	Password string `json:"password"`
 }
 
-// RegisterUser обрабатывает регистрацию пользователя
-func RegisterUser() http.HandlerFunc {
+// RegisterUser обрабатывает регистрацию пользователя в users_service
+func RegisterUser(users UsersService) http.HandlerFunc {
 	// Инициализация логгера
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
@@ -29,7 +31,7 @@ func RegisterUser() http.HandlerFunc {
 		var req RegisterRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			logger.WithError(err).Warn("Auth-Service: Invalid request payload")
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request payload")
 			return
 		}
 
@@ -38,19 +40,20 @@ func RegisterUser() http.HandlerFunc {
 		req.Email = strings.TrimSpace(req.Email)
 		req.Password = strings.TrimSpace(req.Password)
 
-		// Проверка обязательных полей
-		if req.Username == "" || req.Email == "" || req.Password == "" {
-			logger.Warn("Auth-Service: Missing required fields")
-			http.Error(w, "All fields are required", http.StatusBadRequest)
-			return
+		// Проверка обязательных полей; остальные правила проверяет users_service
+		var missing []apierror.FieldError
+		for _, f := range []struct{ field, value string }{
+			{"username", req.Username},
+			{"email", req.Email},
+			{"password", req.Password},
+		} {
+			if f.value == "" {
+				missing = append(missing, apierror.FieldError{Field: f.field, Code: "required", Message: f.field + " is required"})
+			}
 		}
-
-		// Получение URL сервиса пользователей
-		userServiceURL := os.Getenv("USERS_SERVICE_URL")
-
-		if userServiceURL == "" {
-			logger.Error("Auth-Service: Users service URL is not configured")
-			http.Error(w, "Users service URL is not configured", http.StatusInternalServerError)
+		if len(missing) > 0 {
+			logger.Warn("Auth-Service: Missing required fields")
+			apierror.Validation(w, r, missing)
 			return
 		}
 
@@ -58,46 +61,47 @@ func RegisterUser() http.HandlerFunc {
 		reqBody, err := json.Marshal(req)
 		if err != nil {
 			logger.WithError(err).Error("Auth-Service: Failed to encode request payload")
-			http.Error(w, "Failed to encode request", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to encode request")
 			return
 		}
 
 		// Выполнение запроса к сервису пользователей
-		resp, err := http.Post(userServiceURL+"/api/users/register", "application/json", bytes.NewReader(reqBody))
-		log.Printf(userServiceURL + "/api/users/register")
+		resp, err := users.Client.Post(users.URL+"/api/users/register", "application/json", bytes.NewReader(reqBody))
 		if err != nil {
 			logger.WithFields(logrus.Fields{
-				"service_url": userServiceURL,
+				"service_url": users.URL,
 				"error":       err.Error(),
 			}).Error("Auth-Service: Failed to register user")
-			http.Error(w, "Failed to register user", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusBadGateway, apierror.CodeUpstream, "Failed to register user")
 			return
 		}
 		defer resp.Body.Close()
 
-		// Обработка ответа
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			logger.WithError(err).Error("Auth-Service: Failed to read response from users service")
+			apierror.Write(w, r, http.StatusBadGateway, apierror.CodeUpstream, "Failed to register user")
+			return
+		}
+
+		// Обработка ответа: ошибки users_service пересылаются клиенту в едином формате
 		if resp.StatusCode != http.StatusCreated {
 			logger.WithField("status_code", resp.StatusCode).Warn("Auth-Service: Failed to register user")
-
-			var errResp map[string]interface{}
-			if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil {
-				http.Error(w, errResp["message"].(string), resp.StatusCode)
-			} else {
-				http.Error(w, "Failed to register user", resp.StatusCode)
-			}
+			apierror.Relay(w, r, resp.StatusCode, body, "Failed to register user")
 			return
 		}
 
 		logger.Info("Auth-Service: User successfully registered")
 
-		w.WriteHeader(http.StatusCreated)
 		var successResp map[string]string
-		if err := json.NewDecoder(resp.Body).Decode(&successResp); err != nil {
+		if err := json.Unmarshal(body, &successResp); err != nil {
 			logger.WithError(err).Error("Auth-Service: Failed to parse response from users service")
-			http.Error(w, "Failed to parse response from users service", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusBadGateway, apierror.CodeUpstream, "Failed to parse response from users service")
 			return
 		}
 
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
 		if err := json.NewEncoder(w).Encode(successResp); err != nil {
 			logger.WithError(err).Error("Auth-Service: Failed to send response to client")
 		}
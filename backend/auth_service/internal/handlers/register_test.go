@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blog/pkg/apierror"
+)
+
+// Ошибки users_service должны доходить до клиента в едином формате, даже если тело не JSON
+func TestRegisterUserRelaysUsersServiceErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		contentType  string
+		body         string
+		wantStatus   int
+		wantCode     string
+		wantFieldLen int
+	}{
+		{
+			name:         "конфликт с деталями полей",
+			status:       http.StatusConflict,
+			contentType:  "application/problem+json",
+			body:         `{"type":"urn:blog:problem:conflict","title":"Conflict","status":409,"detail":"Email is already registered","code":"conflict","fields":[{"field":"email","code":"taken","message":"Email is already registered"}]}`,
+			wantStatus:   http.StatusConflict,
+			wantCode:     apierror.CodeConflict,
+			wantFieldLen: 1,
+		},
+		{
+			name:        "текстовое тело",
+			status:      http.StatusBadRequest,
+			contentType: "text/plain",
+			body:        "All fields are required\n",
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    apierror.CodeInvalidRequest,
+		},
+		{
+			name:        "JSON без detail",
+			status:      http.StatusConflict,
+			contentType: "application/json",
+			body:        `{"detail":42}`,
+			wantStatus:  http.StatusConflict,
+			wantCode:    apierror.CodeConflict,
+		},
+		{
+			name:        "ошибка сервера",
+			status:      http.StatusInternalServerError,
+			contentType: "text/plain",
+			body:        "boom",
+			wantStatus:  http.StatusBadGateway,
+			wantCode:    apierror.CodeUpstream,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer upstream.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"username":"alice","email":"alice@example.com","password":"correct-horse"}`))
+			w := serve(t, RegisterUser(UsersService{URL: upstream.URL, Client: upstream.Client()}), req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			var resp apierror.Problem
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("ответ не в формате JSON-ошибки: %v", err)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("ожидался код ошибки %q, получен %q", tt.wantCode, resp.Code)
+			}
+			if len(resp.Fields) != tt.wantFieldLen {
+				t.Errorf("ожидалось полей: %d, получено %+v", tt.wantFieldLen, resp.Fields)
+			}
+		})
+	}
+}
+
+func TestRegisterUserRequiresFields(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"username":"alice"}`))
+	w := httptest.NewRecorder()
+	RegisterUser(UsersService{URL: "http://users-service", Client: http.DefaultClient})(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("ожидался код %d, получен %d", http.StatusBadRequest, w.Code)
+	}
+	var resp apierror.Problem
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != apierror.CodeValidationFailed || len(resp.Fields) != 2 {
+		t.Errorf("ожидались ошибки для email и password, получено %+v", resp)
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// CreateAccessToken создаёт персональный токен доступа текущего пользователя
+func CreateAccessToken(users UsersService) http.HandlerFunc {
+	return proxyForUser(users, func(userID int, _ map[string]string) string {
+		return fmt.Sprintf("/api/users/%d/tokens", userID)
+	})
+}
+
+// ListAccessTokens возвращает персональные токены текущего пользователя
+func ListAccessTokens(users UsersService) http.HandlerFunc {
+	return proxyForUser(users, func(userID int, _ map[string]string) string {
+		return fmt.Sprintf("/api/users/%d/tokens", userID)
+	})
+}
+
+// RevokeAccessToken отзывает персональный токен текущего пользователя
+func RevokeAccessToken(users UsersService) http.HandlerFunc {
+	return proxyForUser(users, func(userID int, vars map[string]string) string {
+		return fmt.Sprintf("/api/users/%d/tokens/%s", userID, vars["tokenId"])
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"auth-service/internal/metrics"
+	"auth-service/internal/tokens"
+	"blog/pkg/apierror"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LoginTwoFactorRequest представляет второй шаг входа
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorCodeRequest представляет запрос с одноразовым кодом
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// LoginTwoFactor обменивает токен второго шага и код TOTP (или код восстановления) на JWT.
+// Неверные коды считает users_service: после серии ошибок он блокирует проверку дольше,
+// чем живёт токен второго шага, и вход приходится начинать заново.
+func LoginTwoFactor(tokenService *tokens.Service, users UsersService) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(os.Stdout)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req LoginTwoFactorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithError(err).Warn("Auth-Service: Invalid request payload")
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if req.ChallengeToken == "" || req.Code == "" {
+			logger.Warn("Auth-Service: Challenge token and code are required")
+			apierror.Error(w, r, http.StatusBadRequest, "Challenge token and code are required")
+			return
+		}
+
+		userID, claims, err := tokenService.ParseChallenge(req.ChallengeToken)
+		if err != nil {
+			logger.WithError(err).Warn("Auth-Service: Invalid challenge token")
+			apierror.Error(w, r, http.StatusUnauthorized, "Invalid or expired challenge token")
+			return
+		}
+
+		body, _ := json.Marshal(TwoFactorCodeRequest{Code: req.Code})
+		status, _, err := callUsersService(users, http.MethodPost, fmt.Sprintf("/internal/users/%d/totp/verify", userID), body, "")
+		if err != nil {
+			logger.WithError(err).Error("Auth-Service: Failed to verify second factor")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to verify code")
+			return
+		}
+		if status == http.StatusTooManyRequests {
+			logger.WithField("user_id", userID).Warn("Auth-Service: Second factor verification is locked")
+			metrics.Logins.WithLabelValues("failure").Inc()
+			apierror.Error(w, r, http.StatusTooManyRequests, "Too many invalid codes, sign in again later")
+			return
+		}
+		if status != http.StatusOK {
+			logger.WithFields(logrus.Fields{
+				"user_id":     userID,
+				"status_code": status,
+			}).Warn("Auth-Service: Invalid second factor")
+			metrics.Logins.WithLabelValues("failure").Inc()
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid code")
+			return
+		}
+
+		tokenStr, err := tokenService.IssueAccess(userID, claims.Email, claims.Role)
+		if err != nil {
+			logger.WithError(err).Error("Auth-Service: Failed to generate token")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to generate token")
+			return
+		}
+
+		metrics.Logins.WithLabelValues("success").Inc()
+		logger.WithField("user_id", userID).Info("Auth-Service: Two-factor login successful")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Login successful",
+			"user": map[string]interface{}{
+				"id":       userID,
+				"username": claims.Username,
+				"email":    claims.Email,
+			},
+			"token": tokenStr,
+		})
+	}
+}
+
+// EnrollTwoFactor начинает подключение 2FA для текущего пользователя
+func EnrollTwoFactor(users UsersService) http.HandlerFunc {
+	return proxyForUser(users, func(userID int, _ map[string]string) string {
+		return fmt.Sprintf("/api/users/%d/totp/enroll", userID)
+	})
+}
+
+// ConfirmTwoFactor подтверждает подключение 2FA первым кодом
+func ConfirmTwoFactor(users UsersService) http.HandlerFunc {
+	return proxyForUser(users, func(userID int, _ map[string]string) string {
+		return fmt.Sprintf("/api/users/%d/totp/confirm", userID)
+	})
+}
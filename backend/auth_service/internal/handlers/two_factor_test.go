@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"auth-service/internal/keys"
+	"auth-service/internal/tokens"
+)
+
+// fakeUsersService повторяет проверку второго фактора users_service: TOTP-код 123456,
+// одноразовые коды восстановления и блокировку после maxAttempts неверных кодов подряд
+type fakeUsersService struct {
+	maxAttempts int
+
+	mu       sync.Mutex
+	recovery map[string]bool
+	failures int
+	locked   bool
+}
+
+func (f *fakeUsersService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/internal/credentials/verify":
+		var req LoginRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Email != "alice@example.com" || req.Password != "correct-horse" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": 1, "username": "alice", "email": "alice@example.com", "role": "user", "totp_enabled": true,
+		})
+	case "/internal/users/1/totp/verify":
+		var req TwoFactorCodeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.locked {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		method := ""
+		if req.Code == "123456" {
+			method = "totp"
+		} else if unused, ok := f.recovery[req.Code]; ok && unused {
+			f.recovery[req.Code] = false
+			method = "recovery_code"
+		}
+		if method == "" {
+			f.failures++
+			if f.failures >= f.maxAttempts {
+				f.locked = true
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.failures = 0
+		json.NewEncoder(w).Encode(map[string]interface{}{"valid": true, "method": method})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestTwoFactorLoginFlow(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := keys.Generate(dir); err != nil {
+		t.Fatal(err)
+	}
+	ring, err := keys.Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenService := &tokens.Service{Keys: ring, Issuer: "auth-service", Audience: "blog-api"}
+
+	users := &fakeUsersService{maxAttempts: 3, recovery: map[string]bool{"abcd-efgh": true}}
+	upstream := httptest.NewServer(users)
+	defer upstream.Close()
+
+	login := func(t *testing.T) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"alice@example.com","password":"correct-horse"}`))
+		w := serve(t, Login(tokenService, UsersService{URL: upstream.URL, Client: upstream.Client()}), req)
+		var resp struct {
+			TwoFactorRequired bool   `json:"two_factor_required"`
+			ChallengeToken    string `json:"challenge_token"`
+			Token             string `json:"token"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusOK || !resp.TwoFactorRequired || resp.ChallengeToken == "" || resp.Token != "" {
+			t.Fatalf("ожидался токен второго шага вместо JWT: %d %+v", w.Code, resp)
+		}
+		return resp.ChallengeToken
+	}
+	secondFactor := func(t *testing.T, challenge, code string) (int, string) {
+		t.Helper()
+		body, _ := json.Marshal(LoginTwoFactorRequest{ChallengeToken: challenge, Code: code})
+		req := httptest.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(string(body)))
+		w := serve(t, LoginTwoFactor(tokenService, UsersService{URL: upstream.URL, Client: upstream.Client()}), req)
+		var resp struct {
+			Token string `json:"token"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp.Token
+	}
+
+	challenge := login(t)
+
+	// Токен второго шага не заменяет JWT
+	if _, err := tokenService.ParseAccess(challenge); err == nil {
+		t.Error("токен второго шага принят как токен доступа")
+	}
+	if status, _ := secondFactor(t, "not-a-token", "123456"); status != http.StatusUnauthorized {
+		t.Errorf("неверный токен второго шага: ожидался код 401, получен %d", status)
+	}
+
+	if status, token := secondFactor(t, challenge, "000000"); status != http.StatusUnauthorized || token != "" {
+		t.Errorf("неверный код: ожидался код 401, получен %d", status)
+	}
+
+	status, token := secondFactor(t, challenge, "abcd-efgh")
+	if status != http.StatusOK {
+		t.Fatalf("код восстановления: ожидался код 200, получен %d", status)
+	}
+	if claims, err := tokenService.ParseAccess(token); err != nil || claims.UserID != 1 {
+		t.Errorf("выдан недействительный JWT: %+v, %v", claims, err)
+	}
+
+	// Код восстановления одноразовый, в том числе при новом входе
+	if status, _ := secondFactor(t, login(t), "abcd-efgh"); status != http.StatusUnauthorized {
+		t.Errorf("повторный код восстановления: ожидался код 401, получен %d", status)
+	}
+
+	// Повторный код восстановления — вторая ошибка подряд; третья блокирует проверку,
+	// и верный код с тем же токеном второго шага уже не принимается
+	challenge = login(t)
+	if status, _ := secondFactor(t, challenge, "222222"); status != http.StatusUnauthorized {
+		t.Errorf("неверный код: ожидался код 401, получен %d", status)
+	}
+	if status, _ := secondFactor(t, challenge, "111111"); status != http.StatusTooManyRequests {
+		t.Errorf("после серии неверных кодов ожидался код 429, получен %d", status)
+	}
+	if status, token := secondFactor(t, challenge, "123456"); status != http.StatusTooManyRequests || token != "" {
+		t.Errorf("во время блокировки ожидался код 429, получен %d", status)
+	}
+}
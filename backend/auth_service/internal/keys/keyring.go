@@ -0,0 +1,204 @@
+// Package keys управляет набором RSA-ключей для подписи JWT.
+//
+// Ключи хранятся в каталоге в виде PEM-файлов, имя файла без расширения
+// используется как kid. Новые токены подписываются активным ключом, а все
+// остальные ключи из каталога публикуются в JWKS и остаются пригодными для
+// проверки, пока файл не удалён. Ротация: сгенерировать новый ключ командой
+// `auth-service keygen`, перезапустить сервис (или явно указать JWT_ACTIVE_KID),
+// а старый файл удалить после истечения времени жизни выпущенных им токенов.
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// keyBits — размер генерируемых ключей
+const keyBits = 2048
+
+// ErrUnknownKey возвращается, если kid токена отсутствует в наборе ключей
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeyRing — набор ключей подписи с одним активным ключом
+type KeyRing struct {
+	keys   map[string]*rsa.PrivateKey
+	order  []string
+	active string
+}
+
+// Load загружает все ключи из каталога dir. Если activeKID пуст, активным
+// становится ключ с лексикографически наибольшим kid (ключи именуются по дате создания).
+func Load(dir, activeKID string) (*KeyRing, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.pem keys found in %s", dir)
+	}
+
+	ring := &KeyRing{keys: make(map[string]*rsa.PrivateKey)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %s: %w", path, err)
+		}
+		key, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %s: %w", path, err)
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		ring.keys[kid] = key
+		ring.order = append(ring.order, kid)
+	}
+	sort.Strings(ring.order)
+
+	ring.active = ring.order[len(ring.order)-1]
+	if activeKID != "" {
+		if _, ok := ring.keys[activeKID]; !ok {
+			return nil, fmt.Errorf("active key %q not found in %s", activeKID, dir)
+		}
+		ring.active = activeKID
+	}
+	return ring, nil
+}
+
+// Ephemeral создаёт набор из одного ключа в памяти. Используется для локальной
+// разработки: после перезапуска все выпущенные токены становятся недействительными.
+func Ephemeral() (*KeyRing, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	kid := "ephemeral-" + time.Now().UTC().Format("20060102T150405")
+	return &KeyRing{
+		keys:   map[string]*rsa.PrivateKey{kid: key},
+		order:  []string{kid},
+		active: kid,
+	}, nil
+}
+
+// Generate создаёт новый ключ в каталоге dir и возвращает его kid
+func Generate(dir string) (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	kid := time.Now().UTC().Format("20060102T150405")
+	path := filepath.Join(dir, kid+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create key file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write key: %w", err)
+	}
+	return kid, nil
+}
+
+// ActiveKID возвращает kid ключа, которым подписываются новые токены
+func (k *KeyRing) ActiveKID() string {
+	return k.active
+}
+
+// Sign подписывает claims активным ключом и проставляет kid в заголовок
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = k.active
+	return token.SignedString(k.keys[k.active])
+}
+
+// Parse проверяет подпись токена любым ключом из набора и заполняет claims
+func (k *KeyRing) Parse(tokenStr string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return &key.PublicKey, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+// JWK — публичный ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS — набор публичных ключей
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает публичные части всех ключей набора
+func (k *KeyRing) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(k.order))}
+	for _, kid := range k.order {
+		pub := k.keys[kid].PublicKey
+		set.Keys = append(set.Keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		})
+	}
+	return set
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("key is not an RSA private key")
+	}
+	return key, nil
+}
@@ -0,0 +1,14 @@
+// Package metrics содержит бизнес-метрики auth_service; метрики HTTP собирает blog/pkg/metrics
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Logins — входы по результату: success (выдан токен доступа), failure (неверный пароль или код 2FA)
+// или error (users_service недоступен, учётные данные не проверены)
+var Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "blog_logins_total",
+	Help: "Login attempts by result: success, failure or error.",
+}, []string{"result"})
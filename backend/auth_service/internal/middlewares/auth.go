@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"auth-service/internal/tokens"
+	"blog/pkg/apierror"
+)
+
+type ContextKey string
+
+const (
+	UserIDKey ContextKey = "user_id"
+	TokenKey  ContextKey = "token"
+)
+
+// AuthMiddleware проверяет основной JWT пользователя и кладёт его ID в контекст
+func AuthMiddleware(tokenService *tokens.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if tokenString == "" {
+				apierror.Error(w, r, http.StatusUnauthorized, "Authorization token missing")
+				return
+			}
+
+			claims, err := tokenService.ParseAccess(tokenString)
+			if err != nil {
+				log.Println("AuthMiddleware: Invalid token")
+				apierror.Error(w, r, http.StatusUnauthorized, "Invalid token")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, TokenKey, tokenString)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Package tokens выпускает и проверяет JWT сервиса авторизации
+package tokens
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"auth-service/internal/keys"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// AccessTokenTTL — время жизни основного JWT
+	AccessTokenTTL = 72 * time.Hour
+	// ChallengeTokenTTL — время жизни токена второго шага входа
+	ChallengeTokenTTL = 5 * time.Minute
+
+	// challengeAudienceSuffix отделяет аудиторию токена второго шага от основной,
+	// чтобы такой токен нельзя было использовать для доступа к API
+	challengeAudienceSuffix = ":2fa"
+)
+
+// ErrInvalidToken возвращается для любого токена, не прошедшего проверку
+var ErrInvalidToken = errors.New("invalid token")
+
+// Service выпускает токены, подписанные ключами из KeyRing
+type Service struct {
+	Keys     *keys.KeyRing
+	Issuer   string
+	Audience string
+}
+
+// AccessClaims — содержимое основного JWT
+type AccessClaims struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"`
+	jwt.StandardClaims
+}
+
+// ChallengeClaims — содержимое токена второго шага входа
+type ChallengeClaims struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role,omitempty"`
+	jwt.StandardClaims
+}
+
+// IssueAccess выпускает основной JWT пользователя
+func (s *Service) IssueAccess(userID int, email, role string) (string, error) {
+	now := time.Now()
+	return s.Keys.Sign(AccessClaims{
+		UserID: userID,
+		Email:  email,
+		Role:   role,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.Issuer,
+			Audience:  s.Audience,
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(AccessTokenTTL).Unix(),
+		},
+	})
+}
+
+// ParseAccess проверяет основной JWT, выпущенный этим сервисом
+func (s *Service) ParseAccess(tokenStr string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	if err := s.parse(tokenStr, claims, &claims.StandardClaims, s.Audience); err != nil {
+		return nil, err
+	}
+	if claims.UserID <= 0 {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// IssueChallenge выпускает короткоживущий токен, который обменивается на JWT вместе с кодом 2FA
+func (s *Service) IssueChallenge(userID int, username, email, role string) (string, error) {
+	now := time.Now()
+	return s.Keys.Sign(ChallengeClaims{
+		Username: username,
+		Email:    email,
+		Role:     role,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.Issuer,
+			Audience:  s.Audience + challengeAudienceSuffix,
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ChallengeTokenTTL).Unix(),
+		},
+	})
+}
+
+// ParseChallenge проверяет токен второго шага и возвращает ID пользователя и claims
+func (s *Service) ParseChallenge(tokenStr string) (int, *ChallengeClaims, error) {
+	claims := &ChallengeClaims{}
+	if err := s.parse(tokenStr, claims, &claims.StandardClaims, s.Audience+challengeAudienceSuffix); err != nil {
+		return 0, nil, err
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return 0, nil, ErrInvalidToken
+	}
+	return userID, claims, nil
+}
+
+// parse проверяет подпись, а также строго проверяет iss, aud и exp
+func (s *Service) parse(tokenStr string, claims jwt.Claims, std *jwt.StandardClaims, audience string) error {
+	if err := s.Keys.Parse(tokenStr, claims); err != nil {
+		return ErrInvalidToken
+	}
+	now := time.Now().Unix()
+	if std.Issuer != s.Issuer || std.Audience != audience || !std.VerifyExpiresAt(now, true) {
+		return ErrInvalidToken
+	}
+	return nil
+}
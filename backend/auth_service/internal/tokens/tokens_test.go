@@ -0,0 +1,102 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"auth-service/internal/keys"
+)
+
+func newService(t *testing.T, dir string) *Service {
+	t.Helper()
+	ring, err := keys.Load(dir, "")
+	if err != nil {
+		t.Fatalf("не удалось загрузить ключи: %v", err)
+	}
+	return &Service{Keys: ring, Issuer: "auth-service", Audience: "blog-api"}
+}
+
+func TestAccessTokenRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := keys.Generate(dir); err != nil {
+		t.Fatal(err)
+	}
+	svc := newService(t, dir)
+
+	token, err := svc.IssueAccess(42, "user@example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.ParseAccess(token)
+	if err != nil {
+		t.Fatalf("токен должен быть валидным: %v", err)
+	}
+	if claims.UserID != 42 || claims.Email != "user@example.com" {
+		t.Errorf("неожиданные claims: %+v", claims)
+	}
+
+	other := &Service{Keys: svc.Keys, Issuer: "auth-service", Audience: "other-api"}
+	if _, err := other.ParseAccess(token); err == nil {
+		t.Error("токен с чужой аудиторией не должен приниматься")
+	}
+}
+
+func TestChallengeTokenIsNotAccessToken(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := keys.Generate(dir); err != nil {
+		t.Fatal(err)
+	}
+	svc := newService(t, dir)
+
+	challenge, err := svc.IssueChallenge(7, "user", "user@example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ParseAccess(challenge); err == nil {
+		t.Error("токен второго шага не должен приниматься как основной JWT")
+	}
+	userID, _, err := svc.ParseChallenge(challenge)
+	if err != nil || userID != 7 {
+		t.Errorf("ParseChallenge = %d, %v", userID, err)
+	}
+}
+
+func TestRotatedKeyStaysVerifiable(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2024-01")
+	oldSvc := newService(t, dir)
+	token, err := oldSvc.IssueAccess(1, "user@example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Новый ключ с более поздним kid становится активным
+	writeKey(t, dir, "2024-02")
+	rotated := newService(t, dir)
+	if rotated.Keys.ActiveKID() != "2024-02" {
+		t.Fatalf("активным должен стать 2024-02, получен %s", rotated.Keys.ActiveKID())
+	}
+	if _, err := rotated.ParseAccess(token); err != nil {
+		t.Errorf("токен, подписанный старым ключом, должен проверяться: %v", err)
+	}
+	if n := len(rotated.Keys.JWKS().Keys); n != 2 {
+		t.Errorf("JWKS должен содержать 2 ключа, получено %d", n)
+	}
+}
+
+func writeKey(t *testing.T, dir, kid string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"blog/pkg/apierror"
+	"blog/pkg/health"
+	"blog/pkg/jwks"
+	"blog/pkg/metrics"
+	"blog/pkg/server"
+	"blog/pkg/serviceauth"
+	"gateway_service/internal/config"
+	"gateway_service/internal/gateway"
+)
+
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Received %s request for %s", r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func main() {
+	// Настройки и маршруты читаются из файла; адреса сервисов можно переопределить переменными окружения
+	cfg, err := config.Load(getEnv("GATEWAY_ROUTES", "routes.yaml"))
+	if err != nil {
+		log.Fatalf("Failed to load routes: %v", err)
+	}
+	log.Printf("Effective config:\n%s", cfg)
+
+	// Ключи для проверки JWT публикуются auth_service
+	verifier := &jwks.Verifier{
+		Keys:     jwks.NewCache(cfg.JWT.JWKSURL, 10*time.Minute),
+		Issuer:   cfg.JWT.Issuer,
+		Audience: cfg.JWT.Audience,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	}
+	// Заголовки проверенного пользователя подписываются собственным ключом шлюза
+	serviceKeys, err := serviceauth.ParseKeyRing(gateway.ServiceName, cfg.ServiceAuth.Keys, cfg.ServiceAuth.ActiveKeyID)
+	if err != nil {
+		log.Fatalf("Failed to load service keys: %v", err)
+	}
+
+	// Запросы к сервисам попадают в метрики исходящих вызовов
+	gw, err := gateway.New(cfg, verifier, serviceKeys, metrics.Transport(transport))
+	if err != nil {
+		log.Fatalf("Failed to build gateway: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	// Пробы: /health сводит состояние всех сервисов, /ready — только самого шлюза.
+	// Обе используют один кэш проверок, поэтому публичный /health не нагружает сервисы.
+	healthClient := &http.Client{Transport: transport}
+	upstreams := health.NewChecker(gateway.UpstreamChecks(cfg.Upstreams, healthClient)...)
+	mux.Handle("/health", gateway.HealthHandler(upstreams))
+	ready := &server.Readiness{Checks: upstreams}
+	mux.HandleFunc("/ready", ready.Handler())
+	mux.Handle("/", gw)
+
+	// /health/details раскрывает адреса сервисов и их ошибки, а /metrics — маршруты и нагрузку,
+	// поэтому они доступны только на служебном порту
+	internalMux := http.NewServeMux()
+	internalMux.HandleFunc("/health/details", ready.DetailsHandler())
+	internalMux.Handle("/metrics", metrics.Handler())
+	internal := server.New(":"+cfg.InternalPort, internalMux, cfg.Server)
+	internalLn, err := net.Listen("tcp", internal.Addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on internal port: %v", err)
+	}
+	go func() {
+		if err := internal.Serve(internalLn); err != nil && err != http.ErrServerClosed {
+			log.Printf("Internal server stopped with error: %v", err)
+		}
+	}()
+
+	// Запросы к самому шлюзу размечаются путём, проксируемые — префиксом маршрута
+	route := func(r *http.Request) string {
+		if _, pattern := mux.Handler(r); pattern != "" && pattern != "/" {
+			return pattern
+		}
+		return gw.Route(r)
+	}
+	handler := metrics.Middleware(route, apierror.WithRequestID(loggingMiddleware(gateway.CORS(cfg.CORS, mux))))
+	srv := server.New(":"+cfg.Port, handler, cfg.Server)
+	log.Printf("Gateway running on port %s, internal endpoints on port %s", cfg.Port, cfg.InternalPort)
+	// Служебный порт закрывается после основного, чтобы состояние было видно во время остановки
+	if err := server.Run(srv, ready, cfg.Server, server.Hook{Name: "internal server", Stop: internal.Shutdown}); err != nil {
+		log.Fatalf("Gateway stopped with error: %v", err)
+	}
+	log.Println("Gateway stopped")
+}
@@ -0,0 +1,330 @@
+// Package config читает настройки и маршруты шлюза из YAML-файла
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog/pkg/envconfig"
+	"blog/pkg/server"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Режимы проверки токена на маршруте
+const (
+	// AuthNone — заголовок Authorization передаётся как есть, токен не проверяется
+	AuthNone = "none"
+	// AuthOptional — токен проверяется, если он передан; запрос без токена пропускается анонимно
+	AuthOptional = "optional"
+	// AuthRequired — запрос без действительного токена отклоняется
+	AuthRequired = "required"
+)
+
+// Config — настройки шлюза
+type Config struct {
+	Port string `yaml:"port"`
+	// InternalPort — порт служебных ручек (/health/details, /metrics), недоступный клиентам снаружи
+	InternalPort string `yaml:"internal_port"`
+	// Server.WriteTimeout должен быть больше времени ответа сервисов за шлюзом
+	Server    server.Config       `yaml:"server"`
+	JWT       JWT                 `yaml:"jwt"`
+	CORS      CORS                `yaml:"cors"`
+	RateLimit RateLimit           `yaml:"rate_limit"`
+	Upstreams map[string]Upstream `yaml:"upstreams"`
+	Routes    []Route             `yaml:"routes"`
+	// ClientIPHeader — заголовок с цепочкой адресов (X-Forwarded-For), если перед шлюзом стоит балансировщик.
+	// Пустое значение означает адрес TCP-соединения.
+	ClientIPHeader string `yaml:"client_ip_header"`
+	// TrustedProxies — адреса и подсети балансировщиков. Заголовок ClientIPHeader учитывается,
+	// только если соединение пришло от них; адресом клиента считается самый правый недоверенный адрес цепочки.
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`
+	// ServiceAuth — ключи, которыми шлюз подписывает заголовки проверенного пользователя
+	ServiceAuth ServiceAuth `yaml:"service_auth"`
+}
+
+// JWT — проверка токенов доступа, выпущенных auth_service
+type JWT struct {
+	// JWKSURL — адрес публичных ключей; по умолчанию строится из AUTH_SERVICE_URL
+	JWKSURL  string `yaml:"jwks_url"`
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+}
+
+// ServiceAuth — общие ключи подписи запросов между сервисами
+type ServiceAuth struct {
+	// Keys — собственные ключи шлюза вида "kid:gateway-service:secret,..."
+	Keys        string `yaml:"keys"`
+	ActiveKeyID string `yaml:"active_key_id"`
+}
+
+// CORS описывает разрешённые источники браузерных запросов
+type CORS struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods"`
+	AllowedHeaders []string `yaml:"allowed_headers"`
+	MaxAge         int      `yaml:"max_age"`
+}
+
+// RateLimit — ограничение частоты запросов одного клиента (token bucket)
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+// Enabled сообщает, задано ли ограничение
+func (l RateLimit) Enabled() bool {
+	return l.RequestsPerSecond > 0
+}
+
+// Upstream — сервис за шлюзом
+type Upstream struct {
+	URL string `yaml:"url"`
+	// Health — путь проверки живости, по умолчанию /health
+	Health string `yaml:"health"`
+}
+
+// Route направляет запросы с префиксом Prefix в сервис Upstream
+type Route struct {
+	Prefix   string `yaml:"prefix"`
+	Upstream string `yaml:"upstream"`
+	// StripPrefix убирает префикс из пути перед отправкой в сервис
+	StripPrefix bool   `yaml:"strip_prefix"`
+	Auth        string `yaml:"auth"`
+	// RateLimit переопределяет общее ограничение для маршрута
+	RateLimit *RateLimit `yaml:"rate_limit,omitempty"`
+	// Deny — пути сервиса (после удаления префикса), недоступные снаружи
+	Deny []string `yaml:"deny,omitempty"`
+}
+
+// Load читает и проверяет файл маршрутов. В файле допускаются подстановки
+// ${VAR} и ${VAR:-значение по умолчанию} из переменных окружения, а PORT,
+// INTERNAL_PORT, JWKS_URL, AUTH_SERVICE_URL, JWT_ISSUER, JWT_AUDIENCE, SERVICE_KEYS
+// и SERVICE_ACTIVE_KEY_ID важнее значений из файла.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gateway config: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse разбирает и проверяет конфигурацию
+func Parse(data []byte) (*Config, error) {
+	cfg := Config{
+		Port:         "8080",
+		InternalPort: "8090",
+		Server: server.Config{
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    64 << 10,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		JWT: JWT{Issuer: "auth-service", Audience: "blog-api"},
+	}
+	if err := yaml.Unmarshal([]byte(expandEnv(string(data))), &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse gateway config: %w", err)
+	}
+	cfg.applyEnv()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) applyEnv() {
+	if value := os.Getenv("PORT"); value != "" {
+		c.Port = value
+	}
+	if value := os.Getenv("INTERNAL_PORT"); value != "" {
+		c.InternalPort = value
+	}
+	if value := os.Getenv("AUTH_SERVICE_URL"); value != "" {
+		c.JWT.JWKSURL = strings.TrimRight(value, "/") + "/.well-known/jwks.json"
+	}
+	if value := os.Getenv("JWKS_URL"); value != "" {
+		c.JWT.JWKSURL = value
+	}
+	if value := os.Getenv("JWT_ISSUER"); value != "" {
+		c.JWT.Issuer = value
+	}
+	if value := os.Getenv("JWT_AUDIENCE"); value != "" {
+		c.JWT.Audience = value
+	}
+	if value := os.Getenv("SERVICE_KEYS"); value != "" {
+		c.ServiceAuth.Keys = value
+	}
+	if value := os.Getenv("SERVICE_ACTIVE_KEY_ID"); value != "" {
+		c.ServiceAuth.ActiveKeyID = value
+	}
+}
+
+func (c *Config) validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port: %q is not a valid port", c.Port))
+	}
+	if port, err := strconv.Atoi(c.InternalPort); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("internal_port: %q is not a valid port", c.InternalPort))
+	} else if c.InternalPort == c.Port {
+		errs = append(errs, errors.New("internal_port must differ from port"))
+	}
+	for name, d := range map[string]time.Duration{
+		"read_timeout":        c.Server.ReadTimeout,
+		"read_header_timeout": c.Server.ReadHeaderTimeout,
+		"write_timeout":       c.Server.WriteTimeout,
+		"idle_timeout":        c.Server.IdleTimeout,
+		"shutdown_timeout":    c.Server.ShutdownTimeout,
+	} {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("server: %s must be positive", name))
+		}
+	}
+	if c.Server.DrainDelay < 0 {
+		errs = append(errs, errors.New("server: drain_delay must not be negative"))
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("server: max_header_bytes must be positive"))
+	}
+	if c.JWT.JWKSURL == "" {
+		errs = append(errs, errors.New("jwt: jwks_url, JWKS_URL or AUTH_SERVICE_URL is required"))
+	} else if u, err := url.Parse(c.JWT.JWKSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, errors.New("jwt: jwks_url must be an absolute http(s) URL"))
+	}
+	if c.JWT.Issuer == "" || c.JWT.Audience == "" {
+		errs = append(errs, errors.New("jwt: issuer and audience are required"))
+	}
+	if c.ServiceAuth.Keys == "" {
+		errs = append(errs, errors.New("service_auth: keys or SERVICE_KEYS is required"))
+	}
+
+	if len(c.Upstreams) == 0 {
+		errs = append(errs, errors.New("no upstreams configured"))
+	}
+	for name, up := range c.Upstreams {
+		u, err := url.Parse(up.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("upstream %q: url must be an absolute http(s) URL", name))
+		}
+		if up.Health == "" {
+			up.Health = "/health"
+			c.Upstreams[name] = up
+		}
+	}
+
+	if len(c.Routes) == 0 {
+		errs = append(errs, errors.New("no routes configured"))
+	}
+	seen := make(map[string]bool)
+	for i := range c.Routes {
+		route := &c.Routes[i]
+		route.Prefix = "/" + strings.Trim(route.Prefix, "/")
+		if seen[route.Prefix] {
+			errs = append(errs, fmt.Errorf("route %s: duplicate prefix", route.Prefix))
+		}
+		seen[route.Prefix] = true
+
+		if _, ok := c.Upstreams[route.Upstream]; !ok {
+			errs = append(errs, fmt.Errorf("route %s: unknown upstream %q", route.Prefix, route.Upstream))
+		}
+		switch route.Auth {
+		case "":
+			route.Auth = AuthOptional
+		case AuthNone, AuthOptional, AuthRequired:
+		default:
+			errs = append(errs, fmt.Errorf("route %s: auth must be none, optional or required", route.Prefix))
+		}
+		if route.RateLimit != nil && route.RateLimit.RequestsPerSecond < 0 {
+			errs = append(errs, fmt.Errorf("route %s: requests_per_second must not be negative", route.Prefix))
+		}
+	}
+	if c.RateLimit.RequestsPerSecond < 0 {
+		errs = append(errs, errors.New("rate_limit: requests_per_second must not be negative"))
+	}
+	if _, err := c.ParseTrustedProxies(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.ClientIPHeader != "" && len(c.TrustedProxies) == 0 {
+		errs = append(errs, errors.New("client_ip_header requires trusted_proxies"))
+	}
+
+	// Более длинные префиксы проверяются первыми
+	sort.SliceStable(c.Routes, func(i, j int) bool {
+		return len(c.Routes[i].Prefix) > len(c.Routes[j].Prefix)
+	})
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid gateway config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// LimitFor возвращает ограничение частоты для маршрута
+func (c *Config) LimitFor(route Route) RateLimit {
+	if route.RateLimit != nil {
+		return *route.RateLimit
+	}
+	return c.RateLimit
+}
+
+// ParseTrustedProxies разбирает TrustedProxies; отдельный адрес считается подсетью из одного адреса
+func (c *Config) ParseTrustedProxies() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, value := range c.TrustedProxies {
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("trusted_proxies: %q is not a valid address or CIDR", value)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("trusted_proxies: %q is not a valid address or CIDR", value)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// String возвращает действующие настройки в YAML, чтобы их можно было вывести в лог при старте.
+// Пароли в адресах сервисов и ключи подписи скрываются.
+func (c Config) String() string {
+	c.JWT.JWKSURL = envconfig.RedactURL(c.JWT.JWKSURL)
+	c.ServiceAuth.Keys = envconfig.Redact(c.ServiceAuth.Keys)
+	upstreams := make(map[string]Upstream, len(c.Upstreams))
+	for name, up := range c.Upstreams {
+		up.URL = envconfig.RedactURL(up.URL)
+		upstreams[name] = up
+	}
+	c.Upstreams = upstreams
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("failed to encode config: %v", err)
+	}
+	return string(data)
+}
+
+// expandEnv подставляет переменные окружения, поддерживая значения по умолчанию
+func expandEnv(s string) string {
+	return os.Expand(s, func(key string) string {
+		name, fallback, _ := strings.Cut(key, ":-")
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return fallback
+	})
+}
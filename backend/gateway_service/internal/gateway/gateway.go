@@ -0,0 +1,266 @@
+// Package gateway маршрутизирует запросы клиентов к сервисам по префиксу пути
+// и проверяет JWT на входе. Данные проверенного пользователя передаются сервисам
+// в заголовках, подписанных ключами serviceauth; сам токен тоже передаётся как есть.
+package gateway
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog/pkg/apierror"
+	"blog/pkg/metrics"
+	"blog/pkg/serviceauth"
+	"gateway_service/internal/config"
+	"gateway_service/internal/middlewares"
+)
+
+// ServiceName — имя шлюза в подписи заголовков пользователя
+const ServiceName = "gateway-service"
+
+// Заголовки с данными о пользователе. Шлюз выставляет их по проверенному JWT и подписывает
+// (serviceauth.KeyRing.VerifyIdentity), а одноимённые заголовки из запроса клиента удаляет.
+const (
+	UserIDHeader   = serviceauth.HeaderUserID
+	UserRoleHeader = serviceauth.HeaderUserRole
+)
+
+type contextKey struct{}
+
+// identity — пользователь, чей JWT проверил шлюз
+type identity struct {
+	userID int
+	role   string
+}
+
+// Verifier проверяет JWT и возвращает ID и роль пользователя
+type Verifier interface {
+	Verify(token string) (int, string, error)
+}
+
+// route — маршрут с готовым прокси и ограничителем частоты
+type route struct {
+	config.Route
+	proxy   *httputil.ReverseProxy
+	limiter *limiter
+}
+
+// Gateway — обработчик всех запросов шлюза
+type Gateway struct {
+	routes   []*route
+	verifier Verifier
+	keys     *serviceauth.KeyRing
+	ipHeader string
+	trusted  []netip.Prefix
+}
+
+// New собирает шлюз по конфигурации. Ключами keys подписываются заголовки пользователя.
+func New(cfg *config.Config, verifier Verifier, keys *serviceauth.KeyRing, transport http.RoundTripper) (*Gateway, error) {
+	trusted, err := cfg.ParseTrustedProxies()
+	if err != nil {
+		return nil, err
+	}
+	g := &Gateway{verifier: verifier, keys: keys, ipHeader: cfg.ClientIPHeader, trusted: trusted}
+	for _, rc := range cfg.Routes {
+		target, err := url.Parse(cfg.Upstreams[rc.Upstream].URL)
+		if err != nil {
+			return nil, err
+		}
+		rt := &route{Route: rc}
+		rt.proxy = g.newProxy(rt, target, transport)
+		if limit := cfg.LimitFor(rc); limit.Enabled() {
+			rt.limiter = newLimiter(limit)
+		}
+		g.routes = append(g.routes, rt)
+	}
+	return g, nil
+}
+
+// ServeHTTP выбирает маршрут, проверяет токен и лимит и проксирует запрос
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt := g.match(r.URL.Path)
+	if rt == nil {
+		apierror.Error(w, r, http.StatusNotFound, "Route not found")
+		return
+	}
+	if rt.denied(r.URL.Path) {
+		apierror.Error(w, r, http.StatusNotFound, "Route not found")
+		return
+	}
+
+	// Заголовки личности от клиента до сервисов не доходят
+	r.Header.Del(UserIDHeader)
+	r.Header.Del(UserRoleHeader)
+	r.Header.Del(serviceauth.HeaderIdentity)
+
+	userID, role, ok := g.authenticate(w, r, rt)
+	if !ok {
+		return
+	}
+	if userID != 0 {
+		r = r.WithContext(context.WithValue(r.Context(), contextKey{}, identity{userID: userID, role: role}))
+	}
+
+	if rt.limiter != nil {
+		key := "ip:" + g.clientIP(r)
+		if userID != 0 {
+			key = "user:" + strconv.Itoa(userID)
+		}
+		if allowed, retryAfter := rt.limiter.allow(key); !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds()+0.999)))
+			apierror.Error(w, r, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		}
+	}
+
+	rt.proxy.ServeHTTP(w, r)
+}
+
+// Route возвращает префикс маршрута, которым обслуживается запрос: по нему размечаются метрики
+func (g *Gateway) Route(r *http.Request) string {
+	if rt := g.match(r.URL.Path); rt != nil && !rt.denied(r.URL.Path) {
+		return rt.Prefix
+	}
+	return metrics.Unmatched
+}
+
+// match возвращает маршрут с самым длинным подходящим префиксом
+func (g *Gateway) match(path string) *route {
+	for _, rt := range g.routes {
+		if rt.Prefix == "/" || path == rt.Prefix || strings.HasPrefix(path, rt.Prefix+"/") {
+			return rt
+		}
+	}
+	return nil
+}
+
+// authenticate проверяет токен по режиму маршрута.
+// Возвращает ID (0 для анонимного запроса) и роль пользователя и false, если ответ уже отправлен.
+func (g *Gateway) authenticate(w http.ResponseWriter, r *http.Request, rt *route) (int, string, bool) {
+	if rt.Auth == config.AuthNone {
+		return 0, "", true
+	}
+
+	header := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if header == "" || token == header || token == "" {
+		if rt.Auth == config.AuthRequired {
+			apierror.Error(w, r, http.StatusUnauthorized, "Authorization token missing")
+			return 0, "", false
+		}
+		return 0, "", true
+	}
+
+	// Персональные токены проверяет только сервис
+	if strings.HasPrefix(token, middlewares.PersonalAccessTokenPrefix) {
+		return 0, "", true
+	}
+
+	userID, role, err := g.verifier.Verify(token)
+	if err != nil {
+		log.Printf("Gateway: rejected token for %s %s: %v", r.Method, r.URL.Path, err)
+		apierror.Error(w, r, http.StatusUnauthorized, "Invalid token")
+		return 0, "", false
+	}
+	return userID, role, true
+}
+
+// clientIP возвращает адрес клиента для ограничения частоты. Левые адреса X-Forwarded-For
+// клиент может подставить сам, поэтому цепочка читается справа налево: доверенные
+// балансировщики пропускаются, первый недоверенный адрес и есть клиент.
+func (g *Gateway) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if g.ipHeader == "" || !g.isTrusted(host) {
+		return host
+	}
+	var chain []string
+	for _, value := range r.Header.Values(g.ipHeader) {
+		chain = append(chain, strings.Split(value, ",")...)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(chain[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// Мусор в цепочке: дальше влево доверять нечему
+			return host
+		}
+		host = hop
+		if !g.isTrusted(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// isTrusted сообщает, принадлежит ли адрес доверенному балансировщику
+func (g *Gateway) isTrusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range g.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamPath возвращает путь запроса в сервисе
+func (rt *route) upstreamPath(path string) string {
+	if !rt.StripPrefix || rt.Prefix == "/" {
+		return path
+	}
+	trimmed := strings.TrimPrefix(path, rt.Prefix)
+	if trimmed == "" {
+		return "/"
+	}
+	return trimmed
+}
+
+// denied сообщает, закрыт ли путь сервиса для внешних клиентов
+func (rt *route) denied(path string) bool {
+	upstream := rt.upstreamPath(path)
+	for _, prefix := range rt.Deny {
+		prefix = "/" + strings.Trim(prefix, "/")
+		if upstream == prefix || strings.HasPrefix(upstream, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// newProxy создаёт обратный прокси к сервису маршрута
+func (g *Gateway) newProxy(rt *route, target *url.URL, transport http.RoundTripper) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = strings.TrimSuffix(target.Path, "/") + rt.upstreamPath(req.URL.Path)
+			req.URL.RawPath = ""
+			req.Host = target.Host
+			// Клиентский Origin сервисам не нужен: CORS обрабатывает шлюз
+			req.Header.Del("Origin")
+			// Подпись покрывает путь сервиса, поэтому ставится после его переписывания
+			if id, ok := req.Context().Value(contextKey{}).(identity); ok {
+				g.keys.SignIdentity(req, id.userID, id.role)
+			}
+		},
+		Transport:     transport,
+		FlushInterval: 100 * time.Millisecond,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			log.Printf("Gateway: upstream %s failed for %s %s: %v", rt.Upstream, req.Method, req.URL.Path, err)
+			apierror.Error(w, req, http.StatusBadGateway, "Service "+rt.Upstream+" is unavailable")
+		},
+	}
+}
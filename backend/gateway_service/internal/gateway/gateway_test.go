@@ -0,0 +1,429 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"blog/pkg/apierror"
+	"blog/pkg/health"
+	"blog/pkg/serviceauth"
+	"gateway_service/internal/config"
+)
+
+const testServiceKeys = "k1:gateway-service:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// fakeVerifier принимает токены из карты токен -> ID пользователя
+type fakeVerifier map[string]int
+
+func (f fakeVerifier) Verify(token string) (int, string, error) {
+	if id, ok := f[token]; ok {
+		return id, "user", nil
+	}
+	return 0, "", errors.New("invalid token")
+}
+
+func testKeys(t *testing.T) *serviceauth.KeyRing {
+	t.Helper()
+	keys, err := serviceauth.ParseKeyRing(ServiceName, testServiceKeys, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// upstreamEcho отвечает путём и заголовками, которые дошли до сервиса.
+// verified — пользователь из заголовков, если их подпись верна.
+func upstreamEcho(t *testing.T) *httptest.Server {
+	t.Helper()
+	keys := testKeys(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified := ""
+		if identity, err := keys.VerifyIdentity(r); err == nil {
+			verified = fmt.Sprintf("%s:%d:%s", identity.Service, identity.UserID, identity.Role)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":          r.URL.RequestURI(),
+			"user_id":       r.Header.Get(UserIDHeader),
+			"role":          r.Header.Get(UserRoleHeader),
+			"verified":      verified,
+			"authorization": r.Header.Get("Authorization"),
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestGateway(t *testing.T, cfg *config.Config) *Gateway {
+	t.Helper()
+	gw, err := New(cfg, fakeVerifier{"good": 7}, testKeys(t), http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gw
+}
+
+func TestGatewayRouting(t *testing.T) {
+	posts := upstreamEcho(t)
+	users := upstreamEcho(t)
+	cfg, err := config.Parse([]byte(`
+jwt: {jwks_url: "http://auth/.well-known/jwks.json"}
+service_auth: {keys: "` + testServiceKeys + `"}
+upstreams:
+  posts: {url: "` + posts.URL + `"}
+  users: {url: "` + users.URL + `"}
+routes:
+  - {prefix: /api/posts, upstream: posts, strip_prefix: true, deny: [/internal]}
+  - {prefix: /api/users, upstream: users, strip_prefix: true}
+  - {prefix: /api/users/admin, upstream: users, auth: required}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := newTestGateway(t, cfg)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		headers    map[string]string
+		wantStatus int
+		wantPath   string
+		// wantUser — подписанный шлюзом пользователь, которого видит сервис
+		wantUser string
+	}{
+		{name: "анонимный запрос", path: "/api/posts/posts?limit=5", wantStatus: http.StatusOK, wantPath: "/posts?limit=5"},
+		{name: "проверенный токен", path: "/api/posts/posts?limit=5", token: "good", wantStatus: http.StatusOK, wantPath: "/posts?limit=5", wantUser: "gateway-service:7:user"},
+		{name: "подделанный заголовок личности", path: "/api/posts/posts", headers: map[string]string{UserIDHeader: "1", UserRoleHeader: "admin"}, wantStatus: http.StatusOK, wantPath: "/posts"},
+		{name: "подделанная подпись личности", path: "/api/posts/posts", token: "good", headers: map[string]string{serviceauth.HeaderIdentity: "gateway-service,k1,0,00"}, wantStatus: http.StatusOK, wantPath: "/posts", wantUser: "gateway-service:7:user"},
+		{name: "недействительный токен", path: "/api/posts/posts", token: "bad", wantStatus: http.StatusUnauthorized},
+		{name: "персональный токен проверяет сервис", path: "/api/posts/posts", token: "blog_pat_abc", wantStatus: http.StatusOK, wantPath: "/posts"},
+		{name: "самый длинный префикс", path: "/api/users/admin/stats", token: "good", wantStatus: http.StatusOK, wantPath: "/api/users/admin/stats", wantUser: "gateway-service:7:user"},
+		{name: "обязательный токен", path: "/api/users/admin/stats", wantStatus: http.StatusUnauthorized},
+		{name: "закрытый путь", path: "/api/posts/internal/events", wantStatus: http.StatusNotFound},
+		{name: "префикс не по границе сегмента", path: "/api/postsx", wantStatus: http.StatusNotFound},
+		{name: "неизвестный маршрут", path: "/unknown", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			gw.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if ct := w.Header().Get("Content-Type"); ct != apierror.ContentType {
+					t.Errorf("ожидалась ошибка в формате problem+json, получен %q", ct)
+				}
+				return
+			}
+
+			var echo map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&echo); err != nil {
+				t.Fatal(err)
+			}
+			if echo["path"] != tt.wantPath {
+				t.Errorf("ожидался путь %q, получен %q", tt.wantPath, echo["path"])
+			}
+			if echo["verified"] != tt.wantUser {
+				t.Errorf("ожидался подписанный пользователь %q, получен %q", tt.wantUser, echo["verified"])
+			}
+			if tt.wantUser == "" && (echo["user_id"] != "" || echo["role"] != "") {
+				t.Errorf("заголовки личности без проверенного токена не должны доходить до сервиса, получены %q и %q", echo["user_id"], echo["role"])
+			}
+			if tt.token != "" && echo["authorization"] != "Bearer "+tt.token {
+				t.Errorf("токен должен передаваться сервису, получен %q", echo["authorization"])
+			}
+		})
+	}
+}
+
+func TestGatewayUpstreamUnavailable(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	cfg, err := config.Parse([]byte(`
+jwt: {jwks_url: "http://auth/.well-known/jwks.json"}
+service_auth: {keys: "` + testServiceKeys + `"}
+upstreams: {posts: {url: "` + down.URL + `"}}
+routes: [{prefix: /api/posts, upstream: posts}]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	newTestGateway(t, cfg).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/posts", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("ожидался код %d, получен %d", http.StatusBadGateway, w.Code)
+	}
+}
+
+func TestGatewayRateLimit(t *testing.T) {
+	posts := upstreamEcho(t)
+	cfg, err := config.Parse([]byte(`
+jwt: {jwks_url: "http://auth/.well-known/jwks.json"}
+service_auth: {keys: "` + testServiceKeys + `"}
+rate_limit: {requests_per_second: 1, burst: 2}
+upstreams: {posts: {url: "` + posts.URL + `"}}
+routes: [{prefix: /api/posts, upstream: posts}]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := newTestGateway(t, cfg)
+
+	send := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		gw.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := send("10.0.0.1:1000", ""); w.Code != http.StatusOK {
+			t.Fatalf("запрос %d в пределах лимита отклонён с кодом %d", i+1, w.Code)
+		}
+	}
+	w := send("10.0.0.1:1001", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("ожидался код %d, получен %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("ожидался Retry-After 1, получен %q", w.Header().Get("Retry-After"))
+	}
+
+	// Лимит считается отдельно для другого адреса и для пользователя с токеном
+	if w := send("10.0.0.2:1000", ""); w.Code != http.StatusOK {
+		t.Errorf("другой адрес не должен попадать под чужой лимит, код %d", w.Code)
+	}
+	if w := send("10.0.0.1:1002", "good"); w.Code != http.StatusOK {
+		t.Errorf("пользователь с токеном не должен попадать под лимит адреса, код %d", w.Code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	cfg := &config.Config{ClientIPHeader: "X-Forwarded-For", TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}
+	gw, err := New(cfg, fakeVerifier{}, testKeys(t), http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "без заголовка", remoteAddr: "10.0.0.5:1000", want: "10.0.0.5"},
+		{name: "соединение не от балансировщика", remoteAddr: "203.0.113.7:1000", forwarded: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "один балансировщик", remoteAddr: "10.0.0.5:1000", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "подставленный клиентом адрес", remoteAddr: "10.0.0.5:1000", forwarded: []string{"1.2.3.4, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "цепочка балансировщиков", remoteAddr: "10.0.0.5:1000", forwarded: []string{"1.2.3.4, 198.51.100.1, 192.168.1.1", "10.0.0.9"}, want: "198.51.100.1"},
+		{name: "мусор в цепочке", remoteAddr: "10.0.0.5:1000", forwarded: []string{"198.51.100.1, garbage"}, want: "10.0.0.5"},
+		{name: "вся цепочка доверенная", remoteAddr: "10.0.0.5:1000", forwarded: []string{"10.0.0.7"}, want: "10.0.0.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := gw.clientIP(req); got != tt.want {
+				t.Errorf("ожидался адрес %q, получен %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLimiterForgetsIdleClients(t *testing.T) {
+	l := newLimiter(config.RateLimit{RequestsPerSecond: 1, Burst: 1})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.allow("a")
+	now = now.Add(idleTTL + time.Second)
+	l.allow("b")
+	if _, ok := l.clients["a"]; ok {
+		t.Error("неактивный клиент должен быть удалён")
+	}
+}
+
+func TestCORS(t *testing.T) {
+	handler := CORS(config.CORS{
+		AllowedOrigins: []string{"http://localhost:5173"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		MaxAge:         600,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantAllowed string
+	}{
+		{name: "preflight разрешённого источника", method: http.MethodOptions, origin: "http://localhost:5173", preflight: true, wantStatus: http.StatusNoContent, wantAllowed: "http://localhost:5173"},
+		{name: "запрос разрешённого источника", method: http.MethodGet, origin: "http://localhost:5173", wantStatus: http.StatusTeapot, wantAllowed: "http://localhost:5173"},
+		{name: "чужой источник", method: http.MethodGet, origin: "http://evil.example", wantStatus: http.StatusTeapot},
+		{name: "preflight чужого источника", method: http.MethodOptions, origin: "http://evil.example", preflight: true, wantStatus: http.StatusNoContent},
+		{name: "запрос без Origin", method: http.MethodGet, wantStatus: http.StatusTeapot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/posts/posts", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowed {
+				t.Errorf("ожидался Access-Control-Allow-Origin %q, получен %q", tt.wantAllowed, got)
+			}
+			if tt.preflight && tt.wantAllowed != "" && w.Header().Get("Access-Control-Allow-Methods") != "GET, POST" {
+				t.Errorf("неожиданный Access-Control-Allow-Methods: %q", w.Header().Get("Access-Control-Allow-Methods"))
+			}
+		})
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	tests := []struct {
+		name          string
+		upstreams     map[string]config.Upstream
+		wantStatus    int
+		wantHealth    string
+		wantUpstreams map[string]string
+	}{
+		{
+			name:          "все сервисы доступны",
+			upstreams:     map[string]config.Upstream{"posts": {URL: healthy.URL, Health: "/health"}, "users": {URL: healthy.URL + "/", Health: "/health"}},
+			wantStatus:    http.StatusOK,
+			wantHealth:    "ok",
+			wantUpstreams: map[string]string{"posts": "ok", "users": "ok"},
+		},
+		{
+			name:          "один сервис недоступен",
+			upstreams:     map[string]config.Upstream{"posts": {URL: healthy.URL, Health: "/health"}, "users": {URL: failing.URL, Health: "/health"}},
+			wantStatus:    http.StatusServiceUnavailable,
+			wantHealth:    "degraded",
+			wantUpstreams: map[string]string{"posts": "ok", "users": "down"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				calls.Add(1)
+				return http.DefaultTransport.RoundTrip(r)
+			})}
+			handler := HealthHandler(health.NewChecker(UpstreamChecks(tt.upstreams, client)...))
+
+			for i := 0; i < 3; i++ {
+				w := httptest.NewRecorder()
+				handler(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+				if w.Code != tt.wantStatus {
+					t.Fatalf("ожидался код %d, получен %d", tt.wantStatus, w.Code)
+				}
+				if body := w.Body.String(); strings.Contains(body, "127.0.0.1") || strings.Contains(body, "unexpected status") {
+					t.Errorf("публичный ответ раскрывает подробности сервисов: %s", body)
+				}
+				var got Health
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatal(err)
+				}
+				if got.Status != tt.wantHealth || !reflect.DeepEqual(got.Upstreams, tt.wantUpstreams) {
+					t.Errorf("неожиданный ответ: %+v", got)
+				}
+			}
+			// Повторные запросы берут результат из кэша
+			if n := int(calls.Load()); n != len(tt.upstreams) {
+				t.Errorf("ожидалось %d запросов к сервисам, выполнено %d", len(tt.upstreams), n)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestUpstreamChecks(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	checker := health.NewChecker(UpstreamChecks(map[string]config.Upstream{
+		"posts": {URL: healthy.URL, Health: "/health"},
+		"users": {URL: failing.URL, Health: "/health"},
+	}, http.DefaultClient)...)
+	report := checker.Report(context.Background())
+
+	// Отказ одного сервиса не выводит шлюз из ротации
+	if report.Status != health.StatusDegraded || !checker.Ready(context.Background()) {
+		t.Errorf("ожидался статус degraded без потери готовности, получен %+v", report)
+	}
+	if got := report.Checks["users"]; got.Status != health.StatusDown || got.LastError != "unexpected status 500" {
+		t.Errorf("неожиданный результат проверки users: %+v", got)
+	}
+	if got := report.Checks["posts"]; got.Status != health.StatusOK {
+		t.Errorf("неожиданный результат проверки posts: %+v", got)
+	}
+}
@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"blog/pkg/health"
+	"gateway_service/internal/config"
+)
+
+// Health — сводное состояние шлюза и сервисов за ним. Ошибки и адреса сервисов
+// в публичный ответ не попадают: подробности есть в /health/details на служебном порту.
+type Health struct {
+	Status    string            `json:"status"`
+	Upstreams map[string]string `json:"upstreams"`
+}
+
+// HealthHandler отдаёт состояние сервисов из checker. Результаты проверок кэшируются
+// в checker, поэтому частые запросы к /health не нагружают сервисы.
+// Ответ 200, если все сервисы отвечают, иначе 503 со статусом degraded.
+func HealthHandler(checker *health.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Report(r.Context())
+		result := Health{Status: health.StatusOK, Upstreams: make(map[string]string, len(report.Checks))}
+		for name, check := range report.Checks {
+			result.Upstreams[name] = check.Status
+		}
+
+		status := http.StatusOK
+		if report.Status != health.StatusOK {
+			result.Status = health.StatusDegraded
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// UpstreamChecks возвращает проверки сервисов для /health и /health/details шлюза. Все они некритичные:
+// при отказе одного сервиса шлюз продолжает обслуживать маршруты остальных.
+func UpstreamChecks(upstreams map[string]config.Upstream, client *http.Client) []health.Check {
+	names := make([]string, 0, len(upstreams))
+	for name := range upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]health.Check, 0, len(names))
+	for _, name := range names {
+		up := upstreams[name]
+		checks = append(checks, health.Check{Name: name, Run: health.HTTP(client, strings.TrimSuffix(up.URL, "/")+up.Health)})
+	}
+	return checks
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+
+	"gateway_service/internal/config"
+
+	"golang.org/x/time/rate"
+)
+
+// idleTTL — через сколько неактивный клиент забывается
+const idleTTL = 10 * time.Minute
+
+// limiter ограничивает частоту запросов отдельно для каждого клиента
+type limiter struct {
+	limit rate.Limit
+	burst int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+type client struct {
+	bucket   *rate.Limiter
+	lastSeen time.Time
+}
+
+func newLimiter(cfg config.RateLimit) *limiter {
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{
+		limit:   rate.Limit(cfg.RequestsPerSecond),
+		burst:   burst,
+		now:     time.Now,
+		clients: make(map[string]*client),
+	}
+}
+
+// allow расходует токен клиента; при исчерпании лимита возвращает время до следующего токена
+func (l *limiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > idleTTL {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > idleTTL {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{bucket: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+
+	reservation := c.bucket.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
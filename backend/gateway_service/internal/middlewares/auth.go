@@ -0,0 +1,5 @@
+package middlewares
+
+// PersonalAccessTokenPrefix отличает персональные токены от JWT. Их проверяют сами сервисы,
+// потому что для этого нужна база users_service.
+const PersonalAccessTokenPrefix = "blog_pat_"
@@ -0,0 +1,182 @@
+// Package apierror формирует ошибки API в формате RFC 7807 (application/problem+json).
+// Пакет общий для всех сервисов, чтобы клиенты разбирали ошибки единообразно.
+package apierror
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType — тип содержимого ответа с ошибкой
+const ContentType = "application/problem+json"
+
+// RequestIDHeader — заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// TypeBase — префикс URI типа проблемы; полный тип — TypeBase + код
+const TypeBase = "urn:blog:problem:"
+
+// Коды ошибок, на которые могут опираться клиенты
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodeGone                 = "gone"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal_error"
+	CodeUpstream             = "upstream_unavailable"
+)
+
+// titles — краткие описания типов проблем; одинаковы для всех ответов с этим кодом
+var titles = map[string]string{
+	CodeInvalidRequest:       "Invalid request",
+	CodeValidationFailed:     "Validation failed",
+	CodeUnauthorized:         "Authentication required",
+	CodeInvalidCredentials:   "Invalid credentials",
+	CodeForbidden:            "Forbidden",
+	CodeNotFound:             "Resource not found",
+	CodeMethodNotAllowed:     "Method not allowed",
+	CodeConflict:             "Conflict",
+	CodeGone:                 "Gone",
+	CodePayloadTooLarge:      "Payload too large",
+	CodeUnsupportedMediaType: "Unsupported media type",
+	CodeRateLimited:          "Too many requests",
+	CodeInternal:             "Internal server error",
+	CodeUpstream:             "Upstream service unavailable",
+}
+
+// FieldError описывает ошибку конкретного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problem — тело ошибки по RFC 7807 с расширениями code, request_id и fields
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+}
+
+// Title возвращает краткое описание типа проблемы по коду
+func Title(code string, status int) string {
+	if t, ok := titles[code]; ok {
+		return t
+	}
+	return http.StatusText(status)
+}
+
+// CodeForStatus возвращает код ошибки по умолчанию для HTTP-статуса
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeUpstream
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
+
+// Write отправляет ошибку с указанным кодом в формате problem+json
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields ...FieldError) {
+	p := Problem{
+		Type:   TypeBase + code,
+		Title:  Title(code, status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Fields: fields,
+	}
+	if r != nil {
+		p.Instance = r.URL.Path
+		p.RequestID = r.Header.Get(RequestIDHeader)
+	}
+	if p.RequestID != "" {
+		w.Header().Set(RequestIDHeader, p.RequestID)
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error отправляет ошибку с кодом по умолчанию для статуса; замена http.Error
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	Write(w, r, status, CodeForStatus(status), detail)
+}
+
+// Validation отправляет 400 с перечнем ошибок по полям
+func Validation(w http.ResponseWriter, r *http.Request, fields []FieldError) {
+	Write(w, r, http.StatusBadRequest, CodeValidationFailed, "Validation failed", fields...)
+}
+
+// Conflict отправляет 409 для значения, которое уже занято
+func Conflict(w http.ResponseWriter, r *http.Request, field, message string) {
+	Write(w, r, http.StatusConflict, CodeConflict, message, FieldError{Field: field, Code: "taken", Message: message})
+}
+
+// NotFoundHandler отвечает problem+json на запросы к неизвестным маршрутам
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, http.StatusNotFound, "Route not found")
+	})
+}
+
+// MethodNotAllowedHandler отвечает problem+json на запросы с неподдерживаемым методом
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+	})
+}
+
+// WithRequestID присваивает запросу идентификатор, если клиент или шлюз его не передали,
+// и возвращает его в заголовке ответа
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
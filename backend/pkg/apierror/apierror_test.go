@@ -0,0 +1,86 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteProblem(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/users/register", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+
+	Validation(w, req, []FieldError{{Field: "email", Code: "invalid", Message: "Email is invalid"}})
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("ожидался код %d, получен %d", http.StatusBadRequest, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("ожидался Content-Type %q, получен %q", ContentType, ct)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	want := Problem{
+		Type:      TypeBase + CodeValidationFailed,
+		Title:     "Validation failed",
+		Status:    http.StatusBadRequest,
+		Detail:    "Validation failed",
+		Instance:  "/api/users/register",
+		Code:      CodeValidationFailed,
+		RequestID: "req-1",
+	}
+	if p.Type != want.Type || p.Title != want.Title || p.Status != want.Status || p.Detail != want.Detail ||
+		p.Instance != want.Instance || p.Code != want.Code || p.RequestID != want.RequestID {
+		t.Errorf("ожидалось %+v, получено %+v", want, p)
+	}
+	if len(p.Fields) != 1 || p.Fields[0].Field != "email" {
+		t.Errorf("ожидалась ошибка поля email, получено %+v", p.Fields)
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:          CodeInvalidRequest,
+		http.StatusUnauthorized:        CodeUnauthorized,
+		http.StatusForbidden:           CodeForbidden,
+		http.StatusNotFound:            CodeNotFound,
+		http.StatusConflict:            CodeConflict,
+		http.StatusTooManyRequests:     CodeRateLimited,
+		http.StatusInternalServerError: CodeInternal,
+		http.StatusBadGateway:          CodeUpstream,
+		http.StatusTeapot:              CodeInvalidRequest,
+	}
+	for status, want := range tests {
+		if got := CodeForStatus(status); got != want {
+			t.Errorf("CodeForStatus(%d) = %q, ожидалось %q", status, got, want)
+		}
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	var seen string
+	handler := WithRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get(RequestIDHeader)
+	}))
+
+	// Переданный идентификатор сохраняется
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "from-gateway")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if seen != "from-gateway" || w.Header().Get(RequestIDHeader) != "from-gateway" {
+		t.Errorf("ожидался идентификатор from-gateway, получен %q", seen)
+	}
+
+	// Без заголовка идентификатор генерируется
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(seen) != 32 || w.Header().Get(RequestIDHeader) != seen {
+		t.Errorf("ожидался сгенерированный идентификатор, получен %q", seen)
+	}
+}
@@ -0,0 +1,27 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Relay пересылает клиенту ошибку другого сервиса. Ответ в формате problem+json передаётся с тем же
+// кодом, описанием и полями, иначе (текст, пустое тело) формируется ошибка с тем же статусом
+// и описанием fallback. Ошибки сервера другого сервиса превращаются в 502.
+func Relay(w http.ResponseWriter, r *http.Request, status int, body []byte, fallback string) {
+	var upstream Problem
+	if err := json.Unmarshal(body, &upstream); err == nil && upstream.Code != "" && upstream.Detail != "" {
+		if status >= 500 {
+			Write(w, r, http.StatusBadGateway, CodeUpstream, upstream.Detail)
+			return
+		}
+		Write(w, r, status, upstream.Code, upstream.Detail, upstream.Fields...)
+		return
+	}
+
+	if status >= 500 || status < 400 {
+		Write(w, r, http.StatusBadGateway, CodeUpstream, fallback)
+		return
+	}
+	Error(w, r, status, fallback)
+}
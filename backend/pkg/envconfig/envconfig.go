@@ -0,0 +1,99 @@
+// Package envconfig читает настройки сервисов из переменных окружения и скрывает секреты при выводе
+package envconfig
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lookup записывает в dst значение переменной key или содержимое файла из key_FILE.
+// Пустая переменная считается незаданной: dst сохраняет значение по умолчанию.
+func Lookup(key string, dst *string) error {
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s_FILE: %w", key, err)
+		}
+		*dst = strings.TrimRight(string(data), "\r\n")
+		return nil
+	}
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+	return nil
+}
+
+// LookupBool читает логическое значение так же, как Lookup
+func LookupBool(key string, dst *bool) error {
+	var value string
+	if err := Lookup(key, &value); err != nil || value == "" {
+		return err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a boolean", key, value)
+	}
+	*dst = b
+	return nil
+}
+
+// LookupDuration читает длительность вида "30s" так же, как Lookup
+func LookupDuration(key string, dst *time.Duration) error {
+	var value string
+	if err := Lookup(key, &value); err != nil || value == "" {
+		return err
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a duration", key, value)
+	}
+	*dst = d
+	return nil
+}
+
+// LookupInt читает целое число так же, как Lookup
+func LookupInt(key string, dst *int) error {
+	var value string
+	if err := Lookup(key, &value); err != nil || value == "" {
+		return err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a number", key, value)
+	}
+	*dst = n
+	return nil
+}
+
+// CheckURL проверяет, что адрес задан и является абсолютным http(s) URL
+func CheckURL(key, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", key)
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s: url must be an absolute http(s) URL", key)
+	}
+	return nil
+}
+
+// Redact скрывает заданный секрет; пустое значение остаётся пустым, чтобы было видно, что секрета нет
+func Redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// RedactURL скрывает пароль, если он передан в адресе
+func RedactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return Redact(value)
+	}
+	return u.Redacted()
+}
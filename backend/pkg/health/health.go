@@ -0,0 +1,192 @@
+// Package health проверяет зависимости сервиса для /ready и /health/details
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL — сколько результаты проверок считаются свежими: частые пробы не нагружают зависимости
+	DefaultTTL = 5 * time.Second
+	// DefaultTimeout ограничивает одну проверку
+	DefaultTimeout = 2 * time.Second
+)
+
+// Состояния проверки и сервиса в целом
+const (
+	StatusOK       = "ok"
+	StatusDown     = "down"
+	StatusUnknown  = "unknown"
+	StatusDegraded = "degraded"
+	StatusDraining = "draining"
+)
+
+// Check — проверка одной зависимости
+type Check struct {
+	Name string
+	// Critical — без зависимости сервис не может обслуживать запросы, и /ready отвечает 503.
+	// Сбой некритичной зависимости виден только в /health/details.
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// Result — последний результат проверки. LastError сохраняется и после восстановления,
+// чтобы по /health/details было видно недавний сбой.
+type Result struct {
+	Status      string     `json:"status"`
+	Critical    bool       `json:"critical"`
+	LatencyMS   int64      `json:"latency_ms"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Report — состояние сервиса: ok, degraded (сбой некритичных зависимостей),
+// down (сбой критичных) или draining (экземпляр останавливается)
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker выполняет проверки параллельно и кэширует результаты на TTL.
+// Проверки запускаются по запросу: пока никто не спрашивает, зависимости не опрашиваются.
+type Checker struct {
+	TTL     time.Duration
+	Timeout time.Duration
+
+	checks  []Check
+	mu      sync.Mutex
+	results map[string]Result
+	expires time.Time
+	running chan struct{}
+}
+
+// NewChecker создаёт проверку зависимостей со значениями TTL и Timeout по умолчанию
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{
+		TTL:     DefaultTTL,
+		Timeout: DefaultTimeout,
+		checks:  checks,
+		results: make(map[string]Result, len(checks)),
+	}
+}
+
+// Report возвращает результаты проверок. Устаревшие результаты обновляются; одновременные
+// запросы ждут одного прогона. Если ctx истекает раньше, возвращаются последние известные
+// результаты, а ещё не выполненные проверки получают статус unknown.
+func (c *Checker) Report(ctx context.Context) Report {
+	c.mu.Lock()
+	if time.Now().Before(c.expires) {
+		defer c.mu.Unlock()
+		return c.report()
+	}
+	done := c.running
+	if done == nil {
+		done = make(chan struct{})
+		c.running = done
+		// Прогон не привязан к запросу: оборванная проба не должна оставлять кэш пустым
+		go c.refresh(done)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.report()
+}
+
+// Ready сообщает, что все критичные зависимости доступны
+func (c *Checker) Ready(ctx context.Context) bool {
+	return c.Report(ctx).Status != StatusDown
+}
+
+func (c *Checker) refresh(done chan struct{}) {
+	type outcome struct {
+		check   Check
+		err     error
+		latency time.Duration
+		at      time.Time
+	}
+	outcomes := make(chan outcome, len(c.checks))
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+			defer cancel()
+			start := time.Now()
+			err := check.Run(ctx)
+			outcomes <- outcome{check: check, err: err, latency: time.Since(start), at: start}
+		}(check)
+	}
+	wg.Wait()
+	close(outcomes)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for o := range outcomes {
+		result := c.results[o.check.Name]
+		at := o.at
+		result.Critical = o.check.Critical
+		result.LatencyMS = o.latency.Milliseconds()
+		result.CheckedAt = &at
+		result.Status = StatusOK
+		if o.err != nil {
+			result.Status = StatusDown
+			result.LastError = o.err.Error()
+			result.LastErrorAt = &at
+		}
+		c.results[o.check.Name] = result
+	}
+	c.expires = time.Now().Add(c.TTL)
+	c.running = nil
+	close(done)
+}
+
+// report собирает ответ из кэша; вызывается под c.mu
+func (c *Checker) report() Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+	for _, check := range c.checks {
+		result, ok := c.results[check.Name]
+		if !ok {
+			result = Result{Status: StatusUnknown, Critical: check.Critical}
+		}
+		report.Checks[check.Name] = result
+		if result.Status == StatusOK {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// HTTP проверяет, что адрес отвечает 200 OK
+func HTTP(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubCheck возвращает проверку, которая считает вызовы и завершается ошибкой, пока выставлен failing
+func stubCheck(name string, critical bool, calls *atomic.Int32, failing *atomic.Bool) Check {
+	return Check{Name: name, Critical: critical, Run: func(context.Context) error {
+		calls.Add(1)
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+}
+
+func TestCheckerStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		dbDown     bool
+		postsDown  bool
+		wantStatus string
+	}{
+		{name: "все зависимости доступны", wantStatus: StatusOK},
+		{name: "недоступна некритичная зависимость", postsDown: true, wantStatus: StatusDegraded},
+		{name: "недоступна критичная зависимость", dbDown: true, wantStatus: StatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			var dbDown, postsDown atomic.Bool
+			dbDown.Store(tt.dbDown)
+			postsDown.Store(tt.postsDown)
+			c := NewChecker(stubCheck("database", true, &calls, &dbDown), stubCheck("posts_service", false, &calls, &postsDown))
+
+			report := c.Report(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Errorf("ожидался статус %s, получен %s", tt.wantStatus, report.Status)
+			}
+			if c.Ready(context.Background()) != (tt.wantStatus != StatusDown) {
+				t.Errorf("Ready не соответствует статусу %s", report.Status)
+			}
+			if got := report.Checks["posts_service"]; tt.postsDown != (got.LastError != "") || got.CheckedAt == nil {
+				t.Errorf("неожиданный результат проверки: %+v", got)
+			}
+		})
+	}
+}
+
+func TestCheckerCachesResults(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	c := NewChecker(stubCheck("database", true, &calls, &failing))
+	c.TTL = 50 * time.Millisecond
+
+	for i := 0; i < 5; i++ {
+		c.Report(context.Background())
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("в пределах TTL ожидался один прогон проверок, выполнено %d", calls.Load())
+	}
+
+	// После восстановления статус меняется, а последняя ошибка остаётся видна
+	failing.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	result := c.Report(context.Background()).Checks["database"]
+	if calls.Load() != 2 {
+		t.Errorf("после истечения TTL проверки должны выполниться снова, выполнено %d", calls.Load())
+	}
+	if result.Status != StatusOK || result.LastError != "connection refused" || result.LastErrorAt == nil {
+		t.Errorf("неожиданный результат после восстановления: %+v", result)
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	c := NewChecker(Check{Name: "database", Critical: true, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	c.Timeout = 20 * time.Millisecond
+
+	// Проба сдаётся раньше проверки: результата ещё нет
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if result := c.Report(ctx).Checks["database"]; result.Status != StatusUnknown {
+		t.Errorf("ожидался статус unknown, получен %+v", result)
+	}
+
+	// Прогон не прерывается вместе с пробой и завершается по своему таймауту
+	result := c.Report(context.Background()).Checks["database"]
+	if result.Status != StatusDown || result.LastError != context.DeadlineExceeded.Error() {
+		t.Errorf("ожидался сбой по таймауту, получен %+v", result)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	if err := HTTP(srv.Client(), srv.URL+"/health")(context.Background()); err != nil {
+		t.Errorf("ожидался успешный ответ, получено %v", err)
+	}
+	if err := HTTP(srv.Client(), srv.URL+"/broken")(context.Background()); err == nil || err.Error() != "unexpected status 500" {
+		t.Errorf("ожидалась ошибка статуса, получено %v", err)
+	}
+}
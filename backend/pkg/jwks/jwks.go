@@ -0,0 +1,139 @@
+// Package jwks загружает публичные ключи auth_service для проверки JWT
+package jwks
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey возвращается, если kid токена не найден в JWKS
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySource возвращает публичный ключ по его kid
+type KeySource interface {
+	Key(kid string) (*rsa.PublicKey, error)
+}
+
+// Cache загружает и кэширует публичные ключи auth_service
+type Cache struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// minRefreshInterval ограничивает частоту внеплановых обновлений при неизвестном kid
+const minRefreshInterval = 30 * time.Second
+
+// NewCache создаёт кэш JWKS с заданным временем жизни
+func NewCache(url string, ttl time.Duration) *Cache {
+	return &Cache{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Key возвращает ключ по kid. Если ключ не найден или кэш устарел,
+// JWKS загружается заново, что позволяет подхватывать ротацию ключей.
+func (c *Cache) Key(kid string) (*rsa.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	fresh := time.Since(c.fetchedAt) < c.ttl
+	c.mu.RUnlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := c.refresh(kid); err != nil {
+		// При недоступности auth_service продолжаем работать с уже известным ключом
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// refresh загружает JWKS, если ключ kid всё ещё не найден или кэш устарел
+func (c *Cache) refresh(kid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Пока запрос ждал блокировку, JWKS мог обновить другой запрос:
+	// после истечения TTL auth_service получает один запрос, а не по одному на каждый ожидающий
+	_, known := c.keys[kid]
+	if known && time.Since(c.fetchedAt) < c.ttl {
+		return nil
+	}
+
+	// Неизвестный kid не должен приводить к запросу JWKS на каждый запрос клиента
+	if !known && time.Since(c.lastAttempt) < minRefreshInterval && time.Since(c.fetchedAt) < c.ttl {
+		return nil
+	}
+	c.lastAttempt = time.Now()
+
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Kid == "" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,53 @@
+package jwks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheRefreshesOnceAfterExpiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer srv.Close()
+
+	cache := NewCache(srv.URL, time.Minute)
+	if _, err := cache.Key("k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Кэш устарел. Первый запрос обновляет JWKS, а запрос, который увидел устаревший кэш
+	// одновременно с ним и ждал блокировку, не должен загружать JWKS повторно.
+	cache.mu.Lock()
+	cache.fetchedAt = time.Now().Add(-2 * time.Minute)
+	cache.mu.Unlock()
+	if _, err := cache.Key("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.refresh("k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("ожидалось 2 загрузки JWKS (первая и одно обновление), получено %d", got)
+	}
+}
@@ -0,0 +1,65 @@
+package jwks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Verifier строго проверяет JWT, выпущенные auth_service
+type Verifier struct {
+	Keys     KeySource
+	Issuer   string
+	Audience string
+}
+
+// Verify проверяет подпись (RS256 и известный kid), iss, aud и exp и возвращает ID и роль пользователя
+func (v *Verifier) Verify(tokenString string) (int, string, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid")
+		}
+		return v.Keys.Key(kid)
+	})
+	if err != nil || !token.Valid {
+		return 0, "", fmt.Errorf("invalid token: %v", err)
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return 0, "", errors.New("token has no valid exp")
+	}
+	if iss, _ := claims["iss"].(string); iss != v.Issuer {
+		return 0, "", errors.New("unexpected issuer")
+	}
+	if !hasAudience(claims["aud"], v.Audience) {
+		return 0, "", errors.New("unexpected audience")
+	}
+
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok || userIDFloat <= 0 {
+		return 0, "", errors.New("user_id not found in claims")
+	}
+	role, _ := claims["role"].(string)
+	return int(userIDFloat), role, nil
+}
+
+func hasAudience(aud interface{}, expected string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == expected
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package jwks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type staticKeys map[string]*rsa.PublicKey
+
+func (s staticKeys) Key(kid string) (*rsa.PublicKey, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func TestVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &Verifier{
+		Keys:     staticKeys{"k1": &key.PublicKey},
+		Issuer:   "auth-service",
+		Audience: "blog-api",
+	}
+	sign := func(method jwt.SigningMethod, signKey interface{}, kid string, mod func(jwt.MapClaims)) string {
+		claims := jwt.MapClaims{
+			"user_id": 7,
+			"role":    "admin",
+			"iss":     "auth-service",
+			"aud":     "blog-api",
+			"exp":     time.Now().Add(time.Hour).Unix(),
+		}
+		if mod != nil {
+			mod(claims)
+		}
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(signKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	rs := func(kid string, mod func(jwt.MapClaims)) string { return sign(jwt.SigningMethodRS256, key, kid, mod) }
+
+	tests := []struct {
+		name   string
+		token  string
+		wantOK bool
+	}{
+		{"валидный токен", rs("k1", nil), true},
+		{"аудитория списком", rs("k1", func(c jwt.MapClaims) { c["aud"] = []string{"other", "blog-api"} }), true},
+		{"неизвестный kid", rs("k2", nil), false},
+		{"без kid", rs("", nil), false},
+		{"HS256 вместо RS256", sign(jwt.SigningMethodHS256, []byte("secret"), "k1", nil), false},
+		{"чужой issuer", rs("k1", func(c jwt.MapClaims) { c["iss"] = "evil" }), false},
+		{"чужая аудитория", rs("k1", func(c jwt.MapClaims) { c["aud"] = "blog-api:2fa" }), false},
+		{"без exp", rs("k1", func(c jwt.MapClaims) { delete(c, "exp") }), false},
+		{"истёкший", rs("k1", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), false},
+		{"без user_id", rs("k1", func(c jwt.MapClaims) { delete(c, "user_id") }), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, role, err := verifier.Verify(tt.token)
+			if !tt.wantOK {
+				if err == nil {
+					t.Errorf("токен должен быть отклонён")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			if userID != 7 || role != "admin" {
+				t.Errorf("ожидался пользователь 7 с ролью admin, получено %d %q", userID, role)
+			}
+		})
+	}
+}
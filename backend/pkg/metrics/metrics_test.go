@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.HandleFunc("/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods("GET")
+	r.HandleFunc("/posts", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}).Methods("GET")
+	handler := Middleware(MuxRoute(r), r)
+
+	tests := []struct {
+		name   string
+		path   string
+		route  string
+		status string
+	}{
+		{name: "Метка — шаблон маршрута, а не путь", path: "/posts/42", route: "/posts/{id}", status: "404"},
+		{name: "Код по умолчанию", path: "/posts", route: "/posts", status: "200"},
+		{name: "Запрос без маршрута", path: "/unknown/7", route: Unmatched, status: "404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, tt.route, tt.status))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, tt.route, tt.status)); got != before+1 {
+				t.Errorf("счётчик %s %s не увеличился: %v -> %v", tt.route, tt.status, before, got)
+			}
+		})
+	}
+
+	// Путь с ID не должен попадать в метки
+	if n := testutil.CollectAndCount(httpRequests); n != len(tests) {
+		t.Errorf("ожидалось %d временных рядов, получено %d", len(tests), n)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	client := &http.Client{Transport: Transport(nil)}
+
+	resp, err := client.Post(srv.URL+"/notifications", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := testutil.ToFloat64(clientRequests.WithLabelValues(host, http.MethodPost, "201")); got != 1 {
+		t.Errorf("ожидался один успешный запрос к %s, получено %v", host, got)
+	}
+
+	// Сервис недоступен: ответа нет, запрос учитывается со статусом error
+	srv.Close()
+	if _, err := client.Get(srv.URL + "/health"); err == nil {
+		t.Fatal("ожидалась ошибка соединения")
+	}
+	if got := testutil.ToFloat64(clientRequests.WithLabelValues(host, http.MethodGet, "error")); got != 1 {
+		t.Errorf("ожидался один неудачный запрос, получено %v", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	clientRequests.WithLabelValues("users_service:8084", http.MethodGet, "200").Inc()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "http_client_requests_total") {
+		t.Errorf("в ответе /metrics нет счётчика исходящих запросов: %d\n%s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// MuxRoute возвращает шаблон маршрута router, под который попадает запрос.
+// Маршрут ищется заново, поэтому метка есть и у запросов, отклонённых до роутера (например, проверкой OpenAPI).
+func MuxRoute(router *mux.Router) func(*http.Request) string {
+	return func(r *http.Request) string {
+		var match mux.RouteMatch
+		if !router.Match(r, &match) || match.Route == nil {
+			return Unmatched
+		}
+		template, err := match.Route.GetPathTemplate()
+		if err != nil {
+			return Unmatched
+		}
+		return template
+	}
+}
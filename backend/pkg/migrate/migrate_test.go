@@ -0,0 +1,249 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []int64
+		wantErr string
+	}{
+		{
+			name: "Миграции сортируются по версии",
+			files: fstest.MapFS{
+				"0002_b.up.sql":   {Data: []byte("SELECT 2")},
+				"0002_b.down.sql": {Data: []byte("SELECT -2")},
+				"0001_a.up.sql":   {Data: []byte("SELECT 1")},
+				"0001_a.down.sql": {Data: []byte("SELECT -1")},
+				"embed.go":        {Data: []byte("package migrations")},
+			},
+			want: []int64{1, 2},
+		},
+		{
+			name: "Нет скрипта отката",
+			files: fstest.MapFS{
+				"0001_a.up.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: "must have non-empty up and down",
+		},
+		{
+			name: "Неверное имя файла",
+			files: fstest.MapFS{
+				"create_users.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: "name must look like",
+		},
+		{
+			name: "Два имени у одной версии",
+			files: fstest.MapFS{
+				"0001_a.up.sql":   {Data: []byte("SELECT 1")},
+				"0001_b.down.sql": {Data: []byte("SELECT -1")},
+			},
+			wantErr: "has two names",
+		},
+		{
+			name: "Нулевая версия",
+			files: fstest.MapFS{
+				"0000_a.up.sql":   {Data: []byte("SELECT 1")},
+				"0000_a.down.sql": {Data: []byte("SELECT -1")},
+			},
+			wantErr: "version must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := Load(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Ожидалась ошибка %q, получено %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Неожиданная ошибка: %v", err)
+			}
+			var got []int64
+			for _, m := range list {
+				got = append(got, m.Version)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Ожидались версии %v, получено %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Ожидались версии %v, получено %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_a", Up: "CREATE TABLE a (id int)", Down: "DROP TABLE a"},
+	{Version: 2, Name: "create_b", Up: "CREATE TABLE b (id int)", Down: "DROP TABLE b"},
+	{Version: 3, Name: "create_c", Up: "CREATE TABLE c (id int)", Down: "DROP TABLE c"},
+}
+
+// expectLocked описывает запросы locked до вызова fn
+func expectLocked(mock sqlmock.Sqlmock, applied ...int64) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WithArgs(LockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, v := range applied {
+		rows.AddRow(v, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").WithArgs("users-service").WillReturnRows(rows)
+}
+
+func expectApply(mock sqlmock.Sqlmock, script string, version int64, up bool) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(script)).WillReturnResult(sqlmock.NewResult(0, 0))
+	if up {
+		mock.ExpectExec("INSERT INTO schema_migrations").WithArgs("users-service", version, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	} else {
+		mock.ExpectExec("DELETE FROM schema_migrations").WithArgs("users-service", version).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(LockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		setup      func(mock sqlmock.Sqlmock)
+		wantErr    string
+		wantOutput []string
+	}{
+		{
+			name: "up применяет только неприменённые миграции",
+			args: []string{"up"},
+			setup: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock, 1)
+				expectApply(mock, "CREATE TABLE b", 2, true)
+				expectApply(mock, "CREATE TABLE c", 3, true)
+				expectUnlock(mock)
+			},
+		},
+		{
+			name: "down по умолчанию откатывает одну миграцию",
+			args: []string{"down"},
+			setup: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock, 1, 2)
+				expectApply(mock, "DROP TABLE b", 2, false)
+				expectUnlock(mock)
+			},
+		},
+		{
+			name: "to откатывает старшие и применяет младшие версии",
+			args: []string{"to", "2"},
+			setup: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock, 1, 3)
+				expectApply(mock, "DROP TABLE c", 3, false)
+				expectApply(mock, "CREATE TABLE b", 2, true)
+				expectUnlock(mock)
+			},
+		},
+		{
+			name: "to 0 откатывает всё",
+			args: []string{"to", "0"},
+			setup: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock, 1, 2)
+				expectApply(mock, "DROP TABLE b", 2, false)
+				expectApply(mock, "DROP TABLE a", 1, false)
+				expectUnlock(mock)
+			},
+		},
+		{
+			name: "Ошибка скрипта откатывает транзакцию и снимает блокировку",
+			args: []string{"up"},
+			setup: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock, 1, 2)
+				mock.ExpectBegin()
+				mock.ExpectExec("CREATE TABLE c").WillReturnError(sqlmock.ErrCancelled)
+				mock.ExpectRollback()
+				expectUnlock(mock)
+			},
+			wantErr: "migration 0003_create_c (up) failed",
+		},
+		{
+			name: "status показывает применённые, ожидающие и неизвестные версии",
+			args: []string{"status"},
+			setup: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock, 1, 7)
+				expectUnlock(mock)
+			},
+			wantOutput: []string{
+				"0001  create_a                                 applied 2024-01-01T00:00:00Z",
+				"0002  create_b                                 pending",
+				"0003  create_c                                 pending",
+				"0007  (missing)                                applied 2024-01-01T00:00:00Z",
+			},
+		},
+		{
+			name:    "Неизвестная версия",
+			args:    []string{"to", "5"},
+			setup:   func(mock sqlmock.Sqlmock) {},
+			wantErr: "unknown migration version 5",
+		},
+		{
+			name:    "Неверное число шагов",
+			args:    []string{"down", "zero"},
+			setup:   func(mock sqlmock.Sqlmock) {},
+			wantErr: "invalid number of steps",
+		},
+		{
+			name:    "Неизвестная команда",
+			args:    []string{"redo"},
+			setup:   func(mock sqlmock.Sqlmock) {},
+			wantErr: "unknown migrate command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Ошибка создания мока: %v", err)
+			}
+			defer db.Close()
+			tt.setup(mock)
+
+			m := &Migrator{DB: db, Service: "users-service", Migrations: testMigrations}
+			var out bytes.Buffer
+			err = Run(context.Background(), m, tt.args, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Ожидалась ошибка %q, получено %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Неожиданная ошибка: %v", err)
+			}
+
+			if tt.wantOutput != nil {
+				got := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+				if strings.Join(got, "\n") != strings.Join(tt.wantOutput, "\n") {
+					t.Errorf("Ожидался вывод:\n%s\nполучено:\n%s", strings.Join(tt.wantOutput, "\n"), out.String())
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Не все ожидания выполнены: %v", err)
+			}
+		})
+	}
+}
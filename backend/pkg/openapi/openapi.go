@@ -0,0 +1,226 @@
+// Package openapi отдаёт OpenAPI-документ сервиса по /openapi.json
+// и проверяет по нему запросы и (в тестовом режиме) ответы.
+// Сами документы хранятся в сервисах (internal/apispec).
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"blog/pkg/apierror"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// DefaultMaxBodyBytes ограничивает тело запроса, которое валидатор читает в память
+const DefaultMaxBodyBytes = 8 << 20
+
+// Load разбирает документ spec и проверяет его корректность
+func Load(spec []byte) (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
+
+// Handler отдаёт документ в формате JSON
+func Handler(doc *openapi3.T) http.HandlerFunc {
+	body, err := json.Marshal(doc)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to encode OpenAPI document")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+// Validator проверяет запросы по OpenAPI-документу
+type Validator struct {
+	router routers.Router
+	// ValidateResponses включает проверку ответов; используется в тестах, чтобы расхождение
+	// обработчиков с документом приводило к ошибке 500
+	ValidateResponses bool
+	// MaxBodyBytes — максимальный размер тела запроса, по умолчанию DefaultMaxBodyBytes
+	MaxBodyBytes int64
+}
+
+// NewValidator создаёт валидатор для документа
+func NewValidator(doc *openapi3.T) (*Validator, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI router: %w", err)
+	}
+	return &Validator{router: router, MaxBodyBytes: DefaultMaxBodyBytes}, nil
+}
+
+// Middleware отклоняет запросы, не соответствующие документу, ошибкой в формате problem+json.
+// Маршруты, которых нет в документе, пропускаются как есть; в режиме проверки ответов
+// они считаются расхождением, если обработчик ответил не 404 и не 405.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	options := &openapi3filter.Options{
+		MultiError: true,
+		// Токены и подписи сервисов проверяют собственные middleware
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			if !v.ValidateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rec := newRecorder()
+			next.ServeHTTP(rec, r)
+			if rec.status != http.StatusNotFound && rec.status != http.StatusMethodNotAllowed {
+				apierror.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Route %s %s is not described in the OpenAPI document", r.Method, r.URL.Path))
+				return
+			}
+			rec.flush(w)
+			return
+		}
+
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, v.MaxBodyBytes)
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			writeRequestError(w, r, err)
+			return
+		}
+
+		if !v.ValidateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := newRecorder()
+		next.ServeHTTP(rec, r)
+		err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 rec.status,
+			Header:                 rec.header,
+			Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+			Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+		})
+		if err != nil {
+			log.Printf("OpenAPI: response of %s %s does not match the document: %v", r.Method, r.URL.Path, err)
+			apierror.Error(w, r, http.StatusInternalServerError, "Response does not match the OpenAPI document: "+err.Error())
+			return
+		}
+		rec.flush(w)
+	})
+}
+
+// writeRequestError переводит ошибки валидации запроса в problem+json
+func writeRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		apierror.Error(w, r, http.StatusRequestEntityTooLarge, "Request body is too large")
+		return
+	}
+
+	var fields []apierror.FieldError
+	for _, e := range flatten(err) {
+		var reqErr *openapi3filter.RequestError
+		if !errors.As(e, &reqErr) {
+			apierror.Error(w, r, http.StatusBadRequest, e.Error())
+			return
+		}
+		if reqErr.RequestBody != nil && strings.HasPrefix(reqErr.Reason, "header Content-Type has unexpected value") {
+			apierror.Error(w, r, http.StatusUnsupportedMediaType, "Unsupported Content-Type")
+			return
+		}
+
+		if reqErr.Err == nil {
+			if reqErr.Parameter == nil {
+				apierror.Error(w, r, http.StatusBadRequest, reqErr.Error())
+				return
+			}
+			fields = append(fields, apierror.FieldError{Field: reqErr.Parameter.Name, Code: "invalid", Message: reqErr.Error()})
+			continue
+		}
+
+		for _, sub := range flatten(reqErr.Err) {
+			var schemaErr *openapi3.SchemaError
+			switch {
+			case reqErr.Parameter != nil:
+				fields = append(fields, apierror.FieldError{Field: reqErr.Parameter.Name, Code: "invalid", Message: sub.Error()})
+			case errors.As(sub, &schemaErr):
+				fields = append(fields, apierror.FieldError{Field: strings.Join(schemaErr.JSONPointer(), "."), Code: "invalid", Message: schemaErr.Reason})
+			default:
+				apierror.Error(w, r, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+	}
+	apierror.Validation(w, r, fields)
+}
+
+// flatten раскрывает вложенные openapi3.MultiError в плоский список
+func flatten(err error) []error {
+	// errors.As здесь не подходит: он нашёл бы MultiError внутри RequestError
+	// и потерял бы сведения о параметре
+	multi, ok := err.(openapi3.MultiError)
+	if !ok {
+		return []error{err}
+	}
+	var out []error
+	for _, e := range multi {
+		out = append(out, flatten(e)...)
+	}
+	return out
+}
+
+// recorder буферизует ответ обработчика, чтобы проверить его до отправки клиенту
+type recorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (rec *recorder) Header() http.Header { return rec.header }
+
+func (rec *recorder) Write(b []byte) (int, error) { return rec.body.Write(b) }
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+}
+
+// flush отправляет буферизованный ответ клиенту
+func (rec *recorder) flush(w http.ResponseWriter) {
+	for k, v := range rec.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rec.status)
+	w.Write(rec.body.Bytes())
+}
@@ -0,0 +1,68 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gorilla/mux"
+)
+
+// Undocumented обходит маршруты router и возвращает те (метод и шаблон пути), которых нет в doc.
+// Тесты сервисов вызывают его, чтобы новый маршрут не остался без описания в документе.
+// Маршруты без методов (префиксы подмаршрутизаторов) пропускаются.
+func Undocumented(doc *openapi3.T, router *mux.Router) ([]string, error) {
+	var missing []string
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return fmt.Errorf("route without path template: %w", err)
+		}
+		path := specPath(template)
+		item := doc.Paths.Find(path)
+		for _, method := range methods {
+			if item == nil || item.GetOperation(method) == nil {
+				missing = append(missing, method+" "+path)
+			}
+		}
+		return nil
+	})
+	sort.Strings(missing)
+	return missing, err
+}
+
+// specPath переводит шаблон gorilla/mux в путь OpenAPI: {id:[0-9]+} -> {id}.
+// В регулярном выражении могут быть свои фигурные скобки ({32}), поэтому учитывается вложенность.
+func specPath(template string) string {
+	var b strings.Builder
+	depth := 0
+	inPattern := false
+	for _, c := range template {
+		switch {
+		case c == '{':
+			depth++
+			if depth == 1 {
+				b.WriteRune(c)
+			}
+			continue
+		case c == '}':
+			depth--
+			if depth == 0 {
+				inPattern = false
+				b.WriteRune(c)
+			}
+			continue
+		case c == ':' && depth == 1:
+			inPattern = true
+		}
+		if !inPattern && depth <= 1 {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
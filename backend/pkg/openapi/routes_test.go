@@ -0,0 +1,53 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+const routesSpec = `
+openapi: 3.0.3
+info: {title: test, version: "1"}
+paths:
+  /items:
+    get:
+      responses: {"200": {description: ok}}
+  /items/{id}:
+    get:
+      parameters: [{name: id, in: path, required: true, schema: {type: integer}}]
+      responses: {"200": {description: ok}}
+  /items/{id}/files/{fileId}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+        - {name: fileId, in: path, required: true, schema: {type: string}}
+      responses: {"200": {description: ok}}
+`
+
+func TestUndocumented(t *testing.T) {
+	doc, err := Load([]byte(routesSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noop := func(http.ResponseWriter, *http.Request) {}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/items", noop).Methods("GET", "POST")
+	r.HandleFunc("/items/{id:[0-9]+}", noop).Methods("GET")
+	r.HandleFunc("/items/{id:[0-9]+}", noop).Methods("DELETE")
+	sub := r.PathPrefix("/items/{id:[0-9]+}/files").Subrouter()
+	sub.HandleFunc("/{fileId:[0-9a-f]{32}}", noop).Methods("GET")
+	r.HandleFunc("/hidden", noop).Methods("GET")
+
+	missing, err := Undocumented(doc, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "DELETE /items/{id},GET /hidden,POST /items"
+	if got := strings.Join(missing, ","); got != want {
+		t.Errorf("ожидались неописанные маршруты %s, получены %s", want, got)
+	}
+}
@@ -0,0 +1,230 @@
+// Package server запускает HTTP-сервер сервиса и плавно останавливает его по сигналу
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"blog/pkg/health"
+)
+
+// Config — параметры HTTP-сервера и плавной остановки
+type Config struct {
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+	// DrainDelay — сколько /ready отвечает 503 перед остановкой приёма соединений,
+	// чтобы балансировщик успел вывести экземпляр из ротации
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// ShutdownTimeout ограничивает ожидание текущих запросов и шагов остановки (Hook)
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Validate проверяет тайм-ауты и лимиты сервера; в ошибках указаны переменные окружения, которые их задают
+func (s Config) Validate() []error {
+	var errs []error
+	for _, f := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", s.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", s.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", s.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", s.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", s.ShutdownTimeout},
+	} {
+		if f.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", f.key))
+		}
+	}
+	if s.DrainDelay < 0 {
+		errs = append(errs, errors.New("SHUTDOWN_DRAIN_DELAY must not be negative"))
+	}
+	if s.MaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("HTTP_MAX_HEADER_BYTES must be positive"))
+	}
+	return errs
+}
+
+// New создаёт HTTP-сервер с ограничениями времени и размера заголовков из настроек
+func New(addr string, handler http.Handler, cfg Config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// Readiness сообщает балансировщику, принимает ли экземпляр новые запросы
+type Readiness struct {
+	// Checks — зависимости сервиса; без них экземпляр готов, пока не начал останавливаться
+	Checks   *health.Checker
+	draining atomic.Bool
+}
+
+// Drain переводит экземпляр в состояние остановки: /ready начинает отвечать 503
+func (r *Readiness) Drain() {
+	r.draining.Store(true)
+}
+
+// Draining сообщает, что экземпляр останавливается
+func (r *Readiness) Draining() bool {
+	return r.draining.Load()
+}
+
+// Report возвращает состояние зависимостей с учётом остановки экземпляра
+func (r *Readiness) Report(ctx context.Context) health.Report {
+	report := health.Report{Status: health.StatusOK, Checks: map[string]health.Result{}}
+	if r.Checks != nil {
+		report = r.Checks.Report(ctx)
+	}
+	if r.Draining() {
+		report.Status = health.StatusDraining
+	}
+	return report
+}
+
+// Handler — Readiness Probe: сервис готов обслуживать запросы, пока доступны критичные
+// зависимости и экземпляр не начал останавливаться
+func (r *Readiness) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch r.Report(req.Context()).Status {
+		case health.StatusDraining:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Shutting down"))
+		case health.StatusDown:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Dependencies unavailable"))
+		default:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Ready"))
+		}
+	}
+}
+
+// DetailsHandler отдаёт состояние каждой зависимости: статус, время ответа и последнюю ошибку.
+// Код ответа совпадает с /ready.
+func (r *Readiness) DetailsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		report := r.Report(req.Context())
+		status := http.StatusOK
+		if report.Status == health.StatusDown || report.Status == health.StatusDraining {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Hook — шаг остановки, который выполняется после HTTP-сервера
+type Hook struct {
+	Name string
+	Stop func(ctx context.Context) error
+}
+
+// Task — фоновая задача, которая работает до отмены своего контекста
+type Task struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Go запускает run в отдельной горутине
+func Go(run func(ctx context.Context)) *Task {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Task{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		run(ctx)
+	}()
+	return t
+}
+
+// Stop отменяет контекст задачи и ждёт её завершения, но не дольше ctx
+func (t *Task) Stop(ctx context.Context) error {
+	t.cancel()
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run обслуживает запросы до SIGINT или SIGTERM и затем останавливает сервис:
+// /ready начинает отвечать 503, через DrainDelay сервер перестаёт принимать соединения
+// и дожидается текущих запросов, после чего по порядку выполняются hooks.
+// На ожидание запросов и hooks отводится ShutdownTimeout.
+// Повторный сигнал во время остановки завершает процесс сразу.
+func Run(srv *http.Server, ready *Readiness, cfg Config, hooks ...Hook) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// После первого сигнала возвращается обработка по умолчанию
+		<-ctx.Done()
+		stop()
+	}()
+	return serve(ctx, srv, ln, ready, cfg, hooks)
+}
+
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, ready *Readiness, cfg Config, hooks []Hook) error {
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutdown signal received, draining for %s", cfg.DrainDelay)
+	ready.Drain()
+	time.Sleep(cfg.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Запросы не успели завершиться: соединения закрываются принудительно
+		srv.Close()
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+	}
+	log.Println("HTTP server stopped")
+
+	for _, hook := range hooks {
+		if shutdownCtx.Err() != nil {
+			// Предыдущий шаг не уложился в ShutdownTimeout и может ещё работать:
+			// следующие шаги (например, закрытие базы) выбили бы ресурсы у него из-под ног
+			log.Printf("Skipped %s: shutdown timeout exceeded", hook.Name)
+			errs = append(errs, fmt.Errorf("%s: skipped after shutdown timeout", hook.Name))
+			continue
+		}
+		if err := hook.Stop(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+			continue
+		}
+		log.Printf("Stopped %s", hook.Name)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"blog/pkg/health"
+)
+
+func TestServeDrainsBeforeShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "http://" + ln.Addr().String()
+
+	ready := &Readiness{}
+	started, release := make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	mux.Handle("/ready", ready.Handler())
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	var mu sync.Mutex
+	var steps []string
+	hook := func(name string) Hook {
+		return Hook{Name: name, Stop: func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			steps = append(steps, name)
+			return nil
+		}}
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	cfg := Config{DrainDelay: 200 * time.Millisecond, ShutdownTimeout: 5 * time.Second}
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, New("", mux, cfg), ln, ready, cfg, []Hook{hook("workers"), hook("database")})
+	}()
+
+	if body, status := get(t, base+"/ready"); status != http.StatusOK || body != "Ready" {
+		t.Fatalf("до остановки ожидался ответ 200 Ready, получен %d %s", status, body)
+	}
+
+	// Запрос, начатый до сигнала, должен завершиться
+	slow := make(chan string, 1)
+	go func() {
+		body, _ := get(t, base+"/slow")
+		slow <- body
+	}()
+	<-started
+	stop()
+
+	// Во время паузы сервер ещё принимает запросы, но /ready уже сообщает об остановке
+	time.Sleep(50 * time.Millisecond)
+	if body, status := get(t, base+"/ready"); status != http.StatusServiceUnavailable {
+		t.Errorf("во время остановки ожидался код 503, получен %d %s", status, body)
+	}
+	mu.Lock()
+	if len(steps) != 0 {
+		t.Errorf("шаги остановки выполнены до завершения запросов: %v", steps)
+	}
+	mu.Unlock()
+
+	close(release)
+	if body := <-slow; body != "done" {
+		t.Errorf("текущий запрос прерван: %q", body)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(steps, ","); got != "workers,database" {
+		t.Errorf("ожидался порядок остановки workers,database, получен %s", got)
+	}
+}
+
+func TestServeSkipsHooksAfterTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Воркер не реагирует на отмену и не укладывается в ShutdownTimeout
+	block := make(chan struct{})
+	defer close(block)
+	worker := Go(func(context.Context) { <-block })
+	var dbClosed atomic.Bool
+
+	ctx, stop := context.WithCancel(context.Background())
+	stop()
+	cfg := Config{ShutdownTimeout: 50 * time.Millisecond}
+	err = serve(ctx, New("", http.NewServeMux(), cfg), ln, &Readiness{}, cfg, []Hook{
+		{Name: "worker", Stop: worker.Stop},
+		{Name: "database", Stop: func(context.Context) error {
+			dbClosed.Store(true)
+			return nil
+		}},
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ожидалась ошибка DeadlineExceeded, получено %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "database: skipped") {
+		t.Errorf("в ошибке нет пропущенного шага: %v", err)
+	}
+	if dbClosed.Load() {
+		t.Error("база закрыта, пока воркер ещё работает")
+	}
+}
+
+func TestReadinessChecks(t *testing.T) {
+	var dbDown atomic.Bool
+	ready := &Readiness{Checks: health.NewChecker(
+		health.Check{Name: "database", Critical: true, Run: func(context.Context) error {
+			if dbDown.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		}},
+	)}
+	ready.Checks.TTL = 0
+
+	tests := []struct {
+		name       string
+		dbDown     bool
+		draining   bool
+		wantStatus int
+		wantBody   string
+		wantReport string
+	}{
+		{name: "зависимости доступны", wantStatus: http.StatusOK, wantBody: "Ready", wantReport: health.StatusOK},
+		{name: "база недоступна", dbDown: true, wantStatus: http.StatusServiceUnavailable, wantBody: "Dependencies unavailable", wantReport: health.StatusDown},
+		{name: "экземпляр останавливается", draining: true, wantStatus: http.StatusServiceUnavailable, wantBody: "Shutting down", wantReport: health.StatusDraining},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbDown.Store(tt.dbDown)
+			if tt.draining {
+				ready.Drain()
+			}
+
+			w := httptest.NewRecorder()
+			ready.Handler()(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Errorf("/ready: ожидался ответ %d %s, получен %d %s", tt.wantStatus, tt.wantBody, w.Code, w.Body.String())
+			}
+
+			w = httptest.NewRecorder()
+			ready.DetailsHandler()(w, httptest.NewRequest(http.MethodGet, "/health/details", nil))
+			var report health.Report
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.wantStatus || report.Status != tt.wantReport {
+				t.Errorf("/health/details: ожидался ответ %d %s, получен %d %+v", tt.wantStatus, tt.wantReport, w.Code, report)
+			}
+			if _, ok := report.Checks["database"]; !ok {
+				t.Errorf("в ответе нет проверки базы: %+v", report)
+			}
+		})
+	}
+}
+
+func TestTaskStop(t *testing.T) {
+	stopped := make(chan struct{})
+	task := Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+	if err := task.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("Stop вернулся до завершения задачи")
+	}
+
+	// Задача, которая не реагирует на отмену, ограничена контекстом остановки
+	block := make(chan struct{})
+	defer close(block)
+	hung := Go(func(context.Context) { <-block })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := hung.Stop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ожидалась ошибка DeadlineExceeded, получено %v", err)
+	}
+}
+
+func get(t *testing.T, url string) (string, int) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Errorf("запрос %s не выполнен: %v", url, err)
+		return "", 0
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), resp.StatusCode
+}
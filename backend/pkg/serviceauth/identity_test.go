@@ -0,0 +1,66 @@
+package serviceauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyIdentity(t *testing.T) {
+	keys := mustKeyRing(t, "gateway", "k1:gateway:"+secretA+",p1:posts-service:"+secretC, "")
+	other := mustKeyRing(t, "gateway", "k1:gateway:"+secretB, "")
+	// posts-service знает только свой ключ и пытается выдать себя за шлюз
+	impostor := mustKeyRing(t, "gateway", "p1:gateway:"+secretC, "")
+
+	tests := []struct {
+		name    string
+		prepare func(r *http.Request)
+		wantErr error
+	}{
+		{name: "подписанный пользователь", prepare: func(r *http.Request) { keys.SignIdentity(r, 7, "admin") }},
+		{name: "без подписи", prepare: func(r *http.Request) {
+			r.Header.Set(HeaderUserID, "7")
+			r.Header.Set(HeaderUserRole, "admin")
+		}, wantErr: ErrUnsigned},
+		{name: "подменённая роль", prepare: func(r *http.Request) {
+			keys.SignIdentity(r, 7, "user")
+			r.Header.Set(HeaderUserRole, "admin")
+		}, wantErr: ErrInvalidSignature},
+		{name: "подменённый ID", prepare: func(r *http.Request) {
+			keys.SignIdentity(r, 7, "user")
+			r.Header.Set(HeaderUserID, "1")
+		}, wantErr: ErrInvalidSignature},
+		{name: "подпись для другого пути", prepare: func(r *http.Request) {
+			keys.SignIdentity(r, 7, "user")
+			r.URL.Path = "/admin"
+		}, wantErr: ErrInvalidSignature},
+		{name: "чужой ключ", prepare: func(r *http.Request) { other.SignIdentity(r, 7, "user") }, wantErr: ErrInvalidSignature},
+		{name: "ключ другого сервиса", prepare: func(r *http.Request) { impostor.SignIdentity(r, 7, "user") }, wantErr: ErrInvalidSignature},
+		{name: "просроченная подпись", prepare: func(r *http.Request) {
+			keys.SignIdentity(r, 7, "user")
+			parts := strings.Split(r.Header.Get(HeaderIdentity), ",")
+			parts[2] = strconv.FormatInt(time.Now().Add(-2*MaxClockSkew).Unix(), 10)
+			r.Header.Set(HeaderIdentity, strings.Join(parts, ","))
+		}, wantErr: ErrInvalidSignature},
+		{name: "неполная подпись", prepare: func(r *http.Request) { r.Header.Set(HeaderIdentity, "gateway,k1") }, wantErr: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/posts?limit=5", nil)
+			tt.prepare(r)
+
+			identity, err := keys.VerifyIdentity(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ожидалась ошибка %v, получено %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && (identity.Service != "gateway" || identity.UserID != 7 || identity.Role != "admin") {
+				t.Errorf("неверные данные пользователя: %+v", identity)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+// Package serviceauth подписывает и проверяет запросы между сервисами блога.
+//
+// Каждый ключ набора SERVICE_KEYS ("kid:service:secret,...") принадлежит одному сервису.
+// Сервис подписывает запросы HMAC-SHA256 своим ключом, а получатель принимает подпись,
+// только если имя отправителя совпадает с владельцем ключа. Поэтому в SERVICE_KEYS сервиса
+// должны быть только его собственные ключи и ключи тех сервисов, чьи запросы он проверяет:
+// сервис, не знающий секрета auth-service, не может подписаться его именем.
+// Подпись покрывает метод, путь, query, время отправки, случайный nonce, имя сервиса-отправителя
+// и SHA-256 тела запроса. Получатель принимает каждый nonce один раз, пока подпись не истекла,
+// поэтому перехваченный запрос нельзя отправить повторно. Nonce запоминается в памяти
+// экземпляра: повтор на другой экземпляр получателя ограничен только временем подписи.
+// Новые запросы подписываются ключом SERVICE_ACTIVE_KEY_ID (по умолчанию — последним
+// собственным ключом), а проверяются любым ключом из набора. Ротация ключа сервиса:
+//  1. добавить новый ключ в SERVICE_KEYS сервиса и всех его получателей;
+//  2. переключить SERVICE_ACTIVE_KEY_ID сервиса на новый ключ;
+//  3. после перезапуска всех экземпляров сервиса удалить старый ключ из SERVICE_KEYS.
+package serviceauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"blog/pkg/apierror"
+)
+
+// Заголовки подписанного запроса
+const (
+	HeaderService   = "X-Service-Name"
+	HeaderKeyID     = "X-Service-Key-Id"
+	HeaderTimestamp = "X-Service-Timestamp"
+	HeaderNonce     = "X-Service-Nonce"
+	HeaderSignature = "X-Service-Signature"
+)
+
+// MaxClockSkew — допустимое расхождение времени отправителя и получателя
+const MaxClockSkew = 5 * time.Minute
+
+// MaxBodyBytes — наибольший размер тела запроса, который читается для проверки подписи
+const MaxBodyBytes = 1 << 20
+
+// maxNonceLen ограничивает память, которую занимает один запомненный nonce
+const maxNonceLen = 64
+
+type contextKey string
+
+// ServiceKey — ключ контекста с именем проверенного сервиса-отправителя
+const ServiceKey contextKey = "service"
+
+var (
+	// ErrUnsigned возвращается для запросов без подписи
+	ErrUnsigned = errors.New("request is not signed")
+	// ErrInvalidSignature возвращается при неверной или просроченной подписи
+	ErrInvalidSignature = errors.New("invalid request signature")
+	// ErrReplayed возвращается для повторно отправленного подписанного запроса
+	ErrReplayed = errors.New("request nonce was already used")
+	// ErrBodyTooLarge возвращается, если тело запроса больше MaxBodyBytes
+	ErrBodyTooLarge = errors.New("request body is too large")
+)
+
+// serviceKey — секрет и сервис, которому он принадлежит
+type serviceKey struct {
+	service string
+	secret  []byte
+}
+
+// KeyRing — ключи сервиса и его отправителей с одним активным ключом для подписи
+type KeyRing struct {
+	service string
+	keys    map[string]serviceKey
+	active  string
+	nonces  *nonceCache
+}
+
+// ParseKeyRing разбирает набор ключей вида "kid:service:secret,..." для сервиса service.
+// Подписывать можно только ключом самого service; если active пуст, используется
+// последний ключ service в списке.
+func ParseKeyRing(service, spec, active string) (*KeyRing, error) {
+	ring := &KeyRing{service: service, keys: make(map[string]serviceKey), nonces: &nonceCache{seen: make(map[string]time.Time)}}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kid, rest, _ := strings.Cut(part, ":")
+		owner, secret, ok := strings.Cut(rest, ":")
+		if !ok || kid == "" || owner == "" || len(secret) < 32 {
+			return nil, fmt.Errorf("invalid service key %q: expected kid:service:secret with at least 32 characters", kid)
+		}
+		if _, dup := ring.keys[kid]; dup {
+			return nil, fmt.Errorf("duplicate service key %q", kid)
+		}
+		ring.keys[kid] = serviceKey{service: owner, secret: []byte(secret)}
+		if owner == service {
+			ring.active = kid
+		}
+	}
+	if len(ring.keys) == 0 {
+		return nil, errors.New("no service keys configured")
+	}
+	if active != "" {
+		key, ok := ring.keys[active]
+		if !ok {
+			return nil, fmt.Errorf("active service key %q not found", active)
+		}
+		if key.service != service {
+			return nil, fmt.Errorf("active service key %q belongs to %q, not %q", active, key.service, service)
+		}
+		ring.active = active
+	}
+	if ring.active == "" {
+		return nil, fmt.Errorf("no service key for %q", service)
+	}
+	return ring, nil
+}
+
+// Service возвращает имя сервиса, от которого подписываются запросы
+func (k *KeyRing) Service() string {
+	return k.service
+}
+
+// owner возвращает сервис, которому принадлежит ключ kid
+func (k *KeyRing) owner(kid string) (string, bool) {
+	key, ok := k.keys[kid]
+	return key.service, ok
+}
+
+func canonical(r *http.Request, service, timestamp, nonce string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		timestamp,
+		nonce,
+		service,
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+}
+
+func (k *KeyRing) mac(kid, message string) []byte {
+	m := hmac.New(sha256.New, k.keys[kid].secret)
+	m.Write([]byte(message))
+	return m.Sum(nil)
+}
+
+// Sign подписывает запрос активным ключом от имени своего сервиса
+func (k *KeyRing) Sign(r *http.Request, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := newNonce()
+	r.Header.Set(HeaderService, k.service)
+	r.Header.Set(HeaderKeyID, k.active)
+	r.Header.Set(HeaderTimestamp, timestamp)
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set(HeaderSignature, hex.EncodeToString(k.mac(k.active, canonical(r, k.service, timestamp, nonce, body))))
+}
+
+func newNonce() string {
+	b := make([]byte, 16)
+	// crypto/rand не возвращает ошибок на поддерживаемых платформах
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Verify проверяет подпись входящего запроса и возвращает имя сервиса-отправителя.
+// Имя из заголовка должно совпадать с владельцем ключа, которым подписан запрос.
+// Тело запроса (не больше MaxBodyBytes) читается и восстанавливается для последующих обработчиков.
+func (k *KeyRing) Verify(r *http.Request) (string, error) {
+	service := r.Header.Get(HeaderService)
+	kid := r.Header.Get(HeaderKeyID)
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	signature := r.Header.Get(HeaderSignature)
+	if service == "" || kid == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", ErrUnsigned
+	}
+	if len(nonce) > maxNonceLen {
+		return "", ErrInvalidSignature
+	}
+
+	if owner, ok := k.owner(kid); !ok || owner != service {
+		return "", ErrInvalidSignature
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	signedAt := time.Unix(ts, 0)
+	if skew := time.Since(signedAt); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return "", ErrInvalidSignature
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(http.MaxBytesReader(nil, r.Body, MaxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return "", ErrBodyTooLarge
+			}
+			return "", fmt.Errorf("failed to read request body: %w", err)
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, k.mac(kid, canonical(r, service, timestamp, nonce, body))) {
+		return "", ErrInvalidSignature
+	}
+	// Nonce запоминается только после проверки подписи, иначе чужие запросы вытесняли бы настоящие
+	if !k.nonces.add(service+":"+nonce, signedAt.Add(MaxClockSkew)) {
+		return "", ErrReplayed
+	}
+	return service, nil
+}
+
+// nonceCache помнит nonce принятых запросов до истечения их подписи
+type nonceCache struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	cleanAt time.Time
+}
+
+// add запоминает nonce до expires и сообщает, что он ещё не встречался
+func (c *nonceCache) add(nonce string, expires time.Time) bool {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.After(c.cleanAt) {
+		for n, exp := range c.seen {
+			if now.After(exp) {
+				delete(c.seen, n)
+			}
+		}
+		c.cleanAt = now.Add(time.Minute)
+	}
+	if exp, ok := c.seen[nonce]; ok && !now.After(exp) {
+		return false
+	}
+	c.seen[nonce] = expires
+	return true
+}
+
+// Middleware пропускает только запросы, подписанные одним из сервисов allowed
+func Middleware(keys *KeyRing, allowed ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			service, err := keys.Verify(r)
+			if errors.Is(err, ErrBodyTooLarge) {
+				log.Printf("ServiceAuth: rejected %s %s: %v", r.Method, r.URL.Path, err)
+				apierror.Error(w, r, http.StatusRequestEntityTooLarge, "Request body is too large")
+				return
+			}
+			if err != nil {
+				log.Printf("ServiceAuth: rejected %s %s: %v", r.Method, r.URL.Path, err)
+				apierror.Error(w, r, http.StatusUnauthorized, "Service authentication required")
+				return
+			}
+
+			permitted := len(allowed) == 0
+			for _, name := range allowed {
+				if name == service {
+					permitted = true
+					break
+				}
+			}
+			if !permitted {
+				log.Printf("ServiceAuth: service %q is not allowed to call %s", service, r.URL.Path)
+				apierror.Error(w, r, http.StatusForbidden, "Service is not allowed to call this endpoint")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ServiceKey, service)))
+		})
+	}
+}
+
+// Transport подписывает все исходящие запросы ключами Keys
+type Transport struct {
+	Base http.RoundTripper
+	Keys *KeyRing
+}
+
+// RoundTrip реализует http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body.Close()
+	}
+
+	signed := req.Clone(req.Context())
+	signed.Body = io.NopCloser(bytes.NewReader(body))
+	t.Keys.Sign(signed, body)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(signed)
+}
+
+// NewClient создаёт HTTP-клиент для запросов к другим сервисам, подписывающий их ключами keys.
+// Подписанные запросы отправляются через base (nil — http.DefaultTransport).
+func NewClient(keys *KeyRing, base http.RoundTripper) *http.Client {
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &Transport{Base: base, Keys: keys},
+	}
+}
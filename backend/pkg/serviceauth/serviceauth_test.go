@@ -0,0 +1,193 @@
+package serviceauth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	secretA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	secretB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	secretC = "cccccccccccccccccccccccccccccccc"
+	secretD = "dddddddddddddddddddddddddddddddd"
+)
+
+// receiverKeys — ключи users-service: свой и ключи auth-service и posts-service, чьи запросы он проверяет
+const receiverKeys = "u1:users-service:" + secretD + ",k1:auth-service:" + secretA + ",p1:posts-service:" + secretC
+
+func mustKeyRing(t *testing.T, service, spec, active string) *KeyRing {
+	t.Helper()
+	ring, err := ParseKeyRing(service, spec, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+func TestSignedRequestPassesMiddleware(t *testing.T) {
+	sender := mustKeyRing(t, "auth-service", "k1:auth-service:"+secretA+",k2:auth-service:"+secretB, "k2")
+	// Получатель ещё знает старый ключ k1 и уже знает новый k2
+	receiver := mustKeyRing(t, "users-service", receiverKeys+",k2:auth-service:"+secretB, "")
+
+	var gotBody, gotService string
+	server := httptest.NewServer(Middleware(receiver, "auth-service")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotService, _ = r.Context().Value(ServiceKey).(string)
+	})))
+	defer server.Close()
+
+	client := NewClient(sender, nil)
+	resp, err := client.Post(server.URL+"/internal/credentials/verify?x=1", "application/json", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("ожидался код 200, получен %d", resp.StatusCode)
+	}
+	if gotBody != `{"a":1}` || gotService != "auth-service" {
+		t.Errorf("обработчик получил body=%q service=%q", gotBody, gotService)
+	}
+}
+
+func TestMiddlewareRejects(t *testing.T) {
+	keys := mustKeyRing(t, "auth-service", "k1:auth-service:"+secretA, "")
+	other := mustKeyRing(t, "auth-service", "k1:auth-service:"+secretB, "")
+	posts := mustKeyRing(t, "posts-service", "p1:posts-service:"+secretC, "")
+	// posts-service пытается подписаться именем auth-service своим ключом
+	impostor := mustKeyRing(t, "auth-service", "p1:auth-service:"+secretC, "")
+	receiver := mustKeyRing(t, "users-service", receiverKeys, "")
+	handler := Middleware(receiver, "auth-service")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name       string
+		prepare    func(r *http.Request)
+		wantStatus int
+	}{
+		{"без подписи", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"чужой ключ", func(r *http.Request) { other.Sign(r, []byte("{}")) }, http.StatusUnauthorized},
+		{"изменённое тело", func(r *http.Request) { keys.Sign(r, []byte(`{"x":1}`)) }, http.StatusUnauthorized},
+		{"просроченная подпись", func(r *http.Request) {
+			keys.Sign(r, []byte("{}"))
+			r.Header.Set(HeaderTimestamp, "1")
+		}, http.StatusUnauthorized},
+		{"неразрешённый сервис", func(r *http.Request) { posts.Sign(r, []byte("{}")) }, http.StatusForbidden},
+		{"чужое имя сервиса", func(r *http.Request) {
+			posts.Sign(r, []byte("{}"))
+			r.Header.Set(HeaderService, "auth-service")
+		}, http.StatusUnauthorized},
+		{"ключ другого сервиса", func(r *http.Request) { impostor.Sign(r, []byte("{}")) }, http.StatusUnauthorized},
+		{"без nonce", func(r *http.Request) {
+			keys.Sign(r, []byte("{}"))
+			r.Header.Del(HeaderNonce)
+		}, http.StatusUnauthorized},
+		{"подменённый nonce", func(r *http.Request) {
+			keys.Sign(r, []byte("{}"))
+			r.Header.Set(HeaderNonce, "0123456789abcdef")
+		}, http.StatusUnauthorized},
+		{"верная подпись", func(r *http.Request) { keys.Sign(r, []byte("{}")) }, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/internal/credentials/verify", strings.NewReader("{}"))
+			tt.prepare(req)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRejectsReplay(t *testing.T) {
+	keys := mustKeyRing(t, "auth-service", "k1:auth-service:"+secretA, "")
+	handler := Middleware(mustKeyRing(t, "users-service", receiverKeys, ""), "auth-service")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	first := httptest.NewRequest(http.MethodPost, "/internal/credentials/verify", strings.NewReader("{}"))
+	keys.Sign(first, []byte("{}"))
+	// Перехваченный запрос с теми же заголовками и телом
+	replay := httptest.NewRequest(http.MethodPost, "/internal/credentials/verify", strings.NewReader("{}"))
+	replay.Header = first.Header.Clone()
+
+	for _, tt := range []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{"первый запрос", first, http.StatusOK},
+		{"повтор", replay, http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, tt.req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: ожидался код %d, получен %d", tt.name, tt.wantStatus, w.Code)
+		}
+	}
+}
+
+func TestNonceCacheForgetsExpired(t *testing.T) {
+	c := &nonceCache{seen: make(map[string]time.Time)}
+	if !c.add("a", time.Now().Add(-time.Second)) {
+		t.Fatal("новый nonce должен быть принят")
+	}
+	// Подпись с этим nonce уже истекла, запись больше не нужна
+	if !c.add("b", time.Now().Add(time.Minute)) || !c.add("a", time.Now().Add(time.Minute)) {
+		t.Error("истёкший nonce должен быть забыт")
+	}
+	if c.add("b", time.Now().Add(time.Minute)) {
+		t.Error("повторный nonce должен быть отклонён")
+	}
+}
+
+func TestMiddlewareRejectsLargeBody(t *testing.T) {
+	keys := mustKeyRing(t, "users-service", receiverKeys, "")
+	handler := Middleware(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	body := strings.Repeat("a", MaxBodyBytes+1)
+	req := httptest.NewRequest(http.MethodPost, "/internal/events", strings.NewReader(body))
+	keys.Sign(req, []byte(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("ожидался код %d, получен %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestParseKeyRing(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		active  string
+		wantErr string
+	}{
+		{name: "пустой набор", spec: "", wantErr: "no service keys configured"},
+		{name: "короткий секрет", spec: "k1:auth-service:short", wantErr: "at least 32 characters"},
+		{name: "ключ без сервиса", spec: "k1:" + secretA, wantErr: "expected kid:service:secret"},
+		{name: "повтор ключа", spec: "k1:auth-service:" + secretA + ",k1:auth-service:" + secretB, wantErr: `duplicate service key "k1"`},
+		{name: "неизвестный активный ключ", spec: "k1:auth-service:" + secretA, active: "k9", wantErr: `active service key "k9" not found`},
+		{name: "активный ключ другого сервиса", spec: "k1:auth-service:" + secretA + ",p1:posts-service:" + secretC, active: "p1", wantErr: `belongs to "posts-service"`},
+		{name: "нет собственного ключа", spec: "p1:posts-service:" + secretC, wantErr: `no service key for "auth-service"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseKeyRing("auth-service", tt.spec, tt.active)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ожидалась ошибка с %q, получено %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Активным становится последний собственный ключ, ключи получателей не учитываются
+	ring := mustKeyRing(t, "auth-service", "k1:auth-service:"+secretA+",k2:auth-service:"+secretB+",p1:posts-service:"+secretC, "")
+	if ring.active != "k2" || ring.Service() != "auth-service" {
+		t.Errorf("активным должен быть последний ключ auth-service, получен %q", ring.active)
+	}
+}
@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"blog/pkg/apierror"
+	"blog/pkg/health"
+	"blog/pkg/jwks"
+	"blog/pkg/metrics"
+	"blog/pkg/migrate"
+	"blog/pkg/openapi"
+	"blog/pkg/server"
+	"blog/pkg/serviceauth"
+	"posts_service/internal/apispec"
+	"posts_service/internal/config"
 	"posts_service/internal/database"
 	"posts_service/internal/handlers"
-	"posts_service/internal/middlewares"
-
-	"github.com/gorilla/mux"
+	"posts_service/internal/repository"
+	"posts_service/migrations"
 )
 
 func loggingMiddleware(next http.Handler) http.Handler {
@@ -25,48 +38,123 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-// Readiness Probe: Service is ready to accept traffic
-func readyHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Ready"))
+// newMigrator собирает миграции сервиса из встроенных файлов для выбранного драйвера базы
+func newMigrator(db *sql.DB, driver string) *migrate.Migrator {
+	sqlite := driver == database.DriverSQLite
+	var files fs.FS = migrations.FS
+	if sqlite {
+		files = migrations.SQLite
+	}
+	list, err := migrate.Load(files)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	return &migrate.Migrator{DB: db, Service: "posts-service", Migrations: list, Log: log.Printf, SQLite: sqlite}
 }
 
-func main() {
-	// Подключение к базе данных
-	db, err := database.Connect()
+// runMigrate выполняет команду migrate: posts-service migrate up | down [N] | status | to VERSION.
+// Ей нужно только подключение к базе, остальные настройки не читаются.
+func runMigrate(args []string) {
+	dbConfig, err := config.LoadDatabase()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	db, err := database.Connect(dbConfig)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
+	if err := migrate.Run(context.Background(), newMigrator(db, dbConfig.Driver), args, os.Stdout); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+}
 
-	r := mux.NewRouter()
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
 
-	// Добавляем middleware для логирования
-	r.Use(loggingMiddleware)
-	r.Use(middlewares.AuthMiddleware)
+	// Настройки проверяются при старте: без обязательных значений сервис не запускается
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	log.Printf("Effective config:\n%s", cfg)
 
-	// Пробы
-	r.HandleFunc("/health", healthHandler).Methods("GET")
-	r.HandleFunc("/ready", readyHandler).Methods("GET")
+	// OpenAPI-документ проверяется при старте: сервис с битым документом не запускается
+	doc, err := apispec.Load()
+	if err != nil {
+		log.Fatalf("Failed to load OpenAPI document: %v", err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		log.Fatalf("Failed to build OpenAPI validator: %v", err)
+	}
+	validator.ValidateResponses = cfg.OpenAPI.ValidateResponses
 
-	// Маршруты для постов
-	r.HandleFunc("/posts", handlers.CreatePost(db)).Methods("POST")
-	r.HandleFunc("/posts", handlers.FetchPosts(db)).Methods("GET")
-	r.HandleFunc("/posts/{id}", handlers.FetchPostById(db)).Methods("GET")
-	r.HandleFunc("/posts/{id}", handlers.DeletePost(db)).Methods("DELETE")
+	// Подключение к базе данных
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	metrics.RegisterDB("posts", db)
 
-	// Маршруты для лайков
-	r.HandleFunc("/likes", handlers.ToggleLike(db)).Methods("POST", "DELETE")
-	r.HandleFunc("/likes", handlers.GetLikesForPost(db)).Methods("GET")
+	// В окружениях без отдельного шага миграций схема обновляется при старте
+	if cfg.MigrateOnStart {
+		if err := newMigrator(db, cfg.Database.Driver).Up(context.Background()); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+	}
 
-	// Маршрут для получения постов конкретного пользователя
-	r.HandleFunc("/profile/{username}/posts", handlers.FetchUserPosts(db)).Methods("GET")
+	// Ключи для проверки JWT публикуются auth_service
+	verifier := &jwks.Verifier{
+		Keys:     jwks.NewCache(cfg.JWT.JWKSURL, 10*time.Minute),
+		Issuer:   cfg.JWT.Issuer,
+		Audience: cfg.JWT.Audience,
+	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8083" // Порт для пост-сервиса
+	// Подпись запросов к другим сервисам; исходящие запросы попадают в метрики
+	serviceKeys, err := serviceauth.ParseKeyRing("posts-service", cfg.ServiceAuth.Keys, cfg.ServiceAuth.ActiveKeyID)
+	if err != nil {
+		log.Fatalf("Failed to load service keys: %v", err)
 	}
+	serviceClient := serviceauth.NewClient(serviceKeys, metrics.Transport(nil))
+
+	// Обработчики работают с данными через репозитории
+	repo := repository.NewSQL(db)
 
-	log.Printf("Posts Service running on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	// Readiness Probe: при остановке /ready первым начинает отвечать 503.
+	// Без базы сервис не работает; без уведомлений лайки сохраняются, а ключи JWT
+	// кэшируются, поэтому эти зависимости видны только в /health/details.
+	ready := &server.Readiness{Checks: health.NewChecker(
+		health.Check{Name: "database", Critical: true, Run: db.PingContext},
+		health.Check{Name: "notifications_service", Run: health.HTTP(http.DefaultClient, cfg.NotificationsServiceURL+"/health")},
+		health.Check{Name: "auth_service", Run: health.HTTP(http.DefaultClient, cfg.JWT.JWKSURL)},
+	)}
+	r := routes{
+		spec:  openapi.Handler(doc),
+		ready: ready,
+		api: handlers.Routes{
+			Repo:          repo,
+			Verifier:      verifier,
+			ServiceKeys:   serviceKeys,
+			AnonymousRead: cfg.AnonymousRead,
+			Notifications: handlers.NotificationsService{URL: cfg.NotificationsServiceURL, Client: serviceClient},
+		},
+	}.router()
+
+	// Метрики запросов считаются снаружи, чтобы в них попадали и отклонённые проверкой OpenAPI
+	handler := metrics.Middleware(metrics.MuxRoute(r), apierror.WithRequestID(validator.Middleware(r)))
+	srv := server.New(":"+cfg.Port, handler, cfg.Server)
+	log.Printf("Posts Service running on port %s", cfg.Port)
+	// Пул соединений с базой закрывается после завершения текущих запросов
+	err = server.Run(srv, ready, cfg.Server,
+		server.Hook{Name: "database", Stop: func(context.Context) error { return db.Close() }},
+	)
+	if err != nil {
+		log.Fatalf("Posts Service stopped with error: %v", err)
+	}
+	log.Println("Posts Service stopped")
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+
+	"blog/pkg/metrics"
+	"blog/pkg/server"
+	"posts_service/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// routes — зависимости обработчиков сервиса
+type routes struct {
+	// spec отдаёт OpenAPI-документ
+	spec  http.Handler
+	ready *server.Readiness
+	// api — маршруты API; тесты обработчиков собирают сервер из них же
+	api handlers.Routes
+}
+
+// router регистрирует все маршруты сервиса. routes_test.go сверяет их с OpenAPI-документом.
+func (d routes) router() *mux.Router {
+	r := d.api.Router()
+
+	// Добавляем middleware для логирования
+	r.Use(loggingMiddleware)
+
+	// Пробы
+	r.HandleFunc("/health", healthHandler).Methods("GET")
+	r.HandleFunc("/ready", d.ready.Handler()).Methods("GET")
+	r.HandleFunc("/health/details", d.ready.DetailsHandler()).Methods("GET")
+	r.Handle("/openapi.json", d.spec).Methods("GET")
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+	return r
+}
@@ -0,0 +1,18 @@
+// Package apispec хранит OpenAPI-документ posts_service; запросы по нему проверяет blog/pkg/openapi
+package apispec
+
+import (
+	_ "embed"
+
+	"blog/pkg/openapi"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Load разбирает встроенный документ и проверяет его корректность
+func Load() (*openapi3.T, error) {
+	return openapi.Load(spec)
+}
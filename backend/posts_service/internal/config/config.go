@@ -0,0 +1,272 @@
+// Package config собирает настройки posts_service из окружения и необязательного YAML-файла
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog/pkg/envconfig"
+	"blog/pkg/server"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Драйверы базы данных (DB_DRIVER)
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Config — настройки posts_service
+type Config struct {
+	Port   string        `yaml:"port"`
+	Server server.Config `yaml:"server"`
+	// MigrateOnStart обновляет схему при старте в окружениях без отдельного шага миграций
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+	// AnonymousRead разрешает читать публичные посты без токена
+	AnonymousRead bool `yaml:"anonymous_read"`
+	// NotificationsServiceURL — адрес notifications_service, в котором создаются уведомления о лайках
+	NotificationsServiceURL string      `yaml:"notifications_service_url"`
+	OpenAPI                 OpenAPI     `yaml:"openapi"`
+	Database                Database    `yaml:"database"`
+	JWT                     JWT         `yaml:"jwt"`
+	ServiceAuth             ServiceAuth `yaml:"service_auth"`
+}
+
+// OpenAPI — проверка запросов и ответов по OpenAPI-документу
+type OpenAPI struct {
+	// ValidateResponses нужна в тестовых окружениях, в production она только тратит память
+	ValidateResponses bool `yaml:"validate_responses"`
+}
+
+// Database — подключение к базе данных
+type Database struct {
+	Driver   string `yaml:"driver"`
+	Host     string `yaml:"host"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	// SQLitePath — файл базы SQLite. posts_service и users_service должны указывать
+	// на один и тот же файл: у сервисов общая схема.
+	SQLitePath string `yaml:"sqlite_path"`
+}
+
+// JWT — проверка токенов доступа, выпущенных auth_service
+type JWT struct {
+	// JWKSURL — адрес публичных ключей; по умолчанию строится из AUTH_SERVICE_URL
+	JWKSURL  string `yaml:"jwks_url"`
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+}
+
+// ServiceAuth — ключи подписи запросов между сервисами
+type ServiceAuth struct {
+	// Keys — набор ключей вида "kid1:service1:secret1,kid2:service2:secret2"
+	Keys        string `yaml:"keys"`
+	ActiveKeyID string `yaml:"active_key_id"`
+}
+
+func defaults() *Config {
+	return &Config{
+		Port: "8083",
+		Server: server.Config{
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    64 << 10,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		AnonymousRead: true,
+		Database: Database{
+			Driver:     DriverPostgres,
+			SQLitePath: "blog.db",
+		},
+		JWT: JWT{
+			Issuer:   "auth-service",
+			Audience: "blog-api",
+		},
+	}
+}
+
+// Load собирает настройки: значения по умолчанию, затем YAML-файл из CONFIG_FILE, если он задан,
+// затем переменные окружения. Любую переменную KEY можно заменить на KEY_FILE с путём к файлу,
+// в котором лежит значение (так передаются секреты Docker и Kubernetes).
+func Load() (*Config, error) {
+	cfg, err := read()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadDatabase читает настройки так же, как Load, но проверяет только подключение к базе.
+// Команде migrate не нужны ключи JWT, подписи запросов и адрес notifications_service.
+func LoadDatabase() (Database, error) {
+	cfg, err := read()
+	if err != nil {
+		return Database{}, err
+	}
+	if errs := cfg.Database.validate(); len(errs) > 0 {
+		return Database{}, fmt.Errorf("invalid posts-service config: %w", errors.Join(errs...))
+	}
+	return cfg.Database, nil
+}
+
+// read применяет к значениям по умолчанию YAML-файл и переменные окружения без проверки
+func read() (*Config, error) {
+	cfg := defaults()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, fmt.Errorf("invalid posts-service config: %w", err)
+	}
+	return cfg, nil
+}
+
+func (c *Config) applyEnv() error {
+	var errs []error
+	for key, dst := range map[string]*string{
+		"PORT":                      &c.Port,
+		"NOTIFICATIONS_SERVICE_URL": &c.NotificationsServiceURL,
+		"DB_DRIVER":                 &c.Database.Driver,
+		"POSTGRES_HOST":             &c.Database.Host,
+		"POSTGRES_USER":             &c.Database.User,
+		"POSTGRES_PASSWORD":         &c.Database.Password,
+		"POSTGRES_DB":               &c.Database.Name,
+		"SQLITE_PATH":               &c.Database.SQLitePath,
+		"JWT_ISSUER":                &c.JWT.Issuer,
+		"JWT_AUDIENCE":              &c.JWT.Audience,
+		"SERVICE_KEYS":              &c.ServiceAuth.Keys,
+		"SERVICE_ACTIVE_KEY_ID":     &c.ServiceAuth.ActiveKeyID,
+	} {
+		if err := envconfig.Lookup(key, dst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for key, dst := range map[string]*time.Duration{
+		"HTTP_READ_TIMEOUT":        &c.Server.ReadTimeout,
+		"HTTP_READ_HEADER_TIMEOUT": &c.Server.ReadHeaderTimeout,
+		"HTTP_WRITE_TIMEOUT":       &c.Server.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        &c.Server.IdleTimeout,
+		"SHUTDOWN_DRAIN_DELAY":     &c.Server.DrainDelay,
+		"SHUTDOWN_TIMEOUT":         &c.Server.ShutdownTimeout,
+	} {
+		if err := envconfig.LookupDuration(key, dst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := envconfig.LookupInt("HTTP_MAX_HEADER_BYTES", &c.Server.MaxHeaderBytes); err != nil {
+		errs = append(errs, err)
+	}
+	for key, dst := range map[string]*bool{
+		"OPENAPI_VALIDATE_RESPONSES": &c.OpenAPI.ValidateResponses,
+		"MIGRATE_ON_START":           &c.MigrateOnStart,
+		"ANONYMOUS_READ":             &c.AnonymousRead,
+	} {
+		if err := envconfig.LookupBool(key, dst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Ключи для проверки JWT публикуются auth_service; JWKS_URL важнее AUTH_SERVICE_URL
+	var authServiceURL string
+	if err := envconfig.Lookup("AUTH_SERVICE_URL", &authServiceURL); err != nil {
+		errs = append(errs, err)
+	} else if authServiceURL != "" {
+		c.JWT.JWKSURL = strings.TrimRight(authServiceURL, "/") + "/.well-known/jwks.json"
+	}
+	if err := envconfig.Lookup("JWKS_URL", &c.JWT.JWKSURL); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT: %q is not a valid port", c.Port))
+	}
+	errs = append(errs, c.Server.Validate()...)
+	c.NotificationsServiceURL = strings.TrimRight(c.NotificationsServiceURL, "/")
+	if err := envconfig.CheckURL("NOTIFICATIONS_SERVICE_URL", c.NotificationsServiceURL); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, c.Database.validate()...)
+	if c.JWT.JWKSURL == "" {
+		errs = append(errs, errors.New("JWKS_URL or AUTH_SERVICE_URL is required"))
+	} else if err := envconfig.CheckURL("JWKS_URL", c.JWT.JWKSURL); err != nil {
+		errs = append(errs, err)
+	}
+	if c.JWT.Issuer == "" {
+		errs = append(errs, errors.New("JWT_ISSUER is required"))
+	}
+	if c.JWT.Audience == "" {
+		errs = append(errs, errors.New("JWT_AUDIENCE is required"))
+	}
+	if c.ServiceAuth.Keys == "" {
+		errs = append(errs, errors.New("SERVICE_KEYS is required"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid posts-service config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (d Database) validate() []error {
+	var errs []error
+	switch d.Driver {
+	case DriverPostgres:
+		for _, f := range []struct{ key, value string }{
+			{"POSTGRES_HOST", d.Host},
+			{"POSTGRES_USER", d.User},
+			{"POSTGRES_DB", d.Name},
+		} {
+			if f.value == "" {
+				errs = append(errs, fmt.Errorf("%s is required", f.key))
+			}
+		}
+	case DriverSQLite:
+		if d.SQLitePath == "" {
+			errs = append(errs, errors.New("SQLITE_PATH is required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER: unsupported driver %q (expected %s or %s)", d.Driver, DriverPostgres, DriverSQLite))
+	}
+	return errs
+}
+
+// Redacted возвращает копию настроек со скрытыми секретами
+func (c Config) Redacted() Config {
+	c.NotificationsServiceURL = envconfig.RedactURL(c.NotificationsServiceURL)
+	c.Database.Password = envconfig.Redact(c.Database.Password)
+	c.JWT.JWKSURL = envconfig.RedactURL(c.JWT.JWKSURL)
+	c.ServiceAuth.Keys = envconfig.Redact(c.ServiceAuth.Keys)
+	return c
+}
+
+// String возвращает действующие настройки в YAML без секретов, чтобы их можно было вывести в лог при старте
+func (c Config) String() string {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("failed to encode config: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile создаёт файл во временном каталоге теста и возвращает путь к нему
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setRequired задаёт обязательные переменные, без которых сервис не запускается
+func setRequired(t *testing.T) {
+	t.Helper()
+	t.Setenv("NOTIFICATIONS_SERVICE_URL", "http://notifications:8085")
+	t.Setenv("AUTH_SERVICE_URL", "http://auth:8081")
+	t.Setenv("SERVICE_KEYS", "k1:posts-service:0123456789abcdef0123456789abcdef")
+	t.Setenv("DB_DRIVER", "sqlite")
+}
+
+func TestLoad(t *testing.T) {
+	setRequired(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", `
+anonymous_read: false
+database:
+  driver: postgres
+  host: db
+  user: blog
+  name: blog
+`))
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("MIGRATE_ON_START", "true")
+	t.Setenv("POSTGRES_PASSWORD_FILE", writeFile(t, "db_password", "s3cret\n"))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.AnonymousRead || !cfg.MigrateOnStart {
+		t.Errorf("не применены флаги из файла и окружения: %+v", cfg)
+	}
+	if cfg.Database.Driver != DriverPostgres || cfg.Database.Host != "db" || cfg.Database.Password != "s3cret" {
+		t.Errorf("неожиданные настройки базы: %+v", cfg.Database)
+	}
+	if cfg.JWT.JWKSURL != "http://auth:8081/.well-known/jwks.json" {
+		t.Errorf("адрес JWKS должен строиться из AUTH_SERVICE_URL, получен %q", cfg.JWT.JWKSURL)
+	}
+	if cfg.Port != "8083" {
+		t.Errorf("ожидался порт по умолчанию, получен %q", cfg.Port)
+	}
+
+	t.Setenv("JWKS_URL", "https://keys.example.com/jwks.json")
+	if cfg, err := Load(); err != nil || cfg.JWT.JWKSURL != "https://keys.example.com/jwks.json" {
+		t.Errorf("JWKS_URL должен быть важнее AUTH_SERVICE_URL: %+v, %v", cfg, err)
+	}
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "нет адреса notifications_service",
+			env:     map[string]string{"NOTIFICATIONS_SERVICE_URL": ""},
+			wantErr: "NOTIFICATIONS_SERVICE_URL is required",
+		},
+		{
+			name:    "адрес без схемы",
+			env:     map[string]string{"NOTIFICATIONS_SERVICE_URL": "notifications:8085"},
+			wantErr: "NOTIFICATIONS_SERVICE_URL: url must be an absolute http(s) URL",
+		},
+		{
+			name:    "нет адреса ключей JWT",
+			env:     map[string]string{"AUTH_SERVICE_URL": ""},
+			wantErr: "JWKS_URL or AUTH_SERVICE_URL is required",
+		},
+		{
+			name:    "неизвестный драйвер базы",
+			env:     map[string]string{"DB_DRIVER": "mysql"},
+			wantErr: `DB_DRIVER: unsupported driver "mysql"`,
+		},
+		{
+			name:    "Postgres без хоста",
+			env:     map[string]string{"DB_DRIVER": "postgres", "POSTGRES_USER": "blog", "POSTGRES_DB": "blog"},
+			wantErr: "POSTGRES_HOST is required",
+		},
+		{
+			name:    "неверный флаг",
+			env:     map[string]string{"ANONYMOUS_READ": "off"},
+			wantErr: `ANONYMOUS_READ: "off" is not a boolean`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ожидалась ошибка %q, получено %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadDatabase(t *testing.T) {
+	// Для миграций достаточно настроек базы: ключи и адрес notifications_service не нужны
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("SQLITE_PATH", "/var/lib/blog/blog.db")
+
+	db, err := LoadDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Driver != DriverSQLite || db.SQLitePath != "/var/lib/blog/blog.db" {
+		t.Errorf("неожиданные настройки базы: %+v", db)
+	}
+
+	t.Setenv("DB_DRIVER", "postgres")
+	if _, err := LoadDatabase(); err == nil || !strings.Contains(err.Error(), "POSTGRES_HOST is required") {
+		t.Errorf("ожидалась ошибка настроек Postgres, получено %v", err)
+	}
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	cfg := defaults()
+	cfg.Database.Password = "s3cret"
+	cfg.ServiceAuth.Keys = "k1:posts-service:0123456789abcdef0123456789abcdef"
+
+	out := cfg.String()
+	for _, secret := range []string{"s3cret", "0123456789abcdef"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("секрет %q попал в вывод настроек:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "driver: postgres") {
+		t.Errorf("в выводе нет настроек базы:\n%s", out)
+	}
+}
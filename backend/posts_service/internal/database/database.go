@@ -2,21 +2,29 @@ package database
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
+
+	"posts_service/internal/config"
 
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
-func Connect() (*sql.DB, error) {
-	host := os.Getenv("POSTGRES_HOST")
-	user := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	dbname := os.Getenv("POSTGRES_DB")
+// Connect подключается к базе данных, выбранной в настройках, и возвращает соединение
+func Connect(cfg config.Database) (*sql.DB, error) {
+	switch cfg.Driver {
+	case DriverPostgres:
+		return connectPostgres(cfg)
+	case DriverSQLite:
+		return connectSQLite(cfg.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q (expected %s or %s)", cfg.Driver, DriverPostgres, DriverSQLite)
+	}
+}
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=disable", host, user, password, dbname)
+func connectPostgres(cfg config.Database) (*sql.DB, error) {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=disable", cfg.Host, cfg.User, cfg.Password, cfg.Name)
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, err
@@ -33,11 +41,14 @@ type Post struct {
 	Content        string        `json:"content"`
 	AuthorID       int           `json:"authorId"`
 	AuthorUsername string        `json:"authorUsername"`
+	Visibility     string        `json:"visibility"`
 	Likes          []interface{} `json:"likes"`
 }
 
-// FetchPosts возвращает все посты с лайками и информацией об авторе
-func FetchPosts(db *sql.DB) ([]Post, error) {
+// FetchPosts возвращает ленту постов с лайками и информацией об авторе.
+// Посты авторов, которых читатель скрыл или заблокировал, и авторов, заблокировавших читателя, не попадают в ленту.
+// Из чужих постов в ленте только публичные и, для подписчиков, посты «только для подписчиков».
+func FetchPosts(db *sql.DB, viewerID int) ([]Post, error) {
 	rows, err := db.Query(`
         SELECT 
             posts.id, 
@@ -45,82 +56,128 @@ func FetchPosts(db *sql.DB) ([]Post, error) {
             posts.content, 
             posts.author_id AS author_id,
             users.username AS author_username,
-            COALESCE(
-                json_agg(
-                    json_build_object(
-                        'id', likes.user_id, 
-                        'username', liked_users.username
-                    )
-                ) FILTER (WHERE likes.user_id IS NOT NULL), '[]'
-            ) AS likes
+            posts.visibility
         FROM posts
         JOIN users ON posts.author_id = users.id
-        LEFT JOIN likes ON posts.id = likes.post_id
-        LEFT JOIN users AS liked_users ON likes.user_id = liked_users.id
-        GROUP BY posts.id, users.username
-		ORDER BY posts.created_at DESC
-    `)
+        WHERE NOT EXISTS (
+            SELECT 1 FROM user_relations r
+            WHERE (r.user_id = $1 AND r.target_id = posts.author_id AND r.kind IN ('mute', 'block'))
+               OR (r.user_id = posts.author_id AND r.target_id = $1 AND r.kind = 'block')
+        )
+        AND `+visibleTo("$1", true)+`
+		ORDER BY posts.created_at DESC, posts.id DESC
+    `, viewerID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch posts: %w", err)
 	}
+	return scanPosts(db, rows)
+}
+
+// scanPosts читает строки постов и загружает их лайки
+func scanPosts(db *sql.DB, rows *sql.Rows) ([]Post, error) {
 	defer rows.Close()
 
-	var posts []Post
+	// Пустой список отдаётся как [], а не null
+	posts := []Post{}
 	for rows.Next() {
 		var post Post
-		var likesJSON string
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorUsername, &likesJSON)
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorUsername, &post.Visibility)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan post row: %w", err)
 		}
-
-		// Декодируем JSON-строку likes в массив объектов
-		if err := json.Unmarshal([]byte(likesJSON), &post.Likes); err != nil {
-			return nil, fmt.Errorf("failed to parse likes JSON: %w", err)
-		}
-
 		posts = append(posts, post)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error while iterating over rows: %w", err)
 	}
+	// Курсор закрывается до запроса лайков, чтобы не занимать второе соединение пула
+	rows.Close()
 
+	if err := attachLikes(db, posts); err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
+// likesBatchSize ограничивает число ID постов в одном запросе лайков
+// (у Postgres и SQLite есть предел на количество параметров запроса)
+const likesBatchSize = 500
+
+// attachLikes загружает лайки постов и раскладывает их по постам в порядке лайков.
+// Лайки собираются здесь, а не через json_agg в запросе постов, чтобы запросы работали и в SQLite.
+// Имя лайкнувшего пользователя равно null, если его строки в users уже нет.
+func attachLikes(db *sql.DB, posts []Post) error {
+	index := make(map[int]int, len(posts))
+	for i := range posts {
+		posts[i].Likes = []interface{}{}
+		index[posts[i].ID] = i
+	}
+
+	for start := 0; start < len(posts); start += likesBatchSize {
+		batch := posts[start:min(start+likesBatchSize, len(posts))]
+		ids := make([]interface{}, len(batch))
+		for i, post := range batch {
+			ids[i] = post.ID
+		}
+
+		rows, err := db.Query(`
+            SELECT likes.post_id, likes.user_id, liked_users.username
+            FROM likes
+            LEFT JOIN users AS liked_users ON likes.user_id = liked_users.id
+            WHERE likes.post_id IN (`+placeholders(len(ids))+`)
+            ORDER BY likes.id
+        `, ids...)
+		if err != nil {
+			return fmt.Errorf("failed to fetch likes: %w", err)
+		}
+		for rows.Next() {
+			var (
+				postID, userID int
+				username       *string
+			)
+			if err := rows.Scan(&postID, &userID, &username); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan like: %w", err)
+			}
+			post := &posts[index[postID]]
+			post.Likes = append(post.Likes, map[string]interface{}{"id": userID, "username": username})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error while iterating over likes: %w", err)
+		}
+	}
+	return nil
+}
+
 // CreatePost добавляет новый пост в базу данных и возвращает его информацию
-func CreatePost(db *sql.DB, title, content string, authorID int) (*Post, error) {
+func CreatePost(db *sql.DB, title, content string, authorID int, visibility string) (*Post, error) {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
 	logger.WithFields(logrus.Fields{
-		"title":    title,
-		"content":  content,
-		"authorID": authorID,
+		"title":      title,
+		"content":    content,
+		"authorID":   authorID,
+		"visibility": visibility,
 	}).Info("Inserting post into database")
 
+	// Имя автора берётся из users в той же строке: если автора нет, вставку отклонит внешний ключ
 	var post Post
 	err := db.QueryRow(`
-        WITH inserted_post AS (
-            INSERT INTO posts (title, content, author_id)
-            VALUES ($1, $2, $3)
-            RETURNING id, title, content, author_id
-        )
-        SELECT 
-            inserted_post.id, 
-            inserted_post.title, 
-            inserted_post.content, 
-            inserted_post.author_id, 
-            users.username AS author_username
-        FROM inserted_post
-        JOIN users ON inserted_post.author_id = users.id
-    `, title, content, authorID).Scan(
+        INSERT INTO posts (title, content, author_id, visibility)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, title, content, author_id,
+            (SELECT username FROM users WHERE users.id = posts.author_id),
+            visibility
+    `, title, content, authorID, visibility).Scan(
 		&post.ID,
 		&post.Title,
 		&post.Content,
 		&post.AuthorID,
 		&post.AuthorUsername,
+		&post.Visibility,
 	)
 	if err != nil {
 		logger.WithError(err).Error("Failed to insert post into database")
@@ -140,10 +197,10 @@ func CreatePost(db *sql.DB, title, content string, authorID int) (*Post, error)
 	return &post, nil
 }
 
-// FetchPostByID возвращает пост по ID с информацией о лайках
-func FetchPostByID(db *sql.DB, postID int) (*Post, error) {
+// FetchPostByID возвращает пост по ID с информацией о лайках.
+// Пост, недоступный читателю viewerID по видимости или из-за блокировки автором, считается ненайденным.
+func FetchPostByID(db *sql.DB, postID, viewerID int) (*Post, error) {
 	var post Post
-	var likesJSON string
 
 	err := db.QueryRow(`
         SELECT 
@@ -152,27 +209,22 @@ func FetchPostByID(db *sql.DB, postID int) (*Post, error) {
             posts.content, 
             posts.author_id AS author_id,
             users.username AS author_username,
-            COALESCE(
-                json_agg(
-                    json_build_object(
-                        'id', likes.user_id, 
-                        'username', liked_users.username
-                    )
-                ) FILTER (WHERE likes.user_id IS NOT NULL), '[]'
-            ) AS likes
+            posts.visibility
         FROM posts
         JOIN users ON posts.author_id = users.id
-        LEFT JOIN likes ON posts.id = likes.post_id
-        LEFT JOIN users AS liked_users ON likes.user_id = liked_users.id
         WHERE posts.id = $1
-        GROUP BY posts.id, users.username
-    `, postID).Scan(
+          AND `+visibleTo("$2", false)+`
+          AND NOT EXISTS (
+            SELECT 1 FROM user_relations r
+            WHERE r.user_id = posts.author_id AND r.target_id = $2 AND r.kind = 'block'
+          )
+    `, postID, viewerID).Scan(
 		&post.ID,
 		&post.Title,
 		&post.Content,
 		&post.AuthorID,
 		&post.AuthorUsername,
-		&likesJSON,
+		&post.Visibility,
 	)
 
 	if err == sql.ErrNoRows {
@@ -181,12 +233,11 @@ func FetchPostByID(db *sql.DB, postID int) (*Post, error) {
 		return nil, fmt.Errorf("failed to fetch post: %w", err)
 	}
 
-	// Декодируем JSON-строку likes в массив объектов
-	if err := json.Unmarshal([]byte(likesJSON), &post.Likes); err != nil {
-		return nil, fmt.Errorf("failed to parse likes JSON: %w", err)
+	posts := []Post{post}
+	if err := attachLikes(db, posts); err != nil {
+		return nil, err
 	}
-
-	return &post, nil
+	return &posts[0], nil
 }
 
 // GetPostOwner возвращает ID пользователя, которому принадлежит пост
@@ -210,8 +261,9 @@ func DeletePost(db *sql.DB, postID int) error {
 	return nil
 }
 
-// FetchUserPosts возвращает список постов конкретного пользователя по его userID.
-func FetchUserPosts(db *sql.DB, userID int) ([]Post, error) {
+// FetchUserPosts возвращает список постов конкретного пользователя по его userID,
+// которые видны читателю viewerID; посты unlisted в список не попадают.
+func FetchUserPosts(db *sql.DB, userID, viewerID int) ([]Post, error) {
 	rows, err := db.Query(`
         SELECT 
             posts.id, 
@@ -219,46 +271,72 @@ func FetchUserPosts(db *sql.DB, userID int) ([]Post, error) {
             posts.content, 
             posts.author_id AS author_id,
             users.username AS author_username,
-            COALESCE(
-                json_agg(
-                    json_build_object(
-                        'id', likes.user_id, 
-                        'username', liker.username
-                    )
-                ) FILTER (WHERE likes.user_id IS NOT NULL), '[]'
-            ) AS likes
+            posts.visibility
         FROM posts
         JOIN users ON posts.author_id = users.id
-        LEFT JOIN likes ON posts.id = likes.post_id
-        LEFT JOIN users AS liker ON likes.user_id = liker.id
         WHERE posts.author_id = $1
-        GROUP BY posts.id, users.username
-        ORDER BY posts.created_at DESC
-    `, userID)
+          AND `+visibleTo("$2", true)+`
+        ORDER BY posts.created_at DESC, posts.id DESC
+    `, userID, viewerID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch user posts: %w", err)
 	}
-	defer rows.Close()
+	return scanPosts(db, rows)
+}
 
-	var posts []Post
-	for rows.Next() {
-		var post Post
-		var likesJSON string
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.AuthorUsername, &likesJSON)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan post row: %w", err)
-		}
+// PersonalAccessToken представляет действующий персональный токен доступа
+type PersonalAccessToken struct {
+	ID     int
+	UserID int
+	Scopes []string
+}
 
-		if err := json.Unmarshal([]byte(likesJSON), &post.Likes); err != nil {
-			return nil, fmt.Errorf("failed to parse likes JSON: %w", err)
-		}
+// FindPersonalAccessToken ищет действующий (не отозванный и не истёкший) токен по его SHA-256 хэшу
+// и обновляет время последнего использования
+func FindPersonalAccessToken(db *sql.DB, tokenHash string) (*PersonalAccessToken, error) {
+	var (
+		token  PersonalAccessToken
+		scopes string
+	)
+	err := db.QueryRow(portable(db, `
+		UPDATE personal_access_tokens
+		SET last_used_at = NOW()
+		WHERE token_hash = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING id, user_id, scopes
+	`), tokenHash).Scan(&token.ID, &token.UserID, &scopes)
 
-		posts = append(posts, post)
+	if err == sql.ErrNoRows {
+		return nil, nil // Токен не найден, отозван или истёк
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch personal access token: %w", err)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error while iterating over rows: %w", err)
-	}
+	token.Scopes = strings.Fields(scopes)
+	return &token, nil
+}
 
-	return posts, nil
+// UserStats содержит статистику постов и лайков пользователя
+type UserStats struct {
+	PostCount     int `json:"post_count"`
+	LikesReceived int `json:"likes_received"`
+	LikesGiven    int `json:"likes_given"`
+}
+
+// FetchUserStats возвращает количество постов пользователя, полученных и поставленных им лайков.
+// Статистика показывается в публичном профиле любому читателю, поэтому посты и полученные
+// лайки считаются только по публичным постам: иначе число выдавало бы скрытые посты.
+func FetchUserStats(db *sql.DB, userID int) (*UserStats, error) {
+	var stats UserStats
+	err := db.QueryRow(`
+        SELECT
+            (SELECT COUNT(*) FROM posts WHERE author_id = $1 AND visibility = 'public'),
+            (SELECT COUNT(*) FROM likes JOIN posts ON likes.post_id = posts.id WHERE posts.author_id = $1 AND posts.visibility = 'public'),
+            (SELECT COUNT(*) FROM likes WHERE user_id = $1)
+    `, userID).Scan(&stats.PostCount, &stats.LikesReceived, &stats.LikesGiven)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user stats: %w", err)
+	}
+	return &stats, nil
 }
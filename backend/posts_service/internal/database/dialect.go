@@ -0,0 +1,78 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"posts_service/internal/config"
+
+	"modernc.org/sqlite"
+)
+
+// Драйверы базы данных (переменная DB_DRIVER)
+const (
+	DriverPostgres = config.DriverPostgres
+	DriverSQLite   = config.DriverSQLite
+)
+
+// sqliteNow — текущее время UTC в том же текстовом виде, в котором драйвер SQLite сохраняет time.Time.
+// Время в SQLite хранится строкой, поэтому оно сравнивается лексикографически и должно быть в UTC.
+const sqliteNow = "strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')"
+
+// OpenSQLite открывает файл базы SQLite с внешними ключами и журналом WAL.
+// Транзакции сразу берут блокировку записи (_txlock=immediate) и ждут друг друга до busy_timeout.
+func OpenSQLite(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+	// time.Time сохраняется как "2006-01-02 15:04:05.999999999-07:00", а не в формате String()
+	params.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	return db, nil
+}
+
+func connectSQLite(path string) (*sql.DB, error) {
+	db, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// isSQLite сообщает, открыто ли соединение драйвером SQLite
+func isSQLite(db *sql.DB) bool {
+	_, ok := db.Driver().(*sqlite.Driver)
+	return ok
+}
+
+// portable возвращает запрос в диалекте соединения. Запросы пишутся для Postgres;
+// плейсхолдеры $1, $2 и ON CONFLICT ... DO SQLite понимает сам, а now() заменяется на sqliteNow.
+func portable(db *sql.DB, query string) string {
+	if !isSQLite(db) {
+		return query
+	}
+	return strings.NewReplacer("now()", sqliteNow, "NOW()", sqliteNow).Replace(query)
+}
+
+// placeholders возвращает список "$1, $2, ..., $n" для условия IN.
+// Используется вместо = ANY($1) с массивом: массивов в SQLite нет.
+func placeholders(n int) string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = "$" + strconv.Itoa(i+1)
+	}
+	return strings.Join(list, ", ")
+}
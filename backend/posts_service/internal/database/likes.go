@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// AddLike ставит лайк; повторный лайк того же пользователя ничего не меняет.
+// ON CONFLICT ... DO NOTHING одинаково работает в Postgres и SQLite (3.24+).
+func AddLike(db *sql.DB, postID, userID int) error {
+	_, err := db.Exec(`
+		INSERT INTO likes (post_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (post_id, user_id) DO NOTHING
+	`, postID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to add like: %w", err)
+	}
+	return nil
+}
+
+// RemoveLike снимает лайк пользователя с поста
+func RemoveLike(db *sql.DB, postID, userID int) error {
+	_, err := db.Exec(`DELETE FROM likes WHERE post_id = $1 AND user_id = $2`, postID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove like: %w", err)
+	}
+	return nil
+}
+
+// GetLikes возвращает ID пользователей, лайкнувших пост, в порядке лайков
+func GetLikes(db *sql.DB, postID int) ([]int, error) {
+	rows, err := db.Query(`SELECT user_id FROM likes WHERE post_id = $1 ORDER BY id`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch likes: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var uid int
+		if err := rows.Scan(&uid); err != nil {
+			return nil, fmt.Errorf("failed to scan like: %w", err)
+		}
+		userIDs = append(userIDs, uid)
+	}
+	return userIDs, rows.Err()
+}
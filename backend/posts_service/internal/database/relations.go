@@ -0,0 +1,21 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// IsBlocked сообщает, заблокировал ли пользователь userID пользователя targetID.
+// Отношения хранит users_service в таблице user_relations общей базы.
+func IsBlocked(db *sql.DB, userID, targetID int) (bool, error) {
+	var blocked bool
+	err := db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM user_relations WHERE user_id = $1 AND target_id = $2 AND kind = 'block'
+		)
+	`, userID, targetID).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+	return blocked, nil
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DeletedUserContent содержит количество удалённых записей пользователя
+type DeletedUserContent struct {
+	Posts         int64 `json:"posts"`
+	Likes         int64 `json:"likes"`
+	Notifications int64 `json:"notifications"`
+}
+
+// DeleteUserContent удаляет посты и лайки пользователя, лайки на его постах и связанные уведомления.
+// Таблица notifications создаётся миграциями posts_service, а у notification_service нет
+// своего потребителя user.deleted, поэтому уведомления удалённого аккаунта чистит posts_service.
+// Операция идемпотентна: повторная доставка события ничего не ломает.
+func DeleteUserContent(db *sql.DB, userID int) (*DeletedUserContent, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deleted DeletedUserContent
+	steps := []struct {
+		query string
+		count *int64
+	}{
+		// Уведомления создаются из лайков, поэтому удаляются вместе с ними
+		{`DELETE FROM notifications
+		  WHERE user_id = $1 OR liker_id = $1 OR post_id IN (SELECT id FROM posts WHERE author_id = $1)`, &deleted.Notifications},
+		{`DELETE FROM likes
+		  WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE author_id = $1)`, &deleted.Likes},
+		{`DELETE FROM posts WHERE author_id = $1`, &deleted.Posts},
+	}
+	for _, step := range steps {
+		res, err := tx.Exec(step.query, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete user content: %w", err)
+		}
+		*step.count, _ = res.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user content deletion: %w", err)
+	}
+	return &deleted, nil
+}
+
+// ExportedPost — пост пользователя в архиве персональных данных
+type ExportedPost struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Visibility string    `json:"visibility"`
+	CreatedAt  time.Time `json:"created_at"`
+	LikeCount  int       `json:"like_count"`
+}
+
+// ExportedLike — лайк, поставленный пользователем или полученный им
+type ExportedLike struct {
+	PostID    int    `json:"post_id"`
+	PostTitle string `json:"post_title"`
+	UserID    int    `json:"user_id"`
+}
+
+// ExportedNotification — уведомление, адресованное пользователю
+type ExportedNotification struct {
+	ID      int    `json:"id"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	PostID  *int   `json:"post_id"`
+	LikerID *int   `json:"liker_id"`
+	IsRead  bool   `json:"is_read"`
+}
+
+// UserContentExport содержит все данные пользователя, которыми владеет posts_service
+type UserContentExport struct {
+	Posts         []ExportedPost         `json:"posts"`
+	LikesGiven    []ExportedLike         `json:"likes_given"`
+	LikesReceived []ExportedLike         `json:"likes_received"`
+	Notifications []ExportedNotification `json:"notifications"`
+}
+
+// ExportUserContent собирает посты, лайки и уведомления пользователя для выгрузки персональных данных
+func ExportUserContent(db *sql.DB, userID int) (*UserContentExport, error) {
+	export := UserContentExport{
+		Posts:         []ExportedPost{},
+		LikesGiven:    []ExportedLike{},
+		LikesReceived: []ExportedLike{},
+		Notifications: []ExportedNotification{},
+	}
+
+	rows, err := db.Query(`
+		SELECT p.id, p.title, p.content, p.visibility, p.created_at, COUNT(l.id)
+		FROM posts p
+		LEFT JOIN likes l ON l.post_id = p.id
+		WHERE p.author_id = $1
+		GROUP BY p.id
+		ORDER BY p.created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+	for rows.Next() {
+		var p ExportedPost
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.Visibility, &p.CreatedAt, &p.LikeCount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		export.Posts = append(export.Posts, p)
+	}
+	rows.Close()
+
+	likeQueries := []struct {
+		query string
+		dest  *[]ExportedLike
+	}{
+		{`SELECT l.post_id, p.title, l.user_id FROM likes l JOIN posts p ON p.id = l.post_id
+		  WHERE l.user_id = $1 ORDER BY l.id`, &export.LikesGiven},
+		{`SELECT l.post_id, p.title, l.user_id FROM likes l JOIN posts p ON p.id = l.post_id
+		  WHERE p.author_id = $1 ORDER BY l.id`, &export.LikesReceived},
+	}
+	for _, q := range likeQueries {
+		rows, err := db.Query(q.query, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export likes: %w", err)
+		}
+		for rows.Next() {
+			var l ExportedLike
+			if err := rows.Scan(&l.PostID, &l.PostTitle, &l.UserID); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan like: %w", err)
+			}
+			*q.dest = append(*q.dest, l)
+		}
+		rows.Close()
+	}
+
+	rows, err = db.Query(`
+		SELECT id, type, message, post_id, liker_id, COALESCE(is_read, FALSE)
+		FROM notifications
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export notifications: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var n ExportedNotification
+		if err := rows.Scan(&n.ID, &n.Type, &n.Message, &n.PostID, &n.LikerID, &n.IsRead); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		export.Notifications = append(export.Notifications, n)
+	}
+	return &export, rows.Err()
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Пользователей хранит users_service в таблице users общей базы.
+// Аккаунты в процессе удаления (deleted_at задан) считаются несуществующими.
+
+// FindUserIDByUsername возвращает ID пользователя по имени или 0, если пользователь не найден
+func FindUserIDByUsername(db *sql.DB, username string) (int, error) {
+	var id int
+	err := db.QueryRow(`SELECT id FROM users WHERE username = $1 AND deleted_at IS NULL`, username).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to find user: %w", err)
+	}
+	return id, nil
+}
+
+// FetchUsernames возвращает имена пользователей по их ID; отсутствующих пользователей в результате нет
+func FetchUsernames(db *sql.DB, userIDs []int) (map[int]string, error) {
+	usernames := make(map[int]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return usernames, nil
+	}
+
+	ids := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id
+	}
+	rows, err := db.Query(`SELECT id, username FROM users WHERE id IN (`+placeholders(len(ids))+`) AND deleted_at IS NULL`, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch usernames: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, fmt.Errorf("failed to scan username: %w", err)
+		}
+		usernames[id] = username
+	}
+	return usernames, rows.Err()
+}
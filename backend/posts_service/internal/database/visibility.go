@@ -0,0 +1,57 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Видимость поста
+const (
+	VisibilityPublic    = "public"    // всем
+	VisibilityUnlisted  = "unlisted"  // по прямой ссылке, но не в лентах
+	VisibilityFollowers = "followers" // только подписчикам автора
+	VisibilityPrivate   = "private"   // только автору
+)
+
+// ValidVisibility проверяет, что значение видимости поддерживается
+func ValidVisibility(v string) bool {
+	switch v {
+	case VisibilityPublic, VisibilityUnlisted, VisibilityFollowers, VisibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// visibleTo возвращает SQL-условие, при котором пост доступен читателю viewer (плейсхолдер вида "$1").
+// Для лент (listed) посты unlisted исключаются; по прямой ссылке они доступны.
+// Подписки хранит users_service в таблице user_relations общей базы.
+func visibleTo(viewer string, listed bool) string {
+	open := "'public', 'unlisted'"
+	if listed {
+		open = "'public'"
+	}
+	return fmt.Sprintf(`(
+            posts.visibility IN (%[1]s)
+            OR posts.author_id = %[2]s
+            OR (posts.visibility = 'followers' AND EXISTS (
+                SELECT 1 FROM user_relations f
+                WHERE f.user_id = %[2]s AND f.target_id = posts.author_id AND f.kind = 'follow'
+            ))
+        )`, open, viewer)
+}
+
+// CanViewPost возвращает автора поста и признак того, что читатель viewerID может его открыть.
+// Для несуществующего поста возвращается authorID = 0.
+func CanViewPost(db *sql.DB, postID, viewerID int) (authorID int, visible bool, err error) {
+	err = db.QueryRow(`
+        SELECT posts.author_id, `+visibleTo("$2", false)+`
+        FROM posts
+        WHERE posts.id = $1
+    `, postID, viewerID).Scan(&authorID, &visible)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fmt.Errorf("failed to check post visibility: %w", err)
+	}
+	return authorID, visible, nil
+}
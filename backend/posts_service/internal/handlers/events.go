@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"blog/pkg/apierror"
+	"posts_service/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EventUserDeleted публикуется users_service при удалении аккаунта
+const EventUserDeleted = "user.deleted"
+
+// Event — событие, доставляемое users_service на /internal/events
+type Event struct {
+	ID     int64  `json:"id"`
+	Type   string `json:"type"`
+	UserID int    `json:"user_id"`
+}
+
+// HandleEvent обрабатывает события users_service. Ответ 2xx подтверждает обработку,
+// при любой другой ошибке users_service повторит доставку позже.
+// На user.deleted posts_service удаляет и уведомления пользователя: отдельного потребителя
+// в notification_service нет.
+func HandleEvent(posts repository.PostRepository) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		switch event.Type {
+		case EventUserDeleted:
+			if event.UserID <= 0 {
+				apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+				return
+			}
+			deleted, err := posts.DeleteUserContent(event.UserID)
+			if err != nil {
+				logger.WithError(err).WithField("event_id", event.ID).Error("Failed to delete user content")
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to delete user content")
+				return
+			}
+			logger.WithFields(logrus.Fields{
+				"event_id":      event.ID,
+				"user_id":       event.UserID,
+				"posts":         deleted.Posts,
+				"likes":         deleted.Likes,
+				"notifications": deleted.Notifications,
+			}).Info("User content deleted")
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(deleted)
+
+		default:
+			// Неизвестные события подтверждаются, чтобы не блокировать доставку
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"posts_service/internal/database"
+	"posts_service/internal/repository"
+)
+
+// failingPosts имитирует недоступную базу при удалении данных пользователя
+type failingPosts struct {
+	repository.PostRepository
+}
+
+func (failingPosts) DeleteUserContent(int) (*database.DeletedUserContent, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestHandleEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantPosts  int
+	}{
+		{
+			name:       "удаление пользователя",
+			body:       `{"id":7,"type":"user.deleted","user_id":1}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"posts":4,"likes":3,"notifications":2}`,
+			wantPosts:  1,
+		},
+		{
+			name:       "повторная доставка ничего не удаляет",
+			body:       `{"id":7,"type":"user.deleted","user_id":42}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"posts":0,"likes":0,"notifications":0}`,
+			wantPosts:  5,
+		},
+		{name: "неизвестное событие", body: `{"id":8,"type":"user.renamed","user_id":1}`, wantStatus: http.StatusNoContent, wantPosts: 5},
+		{name: "без пользователя", body: `{"id":9,"type":"user.deleted"}`, wantStatus: http.StatusBadRequest, wantPosts: 5},
+		{name: "некорректное тело", body: `{`, wantStatus: http.StatusBadRequest, wantPosts: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			// Лайки на посты alice и лайк alice на пост bob; уведомления о лайках для alice и bob
+			s.repo.AddLike(1, 2)
+			s.repo.AddLike(5, 1)
+			s.repo.AddLike(3, 3)
+			s.repo.AddLike(5, 3)
+			s.repo.AddNotification(1, 1, 2, "bob liked your post")
+			s.repo.AddNotification(2, 5, 1, "alice liked your post")
+			s.repo.AddNotification(2, 5, 3, "carol liked your post")
+
+			w := s.internal(http.MethodPost, "/internal/events", tt.body)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != tt.wantBody {
+				t.Errorf("ожидался ответ %s, получен %s", tt.wantBody, w.Body.String())
+			}
+			if got := countPosts(s.repo, 1) + countPosts(s.repo, 2); got != tt.wantPosts {
+				t.Errorf("ожидалось постов: %d, осталось %d", tt.wantPosts, got)
+			}
+		})
+	}
+}
+
+// countPosts возвращает число постов пользователя, включая скрытые
+func countPosts(repo *repository.Memory, userID int) int {
+	posts, _ := repo.FetchUserPosts(userID, userID)
+	return len(posts)
+}
+
+func TestHandleEventDatabaseError(t *testing.T) {
+	// Ошибка базы — ответ 5xx, users_service повторит доставку
+	s := &testServer{router: validated(t, HandleEvent(failingPosts{repository.NewMemory()}))}
+
+	w := s.do(http.MethodPost, "/internal/events", "", `{"id":7,"type":"user.deleted","user_id":1}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("ожидался код %d, получен %d: %s", http.StatusInternalServerError, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"blog/pkg/jwks"
+	"blog/pkg/openapi"
+	"blog/pkg/serviceauth"
+	"posts_service/internal/apispec"
+	"posts_service/internal/middlewares"
+	"posts_service/internal/repository"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type staticKeys map[string]*rsa.PublicKey
+
+func (s staticKeys) Key(kid string) (*rsa.PublicKey, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	return nil, jwks.ErrUnknownKey
+}
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+// signingKey генерирует ключ подписи один раз на все тесты пакета
+func signingKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	testKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testKey = key
+	})
+	return testKey
+}
+
+// Ключи подписи запросов сервисов: posts_service проверяет запросы users_service
+const (
+	testUsersServiceKeys = "u1:users-service:0123456789abcdef0123456789abcdef"
+	testPostsServiceKeys = "p1:posts-service:fedcba9876543210fedcba9876543210," + testUsersServiceKeys
+)
+
+// serviceKeys разбирает набор ключей сервиса service и роняет тест при ошибке
+func serviceKeys(t *testing.T, service, spec string) *serviceauth.KeyRing {
+	t.Helper()
+	keys, err := serviceauth.ParseKeyRing(service, spec, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// testServer собирает маршруты API из Routes, как cmd/main.go, поверх хранилища в памяти с данными seed.
+// Запросы и ответы проверяются по OpenAPI-документу, поэтому расхождение
+// обработчика с документом роняет тест кодом 500.
+type testServer struct {
+	router        http.Handler
+	repo          *repository.Memory
+	key           *rsa.PrivateKey
+	notifications *notificationsStub
+	// users подписывает запросы к /internal от имени users_service
+	users *serviceauth.KeyRing
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	repo := repository.NewMemory()
+	seed(repo)
+	notifications := newNotificationsStub(t)
+	key := signingKey(t)
+	verifier := &jwks.Verifier{
+		Keys:     staticKeys{"test": &key.PublicKey},
+		Issuer:   "auth-service",
+		Audience: "blog-api",
+	}
+	r := Routes{
+		Repo:          repo,
+		Verifier:      verifier,
+		ServiceKeys:   serviceKeys(t, "posts-service", testPostsServiceKeys),
+		AnonymousRead: true,
+		Notifications: NotificationsService{URL: notifications.url, Client: http.DefaultClient},
+	}.Router()
+
+	return &testServer{
+		router:        validated(t, r),
+		repo:          repo,
+		key:           key,
+		notifications: notifications,
+		users:         serviceKeys(t, "users-service", testUsersServiceKeys),
+	}
+}
+
+// validated пропускает запросы и ответы обработчика через проверку OpenAPI-документа
+func validated(t *testing.T, handler http.Handler) http.Handler {
+	t.Helper()
+
+	doc, err := apispec.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+	return validator.Middleware(handler)
+}
+
+// token выпускает JWT так же, как auth_service
+func (s *testServer) token(t *testing.T, userID int) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"user_id": userID,
+		"iss":     "auth-service",
+		"aud":     "blog-api",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// personalToken выдаёт персональный токен доступа с указанными правами
+func (s *testServer) personalToken(userID int, scopes ...string) string {
+	token := middlewares.PersonalAccessTokenPrefix + "test" + strings.Join(scopes, "_")
+	sum := sha256.Sum256([]byte(token))
+	s.repo.AddPersonalAccessToken(hex.EncodeToString(sum[:]), userID, scopes)
+	return token
+}
+
+func (s *testServer) do(method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// internal выполняет запрос к /internal, подписанный users_service
+func (s *testServer) internal(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	s.users.Sign(req, []byte(body))
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// seed заполняет хранилище общими данными тестов: пользователи alice (1), bob (2) и carol (3),
+// carol подписана на alice. Посты 1–4 принадлежат alice и имеют видимость public, unlisted,
+// followers и private, пост 5 — публичный пост bob.
+func seed(repo *repository.Memory) {
+	repo.AddUser(1, "alice")
+	repo.AddUser(2, "bob")
+	repo.AddUser(3, "carol")
+	repo.AddRelation(3, 1, repository.RelationFollow)
+
+	repo.CreatePost("Public", "Hello", 1, "public")
+	repo.CreatePost("Unlisted", "By link", 1, "unlisted")
+	repo.CreatePost("Friends", "For followers", 1, "followers")
+	repo.CreatePost("Private", "Only me", 1, "private")
+	repo.CreatePost("Bob", "Bob's post", 2, "public")
+}
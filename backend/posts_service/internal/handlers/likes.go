@@ -2,60 +2,92 @@ package handlers
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
+
+	"blog/pkg/apierror"
+	"posts_service/internal/metrics"
+	"posts_service/internal/middlewares"
+	"posts_service/internal/repository"
 )
 
-func ToggleLike(db *sql.DB) http.HandlerFunc {
+// NotificationsService — адрес notification_service и клиент, подписывающий запросы к нему
+type NotificationsService struct {
+	URL    string
+	Client *http.Client
+}
+
+// ToggleLike ставит или снимает лайк и создаёт уведомление автору в notification_service
+func ToggleLike(posts repository.PostRepository, likes repository.LikeRepository, users repository.UserRepository, notifications NotificationsService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var likeRequest struct {
 			PostID int `json:"postId"`
 			UserID int `json:"userId"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&likeRequest); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid request body")
 			return
 		}
 		defer r.Body.Close()
 
+		// Лайк ставится от имени пользователя из токена; userId в теле оставлен для совместимости
+		userID, ok := r.Context().Value(middlewares.UserIDKey).(int)
+		if !ok {
+			apierror.Error(w, r, http.StatusUnauthorized, "User not authorized")
+			return
+		}
+		if likeRequest.UserID != 0 && likeRequest.UserID != userID {
+			apierror.Error(w, r, http.StatusForbidden, "You can only like posts as yourself")
+			return
+		}
+		likeRequest.UserID = userID
+
 		// Валидация входных данных
-		if likeRequest.PostID <= 0 || likeRequest.UserID <= 0 {
-			http.Error(w, "Invalid PostID or UserID", http.StatusBadRequest)
+		if likeRequest.PostID <= 0 {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid PostID or UserID")
 			return
 		}
 
-		// Проверяем, существует ли пост и получаем его автора
-		var postAuthorID int
-		err := db.QueryRow("SELECT author_id FROM posts WHERE id = $1", likeRequest.PostID).Scan(&postAuthorID)
+		// Проверяем, существует ли пост, и получаем его автора. Лайкнуть можно только видимый пост,
+		// а снять лайк — с любого существующего (например, если автор сделал пост приватным)
+		postAuthorID, visible, err := posts.CanViewPost(likeRequest.PostID, likeRequest.UserID)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				http.Error(w, "Post not found", http.StatusNotFound)
-			} else {
-				log.Printf("Failed to check post: %v", err)
-				http.Error(w, "Failed to check post", http.StatusInternalServerError)
-			}
+			log.Printf("Failed to check post: %v", err)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to check post")
 			return
 		}
-
-		notificationServiceURL := os.Getenv("NOTIFICATIONS_SERVICE_URL")
-		if notificationServiceURL == "" {
-			http.Error(w, "Notifications service URL is not configured", http.StatusInternalServerError)
+		if postAuthorID == 0 || (r.Method == http.MethodPost && !visible) {
+			apierror.Error(w, r, http.StatusNotFound, "Post not found")
 			return
 		}
 
+		// Заблокированный автором пользователь не может лайкать его посты,
+		// поэтому и уведомления от него не создаются
+		if r.Method == http.MethodPost {
+			blocked, err := users.IsBlocked(postAuthorID, likeRequest.UserID)
+			if err != nil {
+				log.Printf("Failed to check block: %v", err)
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to check post")
+				return
+			}
+			if blocked {
+				apierror.Error(w, r, http.StatusForbidden, "You cannot like this user's posts")
+				return
+			}
+		}
+
 		switch r.Method {
 		case http.MethodPost:
 			// Добавляем лайк
-			if err := addLike(db, likeRequest.PostID, likeRequest.UserID); err != nil {
+			if err := likes.AddLike(likeRequest.PostID, likeRequest.UserID); err != nil {
 				log.Printf("Failed to add like: %v", err)
-				http.Error(w, "Failed to add like", http.StatusInternalServerError)
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to add like")
 				return
 			}
+			metrics.LikesToggled.WithLabelValues("like").Inc()
 
 			// Создаём уведомление через notifications_service
 			notification := map[string]interface{}{
@@ -71,14 +103,13 @@ func ToggleLike(db *sql.DB) http.HandlerFunc {
 				log.Printf("Failed to marshal notification: %v", err)
 				// Лайк уже добавлен, продолжаем выполнение.
 			} else {
-				req, err := http.NewRequest("POST", fmt.Sprintf("%s/notifications", notificationServiceURL), bytes.NewBuffer(notificationData))
+				req, err := http.NewRequest("POST", fmt.Sprintf("%s/notifications", notifications.URL), bytes.NewBuffer(notificationData))
 				if err != nil {
 					log.Printf("Failed to create notification request: %v", err)
 					return
 				}
 				req.Header.Set("Content-Type", "application/json")
-				client := &http.Client{}
-				resp, err := client.Do(req)
+				resp, err := notifications.Client.Do(req)
 				if err != nil {
 					log.Printf("Failed to send notification: %v", err)
 				} else {
@@ -93,11 +124,12 @@ func ToggleLike(db *sql.DB) http.HandlerFunc {
 
 		case http.MethodDelete:
 			// Удаляем лайк
-			if err := removeLike(db, likeRequest.PostID, likeRequest.UserID); err != nil {
+			if err := likes.RemoveLike(likeRequest.PostID, likeRequest.UserID); err != nil {
 				log.Printf("Failed to remove like: %v", err)
-				http.Error(w, "Failed to remove like", http.StatusInternalServerError)
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to remove like")
 				return
 			}
+			metrics.LikesToggled.WithLabelValues("unlike").Inc()
 
 			deleteNotificationRequest := map[string]interface{}{
 				"userId":  postAuthorID,
@@ -111,14 +143,13 @@ func ToggleLike(db *sql.DB) http.HandlerFunc {
 				log.Printf("Failed to marshal notification delete request: %v", err)
 				// Удаление лайка успешно, уведомление не удалено.
 			} else {
-				req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/api/notifications", notificationServiceURL), bytes.NewBuffer(deleteNotificationData))
+				req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/api/notifications", notifications.URL), bytes.NewBuffer(deleteNotificationData))
 				if err != nil {
 					log.Printf("Failed to create delete notification request: %v", err)
 					return
 				}
 				req.Header.Set("Content-Type", "application/json")
-				client := &http.Client{}
-				resp, err := client.Do(req)
+				resp, err := notifications.Client.Do(req)
 				if err != nil {
 					log.Printf("Failed to send delete notification request: %v", err)
 				} else {
@@ -132,96 +163,66 @@ func ToggleLike(db *sql.DB) http.HandlerFunc {
 			}
 
 		default:
-			http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+			apierror.Error(w, r, http.StatusMethodNotAllowed, "Invalid method")
 			return
 		}
 
-		// Получаем обновленный список пользователей, лайкнувших пост
-		userIDs, err := getLikes(db, likeRequest.PostID)
-		if err != nil {
-			log.Printf("Failed to fetch likes: %v", err)
-			http.Error(w, "Failed to fetch likes", http.StatusInternalServerError)
+		// Лайк с недоступного поста снимается, но список лайкнувших не раскрывается,
+		// как и в GetLikesForPost
+		if !visible {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode([]map[string]interface{}{})
 			return
 		}
 
-		users, err := fetchUsersFromUsersService(userIDs)
+		// Получаем обновленный список пользователей, лайкнувших пост
+		likers, err := fetchLikers(likes, users, likeRequest.PostID)
 		if err != nil {
-			log.Printf("Failed to fetch user info: %v", err)
-			http.Error(w, "Failed to fetch user info", http.StatusInternalServerError)
+			log.Printf("Failed to fetch likes: %v", err)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch likes")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(users)
+		json.NewEncoder(w).Encode(likers)
 	}
 }
 
-func GetLikesForPost(db *sql.DB) http.HandlerFunc {
+func GetLikesForPost(posts repository.PostRepository, likes repository.LikeRepository, users repository.UserRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		postIDStr := r.URL.Query().Get("postId")
 		if postIDStr == "" {
-			http.Error(w, "Post ID is required", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Post ID is required")
 			return
 		}
 
 		postID, err := strconv.Atoi(postIDStr)
 		if err != nil {
-			http.Error(w, "Invalid Post ID", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid Post ID")
 			return
 		}
 
-		userIDs, err := getLikes(db, postID)
+		// Лайки недоступного читателю поста не раскрываем, как и сам пост
+		viewerID, _ := r.Context().Value(middlewares.UserIDKey).(int)
+		authorID, visible, err := posts.CanViewPost(postID, viewerID)
 		if err != nil {
-			http.Error(w, "Failed to fetch likes", http.StatusInternalServerError)
+			log.Printf("Failed to check post: %v", err)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch likes")
+			return
+		}
+		if authorID == 0 || !visible {
+			apierror.Error(w, r, http.StatusNotFound, "Post not found")
 			return
 		}
 
-		users, err := fetchUsersFromUsersService(userIDs)
+		likers, err := fetchLikers(likes, users, postID)
 		if err != nil {
-			http.Error(w, "Failed to fetch user info", http.StatusInternalServerError)
+			log.Printf("Failed to fetch likes: %v", err)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch likes")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(users)
-	}
-}
-
-func addLike(db *sql.DB, postID, userID int) error {
-	_, err := db.Exec(`
-		INSERT INTO likes (post_id, user_id) 
-		VALUES ($1, $2) 
-		ON CONFLICT (post_id, user_id) DO NOTHING
-	`, postID, userID)
-	return err
-}
-
-func removeLike(db *sql.DB, postID, userID int) error {
-	_, err := db.Exec(`
-		DELETE FROM likes WHERE post_id = $1 AND user_id = $2
-	`, postID, userID)
-	return err
-}
-
-// getLikes возвращает список user_id, лайкнувших пост
-func getLikes(db *sql.DB, postID int) ([]int, error) {
-	rows, err := db.Query(`
-        SELECT user_id
-        FROM likes
-        WHERE post_id = $1
-    `, postID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var userIDs []int
-	for rows.Next() {
-		var uid int
-		if err := rows.Scan(&uid); err != nil {
-			return nil, err
-		}
-		userIDs = append(userIDs, uid)
+		json.NewEncoder(w).Encode(likers)
 	}
-	return userIDs, nil
 }
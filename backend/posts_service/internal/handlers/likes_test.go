@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"posts_service/internal/middlewares"
+	"posts_service/internal/repository"
+)
+
+// notificationsStub записывает запросы posts_service к notifications_service
+type notificationsStub struct {
+	url      string
+	mu       sync.Mutex
+	requests []string
+}
+
+func newNotificationsStub(t *testing.T) *notificationsStub {
+	t.Helper()
+	stub := &notificationsStub{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		stub.requests = append(stub.requests, r.Method+" "+r.URL.Path)
+		stub.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	stub.url = server.URL
+	return stub
+}
+
+func TestToggleLike(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		token      func(s *testServer) string
+		wantStatus int
+		wantBody   string
+		// wantLikes — число лайков поста checkPost после запроса
+		checkPost         int
+		wantLikes         int
+		wantNotifications string
+	}{
+		{
+			name:              "лайк",
+			method:            http.MethodPost,
+			body:              `{"postId":1}`,
+			token:             func(s *testServer) string { return s.token(t, 2) },
+			wantStatus:        http.StatusOK,
+			wantBody:          `[{"id":2,"username":"bob"}]`,
+			checkPost:         1,
+			wantLikes:         1,
+			wantNotifications: "POST /notifications",
+		},
+		{
+			name:              "лайк поста для подписчиков от подписчика",
+			method:            http.MethodPost,
+			body:              `{"postId":3}`,
+			token:             func(s *testServer) string { return s.token(t, 3) },
+			wantStatus:        http.StatusOK,
+			wantBody:          `[{"id":3,"username":"carol"}]`,
+			checkPost:         3,
+			wantLikes:         1,
+			wantNotifications: "POST /notifications",
+		},
+		{
+			name:              "лайк с персональным токеном",
+			method:            http.MethodPost,
+			body:              `{"postId":5}`,
+			token:             func(s *testServer) string { return s.personalToken(1, middlewares.ScopeLikesWrite) },
+			wantStatus:        http.StatusOK,
+			wantBody:          `[{"id":1,"username":"alice"}]`,
+			checkPost:         5,
+			wantLikes:         1,
+			wantNotifications: "POST /notifications",
+		},
+		{
+			name:   "повторный лайк не дублируется",
+			method: http.MethodPost,
+			body:   `{"postId":1,"userId":2}`,
+			token: func(s *testServer) string {
+				s.repo.AddLike(1, 2)
+				return s.token(t, 2)
+			},
+			wantStatus:        http.StatusOK,
+			checkPost:         1,
+			wantLikes:         1,
+			wantNotifications: "POST /notifications",
+		},
+		{
+			name:   "снятие лайка",
+			method: http.MethodDelete,
+			body:   `{"postId":1}`,
+			token: func(s *testServer) string {
+				s.repo.AddLike(1, 2)
+				return s.token(t, 2)
+			},
+			wantStatus:        http.StatusOK,
+			wantBody:          `[]`,
+			checkPost:         1,
+			wantNotifications: "DELETE /api/notifications",
+		},
+		{
+			name:   "снятие лайка с поста, ставшего приватным",
+			method: http.MethodDelete,
+			body:   `{"postId":4}`,
+			token: func(s *testServer) string {
+				s.repo.AddLike(4, 2)
+				s.repo.AddLike(4, 3)
+				return s.token(t, 2)
+			},
+			wantStatus: http.StatusOK,
+			// Лайк carol на недоступном bob посте не раскрывается
+			wantBody:          `[]`,
+			checkPost:         4,
+			wantLikes:         1,
+			wantNotifications: "DELETE /api/notifications",
+		},
+		{
+			name:   "снятие лайка не раскрывает лайки чужого приватного поста",
+			method: http.MethodDelete,
+			body:   `{"postId":4}`,
+			token: func(s *testServer) string {
+				s.repo.AddLike(4, 1)
+				s.repo.AddLike(4, 3)
+				return s.token(t, 2)
+			},
+			wantStatus:        http.StatusOK,
+			wantBody:          `[]`,
+			checkPost:         4,
+			wantLikes:         2,
+			wantNotifications: "DELETE /api/notifications",
+		},
+		{
+			name:       "без авторизации",
+			method:     http.MethodPost,
+			body:       `{"postId":1}`,
+			token:      func(s *testServer) string { return "" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "лайк от чужого имени",
+			method:     http.MethodPost,
+			body:       `{"postId":1,"userId":3}`,
+			token:      func(s *testServer) string { return s.token(t, 2) },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "персональный токен без права на лайки",
+			method:     http.MethodPost,
+			body:       `{"postId":1}`,
+			token:      func(s *testServer) string { return s.personalToken(2, middlewares.ScopePostsRead) },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "заблокированный автором пользователь",
+			method: http.MethodPost,
+			body:   `{"postId":1}`,
+			token: func(s *testServer) string {
+				s.repo.AddRelation(1, 2, repository.RelationBlock)
+				return s.token(t, 2)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "недоступный пост",
+			method:     http.MethodPost,
+			body:       `{"postId":4}`,
+			token:      func(s *testServer) string { return s.token(t, 2) },
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "несуществующий пост",
+			method:     http.MethodPost,
+			body:       `{"postId":42}`,
+			token:      func(s *testServer) string { return s.token(t, 2) },
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "без ID поста",
+			method:     http.MethodPost,
+			body:       `{}`,
+			token:      func(s *testServer) string { return s.token(t, 2) },
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			notifications := s.notifications
+			w := s.do(tt.method, "/likes", tt.token(s), tt.body)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != tt.wantBody {
+				t.Errorf("ожидался ответ %s, получен %s", tt.wantBody, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				if likes, _ := s.repo.GetLikes(tt.checkPost); len(likes) != tt.wantLikes {
+					t.Errorf("ожидалось лайков: %d, получено %v", tt.wantLikes, likes)
+				}
+			}
+			if got := strings.Join(notifications.requests, ","); got != tt.wantNotifications {
+				t.Errorf("ожидались запросы к notifications_service %q, получены %q", tt.wantNotifications, got)
+			}
+		})
+	}
+}
+
+func TestGetLikesForPost(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		token      func(s *testServer) string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "лайки публичного поста анонимно",
+			path:       "/likes?postId=1",
+			token:      func(s *testServer) string { return "" },
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"id":2,"username":"bob"},{"id":3,"username":"carol"},{"id":99,"username":null}]`,
+		},
+		{
+			name:       "лайки поста для подписчиков подписчику",
+			path:       "/likes?postId=3",
+			token:      func(s *testServer) string { return s.token(t, 3) },
+			wantStatus: http.StatusOK,
+			wantBody:   `[]`,
+		},
+		{
+			name:       "лайки недоступного поста",
+			path:       "/likes?postId=4",
+			token:      func(s *testServer) string { return s.token(t, 2) },
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "несуществующий пост",
+			path:       "/likes?postId=42",
+			token:      func(s *testServer) string { return "" },
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "без ID поста",
+			path:       "/likes",
+			token:      func(s *testServer) string { return "" },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "недействительный токен",
+			path:       "/likes?postId=1",
+			token:      func(s *testServer) string { return "not-a-jwt" },
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.repo.AddLike(1, 2)
+			s.repo.AddLike(1, 3)
+			// Лайк удалённого пользователя: имени нет
+			s.repo.AddLike(1, 99)
+
+			w := s.do(http.MethodGet, tt.path, tt.token(s), "")
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != tt.wantBody {
+				t.Errorf("ожидался ответ %s, получен %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -1,52 +1,57 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"net/http"
 
+	"blog/pkg/apierror"
 	"posts_service/internal/database"
+	"posts_service/internal/metrics"
 	"posts_service/internal/middlewares"
+	"posts_service/internal/repository"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
-func FetchPosts(db *sql.DB) http.HandlerFunc {
+func FetchPosts(posts repository.PostRepository) http.HandlerFunc {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Получаем посты через функцию FetchPosts из database
-		posts, err := database.FetchPosts(db)
+		viewerID, _ := r.Context().Value(middlewares.UserIDKey).(int)
+
+		// Получаем ленту из репозитория
+		feed, err := posts.FetchPosts(viewerID)
 		if err != nil {
 			logger.WithError(err).Error("Failed to fetch posts from database")
-			http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch posts")
 			return
 		}
 
 		// Логируем, если постов нет (информативно, но не ошибка)
-		if len(posts) == 0 {
+		if len(feed) == 0 {
 			logger.Info("No posts found, returning empty array")
 		}
 
 		// Отправляем ответ
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(posts); err != nil {
+		if err := json.NewEncoder(w).Encode(feed); err != nil {
 			logger.WithError(err).Error("Failed to encode response")
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to encode response")
 		}
 	}
 }
 
 // CreatePostRequest представляет запрос на создание поста
 type CreatePostRequest struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	Visibility string `json:"visibility"` // public (по умолчанию), unlisted, followers или private
 }
 
 // CreatePost обрабатывает запрос на создание нового поста
-func CreatePost(db *sql.DB) http.HandlerFunc {
+func CreatePost(posts repository.PostRepository) http.HandlerFunc {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
@@ -57,7 +62,7 @@ func CreatePost(db *sql.DB) http.HandlerFunc {
 		userID, ok := r.Context().Value(middlewares.UserIDKey).(int)
 		if !ok {
 			logger.Warn("User not authorized")
-			http.Error(w, "User not authorized", http.StatusUnauthorized)
+			apierror.Error(w, r, http.StatusUnauthorized, "User not authorized")
 			return
 		}
 		logger.WithField("userID", userID).Info("Authorized user")
@@ -66,21 +71,32 @@ func CreatePost(db *sql.DB) http.HandlerFunc {
 		var req CreatePostRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			logger.WithError(err).Warn("Invalid request body")
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid request body")
 			return
 		}
 		logger.WithFields(logrus.Fields{
-			"title":   req.Title,
-			"content": req.Content,
+			"title":      req.Title,
+			"content":    req.Content,
+			"visibility": req.Visibility,
 		}).Info("Request body decoded")
 
+		if req.Visibility == "" {
+			req.Visibility = database.VisibilityPublic
+		}
+		if !database.ValidVisibility(req.Visibility) {
+			logger.WithField("visibility", req.Visibility).Warn("Invalid post visibility")
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid visibility: must be public, unlisted, followers or private")
+			return
+		}
+
 		// Вставляем пост в базу данных
-		post, err := database.CreatePost(db, req.Title, req.Content, userID)
+		post, err := posts.CreatePost(req.Title, req.Content, userID, req.Visibility)
 		if err != nil {
 			logger.WithError(err).Error("Failed to create post in database")
-			http.Error(w, "Failed to create post", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to create post")
 			return
 		}
+		metrics.PostsCreated.Inc()
 		logger.WithFields(logrus.Fields{
 			"id":             post.ID,
 			"title":          post.Title,
@@ -93,13 +109,13 @@ func CreatePost(db *sql.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(post); err != nil {
 			logger.WithError(err).Error("Failed to encode response")
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to encode response")
 		}
 		logger.Info("Response sent to the client")
 	}
 }
 
-func FetchPostById(db *sql.DB) http.HandlerFunc {
+func FetchPostById(posts repository.PostRepository) http.HandlerFunc {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
@@ -108,21 +124,22 @@ func FetchPostById(db *sql.DB) http.HandlerFunc {
 		postID, err := atoiParam(vars["id"])
 		if err != nil {
 			logger.WithField("post_id", vars["id"]).Warn("Invalid post ID")
-			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid post ID")
 			return
 		}
 
-		// Получаем пост из базы данных
-		post, err := database.FetchPostByID(db, postID)
+		// Получаем пост из базы данных; недоступный читателю пост не отличается от несуществующего
+		viewerID, _ := r.Context().Value(middlewares.UserIDKey).(int)
+		post, err := posts.FetchPostByID(postID, viewerID)
 		if err != nil {
 			logger.WithError(err).Error("Failed to fetch post")
-			http.Error(w, "Failed to fetch post", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch post")
 			return
 		}
 
 		if post == nil {
 			logger.WithField("post_id", postID).Warn("Post not found")
-			http.Error(w, "Post not found", http.StatusNotFound)
+			apierror.Error(w, r, http.StatusNotFound, "Post not found")
 			return
 		}
 
@@ -130,12 +147,12 @@ func FetchPostById(db *sql.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(post); err != nil {
 			logger.WithError(err).Error("Failed to encode response")
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to encode response")
 		}
 	}
 }
 
-func DeletePost(db *sql.DB) http.HandlerFunc {
+func DeletePost(posts repository.PostRepository) http.HandlerFunc {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
@@ -144,7 +161,7 @@ func DeletePost(db *sql.DB) http.HandlerFunc {
 		userID, ok := r.Context().Value(middlewares.UserIDKey).(int)
 		if !ok {
 			logger.Warn("User not authorized")
-			http.Error(w, "User not authorized", http.StatusUnauthorized)
+			apierror.Error(w, r, http.StatusUnauthorized, "User not authorized")
 			return
 		}
 
@@ -153,21 +170,21 @@ func DeletePost(db *sql.DB) http.HandlerFunc {
 		postID, err := atoiParam(vars["id"])
 		if err != nil {
 			logger.WithField("post_id", vars["id"]).Warn("Invalid post ID")
-			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid post ID")
 			return
 		}
 
 		// Проверяем, что пост принадлежит данному пользователю
-		ownerID, err := database.GetPostOwner(db, postID)
+		ownerID, err := posts.GetPostOwner(postID)
 		if err != nil {
 			logger.WithError(err).Error("Failed to retrieve post owner")
-			http.Error(w, "Failed to retrieve post owner", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to retrieve post owner")
 			return
 		}
 
 		if ownerID == 0 {
 			logger.WithField("post_id", postID).Warn("Post not found")
-			http.Error(w, "Post not found", http.StatusNotFound)
+			apierror.Error(w, r, http.StatusNotFound, "Post not found")
 			return
 		}
 
@@ -177,14 +194,14 @@ func DeletePost(db *sql.DB) http.HandlerFunc {
 				"owner_id": ownerID,
 				"user_id":  userID,
 			}).Warn("Unauthorized deletion attempt")
-			http.Error(w, "You are not authorized to delete this post", http.StatusForbidden)
+			apierror.Error(w, r, http.StatusForbidden, "You are not authorized to delete this post")
 			return
 		}
 
 		// Удаляем пост
-		if err := database.DeletePost(db, postID); err != nil {
+		if err := posts.DeletePost(postID); err != nil {
 			logger.WithError(err).Error("Failed to delete post")
-			http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to delete post")
 			return
 		}
 
@@ -193,7 +210,7 @@ func DeletePost(db *sql.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(response); err != nil {
 			logger.WithError(err).Error("Failed to encode response")
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to encode response")
 		}
 	}
 }
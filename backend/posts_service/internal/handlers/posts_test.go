@@ -1,23 +1,301 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
-	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"posts_service/internal/middlewares"
+	"posts_service/internal/repository"
 )
 
+// titles возвращает заголовки постов из ответа со списком постов
+func titles(t *testing.T, body string) []string {
+	t.Helper()
+	var posts []struct {
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal([]byte(body), &posts); err != nil {
+		t.Fatalf("некорректный ответ %s: %v", body, err)
+	}
+	result := []string{}
+	for _, p := range posts {
+		result = append(result, p.Title)
+	}
+	return result
+}
+
 func TestFetchPosts(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
-	w := httptest.NewRecorder()
+	tests := []struct {
+		name       string
+		setup      func(s *testServer) string
+		wantStatus int
+		wantTitles []string
+	}{
+		{
+			name:       "анонимный читатель видит только публичные посты",
+			setup:      func(s *testServer) string { return "" },
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Bob", "Public"},
+		},
+		{
+			name:       "автор видит все свои посты",
+			setup:      func(s *testServer) string { return s.token(t, 1) },
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Bob", "Private", "Friends", "Unlisted", "Public"},
+		},
+		{
+			name:       "подписчик видит посты для подписчиков",
+			setup:      func(s *testServer) string { return s.token(t, 3) },
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Bob", "Friends", "Public"},
+		},
+		{
+			name: "скрытый автор не попадает в ленту",
+			setup: func(s *testServer) string {
+				s.repo.AddRelation(3, 2, repository.RelationMute)
+				return s.token(t, 3)
+			},
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Friends", "Public"},
+		},
+		{
+			name: "скрытие действует только для скрывшего",
+			setup: func(s *testServer) string {
+				s.repo.AddRelation(3, 2, repository.RelationMute)
+				return s.token(t, 1)
+			},
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Bob", "Private", "Friends", "Unlisted", "Public"},
+		},
+		{
+			name: "заблокированный читателем автор не попадает в ленту",
+			setup: func(s *testServer) string {
+				s.repo.AddRelation(3, 2, repository.RelationBlock)
+				return s.token(t, 3)
+			},
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Friends", "Public"},
+		},
+		{
+			name: "автор, заблокировавший читателя, не попадает в ленту",
+			setup: func(s *testServer) string {
+				s.repo.AddRelation(1, 3, repository.RelationBlock)
+				return s.token(t, 3)
+			},
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Bob"},
+		},
+		{
+			name:       "персональный токен с правом чтения",
+			setup:      func(s *testServer) string { return s.personalToken(3, middlewares.ScopePostsRead) },
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Bob", "Friends", "Public"},
+		},
+		{
+			name:       "персональный токен без права чтения",
+			setup:      func(s *testServer) string { return s.personalToken(3, middlewares.ScopeLikesWrite) },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "недействительный токен",
+			setup:      func(s *testServer) string { return "not-a-jwt" },
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			w := s.do(http.MethodGet, "/posts", tt.setup(s), "")
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantTitles != nil {
+				if got := titles(t, w.Body.String()); strings.Join(got, ",") != strings.Join(tt.wantTitles, ",") {
+					t.Errorf("ожидались посты %v, получены %v", tt.wantTitles, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchPostsEmptyFeed(t *testing.T) {
+	s := newTestServer(t)
+	s.repo.DeletePost(1)
+	s.repo.DeletePost(5)
+
+	// Анонимному читателю не осталось публичных постов.
+	// Пустая лента отдаётся как [], иначе ответ не соответствует документу
+	w := s.do(http.MethodGet, "/posts", "", "")
+	if w.Code != http.StatusOK || w.Body.String() != "[]\n" {
+		t.Errorf("ожидался пустой список, получен %d: %s", w.Code, w.Body.String())
+	}
+}
 
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`[{"id":1,"title":"Test Post","content":"This is a test post"}]`))
-	})
+func TestCreatePost(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          func(s *testServer) string
+		body           string
+		wantStatus     int
+		wantVisibility string
+	}{
+		{
+			name:           "пост по умолчанию публичный",
+			token:          func(s *testServer) string { return s.token(t, 2) },
+			body:           `{"title":"New","content":"Text"}`,
+			wantStatus:     http.StatusOK,
+			wantVisibility: "public",
+		},
+		{
+			name:           "пост для подписчиков",
+			token:          func(s *testServer) string { return s.token(t, 2) },
+			body:           `{"title":"New","content":"Text","visibility":"followers"}`,
+			wantStatus:     http.StatusOK,
+			wantVisibility: "followers",
+		},
+		{
+			name:       "неизвестная видимость",
+			token:      func(s *testServer) string { return s.token(t, 2) },
+			body:       `{"title":"New","content":"Text","visibility":"friends"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "некорректное тело",
+			token:      func(s *testServer) string { return s.token(t, 2) },
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "без авторизации",
+			token:      func(s *testServer) string { return "" },
+			body:       `{"title":"New","content":"Text"}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "персональный токен только для чтения",
+			token:      func(s *testServer) string { return s.personalToken(2, middlewares.ScopePostsRead) },
+			body:       `{"title":"New","content":"Text"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:           "персональный токен с правом записи",
+			token:          func(s *testServer) string { return s.personalToken(2, middlewares.ScopePostsWrite) },
+			body:           `{"title":"New","content":"Text"}`,
+			wantStatus:     http.StatusOK,
+			wantVisibility: "public",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			w := s.do(http.MethodPost, "/posts", tt.token(s), tt.body)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var post struct {
+				ID             int    `json:"id"`
+				AuthorID       int    `json:"authorId"`
+				AuthorUsername string `json:"authorUsername"`
+				Visibility     string `json:"visibility"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &post); err != nil {
+				t.Fatal(err)
+			}
+			if post.AuthorID != 2 || post.AuthorUsername != "bob" || post.Visibility != tt.wantVisibility {
+				t.Errorf("неожиданный пост: %s", w.Body.String())
+			}
+			if owner, _ := s.repo.GetPostOwner(post.ID); owner != 2 {
+				t.Errorf("пост не сохранён")
+			}
+		})
+	}
+}
+
+func TestFetchPostByID(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		token      func(s *testServer) string
+		wantStatus int
+	}{
+		{name: "публичный пост анонимно", path: "/posts/1", token: func(s *testServer) string { return "" }, wantStatus: http.StatusOK},
+		{name: "пост по ссылке анонимно", path: "/posts/2", token: func(s *testServer) string { return "" }, wantStatus: http.StatusOK},
+		{name: "пост для подписчиков анонимно", path: "/posts/3", token: func(s *testServer) string { return "" }, wantStatus: http.StatusNotFound},
+		{name: "пост для подписчиков подписчику", path: "/posts/3", token: func(s *testServer) string { return s.token(t, 3) }, wantStatus: http.StatusOK},
+		{name: "чужой приватный пост", path: "/posts/4", token: func(s *testServer) string { return s.token(t, 2) }, wantStatus: http.StatusNotFound},
+		{name: "свой приватный пост", path: "/posts/4", token: func(s *testServer) string { return s.token(t, 1) }, wantStatus: http.StatusOK},
+		{
+			name: "автор заблокировал читателя",
+			path: "/posts/1",
+			token: func(s *testServer) string {
+				s.repo.AddRelation(1, 2, repository.RelationBlock)
+				return s.token(t, 2)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{name: "несуществующий пост", path: "/posts/42", token: func(s *testServer) string { return "" }, wantStatus: http.StatusNotFound},
+		{name: "некорректный ID", path: "/posts/abc", token: func(s *testServer) string { return "" }, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			w := s.do(http.MethodGet, tt.path, tt.token(s), "")
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		token       func(s *testServer) string
+		wantStatus  int
+		wantDeleted bool
+	}{
+		{name: "автор удаляет свой пост", path: "/posts/1", token: func(s *testServer) string { return s.token(t, 1) }, wantStatus: http.StatusOK, wantDeleted: true},
+		{name: "чужой пост", path: "/posts/1", token: func(s *testServer) string { return s.token(t, 2) }, wantStatus: http.StatusForbidden},
+		{name: "несуществующий пост", path: "/posts/42", token: func(s *testServer) string { return s.token(t, 1) }, wantStatus: http.StatusNotFound},
+		{name: "без авторизации", path: "/posts/1", token: func(s *testServer) string { return "" }, wantStatus: http.StatusUnauthorized},
+		{name: "недействительный токен", path: "/posts/1", token: func(s *testServer) string { return "not-a-jwt" }, wantStatus: http.StatusUnauthorized},
+		{
+			name:       "персональный токен без права записи",
+			path:       "/posts/1",
+			token:      func(s *testServer) string { return s.personalToken(1, middlewares.ScopePostsRead) },
+			wantStatus: http.StatusForbidden,
+		},
+	}
 
-	handler.ServeHTTP(w, req)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.repo.AddLike(1, 2)
+			w := s.do(http.MethodDelete, tt.path, tt.token(s), "")
 
-	if w.Code != http.StatusOK {
-		t.Errorf("Ожидался код %d, но получен %d", http.StatusOK, w.Code)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			owner, _ := s.repo.GetPostOwner(1)
+			if deleted := owner == 0; deleted != tt.wantDeleted {
+				t.Errorf("ожидалось удаление поста: %v", tt.wantDeleted)
+			}
+			if likes, _ := s.repo.GetLikes(1); tt.wantDeleted && len(likes) != 0 {
+				t.Errorf("лайки удалённого поста остались: %v", likes)
+			}
+		})
 	}
 }
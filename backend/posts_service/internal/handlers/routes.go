@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"blog/pkg/apierror"
+	"blog/pkg/jwks"
+	"blog/pkg/serviceauth"
+	"posts_service/internal/middlewares"
+	"posts_service/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// Routes — зависимости маршрутов API сервиса
+type Routes struct {
+	Repo          repository.Repository
+	Verifier      *jwks.Verifier
+	ServiceKeys   *serviceauth.KeyRing
+	AnonymousRead bool
+	// Notifications — notification_service для уведомлений о лайках
+	Notifications NotificationsService
+}
+
+// Router регистрирует маршруты API. cmd/routes.go добавляет к ним пробы и метрики,
+// а тесты обработчиков собирают сервер из этих же маршрутов.
+func (d Routes) Router() *mux.Router {
+	r := mux.NewRouter()
+
+	// Ошибки маршрутизации тоже в формате problem+json
+	r.NotFoundHandler = apierror.NotFoundHandler()
+	r.MethodNotAllowedHandler = apierror.MethodNotAllowedHandler()
+
+	// Аутентификация настраивается для каждого маршрута: записи требуют токен,
+	// чтение доступно анонимно, если ANONYMOUS_READ не выключен
+	lookupPAT := func(tokenHash string) (int, []string, bool, error) {
+		token, err := d.Repo.FindPersonalAccessToken(tokenHash)
+		if err != nil || token == nil {
+			return 0, nil, false, err
+		}
+		return token.UserID, token.Scopes, true, nil
+	}
+	required := middlewares.RequireAuth(d.Verifier, lookupPAT)
+	optional := middlewares.OptionalAuth(d.Verifier, lookupPAT)
+	if !d.AnonymousRead {
+		optional = required
+	}
+
+	// Маршруты для постов
+	r.Handle("/posts", required(middlewares.RequireScope(middlewares.ScopePostsWrite, CreatePost(d.Repo)))).Methods("POST")
+	r.Handle("/posts", optional(middlewares.RequireScope(middlewares.ScopePostsRead, FetchPosts(d.Repo)))).Methods("GET")
+	r.Handle("/posts/{id}", optional(middlewares.RequireScope(middlewares.ScopePostsRead, FetchPostById(d.Repo)))).Methods("GET")
+	r.Handle("/posts/{id}", required(middlewares.RequireScope(middlewares.ScopePostsWrite, DeletePost(d.Repo)))).Methods("DELETE")
+
+	// Маршруты для лайков
+	r.Handle("/likes", required(middlewares.RequireScope(middlewares.ScopeLikesWrite, ToggleLike(d.Repo, d.Repo, d.Repo, d.Notifications)))).Methods("POST", "DELETE")
+	r.Handle("/likes", optional(middlewares.RequireScope(middlewares.ScopePostsRead, GetLikesForPost(d.Repo, d.Repo, d.Repo)))).Methods("GET")
+
+	// Маршрут для получения постов конкретного пользователя
+	r.Handle("/profile/{username}/posts", optional(middlewares.RequireScope(middlewares.ScopePostsRead, FetchUserPosts(d.Repo, d.Repo)))).Methods("GET")
+
+	// Внутренние эндпоинты для других сервисов (только подписанные запросы)
+	internal := r.PathPrefix("/internal").Subrouter()
+	internal.Use(serviceauth.Middleware(d.ServiceKeys, "users-service"))
+	internal.HandleFunc("/users/{id:[0-9]+}/stats", FetchUserStats(d.Repo)).Methods("GET")
+	internal.HandleFunc("/users/{id:[0-9]+}/export", ExportUserContent(d.Repo)).Methods("GET")
+	internal.HandleFunc("/events", HandleEvent(d.Repo)).Methods("POST")
+	return r
+}
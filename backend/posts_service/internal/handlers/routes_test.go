@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInternalRoutesRequireServiceSignature(t *testing.T) {
+	s := newTestServer(t)
+	stranger := serviceKeys(t, "users-service", "u2:users-service:00000000000000000000000000000000")
+
+	tests := []struct {
+		name       string
+		sign       func(r *http.Request)
+		wantStatus int
+	}{
+		{"без подписи", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"неизвестный ключ", func(r *http.Request) { stranger.Sign(r, nil) }, http.StatusUnauthorized},
+		{"подпись users_service", func(r *http.Request) { s.users.Sign(r, nil) }, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/internal/users/1/stats", nil)
+			tt.sign(req)
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
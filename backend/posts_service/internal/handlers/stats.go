@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"blog/pkg/apierror"
+	"posts_service/internal/repository"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// FetchUserStats возвращает статистику постов и лайков пользователя (внутренний эндпоинт для users_service)
+func FetchUserStats(posts repository.PostRepository) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		userID, err := atoiParam(vars["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		stats, err := posts.FetchUserStats(userID)
+		if err != nil {
+			logger.WithError(err).Error("Failed to fetch user stats")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch user stats")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}
+}
+
+// ExportUserContent возвращает посты, лайки и уведомления пользователя для выгрузки персональных данных
+// (внутренний эндпоинт для users_service)
+func ExportUserContent(posts repository.PostRepository) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		userID, err := atoiParam(vars["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		export, err := posts.ExportUserContent(userID)
+		if err != nil {
+			logger.WithError(err).Error("Failed to export user content")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to export user content")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(export)
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFetchUserStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		// Лайк carol на пост unlisted и сами скрытые посты alice в статистику не попадают
+		{name: "автор с лайками", path: "/internal/users/1/stats", wantStatus: http.StatusOK, wantBody: `{"post_count":1,"likes_received":1,"likes_given":1}`},
+		{name: "пользователь без постов", path: "/internal/users/3/stats", wantStatus: http.StatusOK, wantBody: `{"post_count":0,"likes_received":0,"likes_given":1}`},
+		{name: "неизвестный пользователь", path: "/internal/users/42/stats", wantStatus: http.StatusOK, wantBody: `{"post_count":0,"likes_received":0,"likes_given":0}`},
+		{name: "некорректный ID", path: "/internal/users/abc/stats", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.repo.AddLike(1, 2)
+			s.repo.AddLike(2, 3)
+			s.repo.AddLike(5, 1)
+
+			w := s.internal(http.MethodGet, tt.path, "")
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != tt.wantBody {
+				t.Errorf("ожидался ответ %s, получен %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExportUserContent(t *testing.T) {
+	s := newTestServer(t)
+	s.repo.AddLike(1, 2)
+	s.repo.AddLike(5, 1)
+	s.repo.AddNotification(1, 1, 2, "bob liked your post")
+
+	w := s.internal(http.MethodGet, "/internal/users/1/export", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("ожидался код %d, получен %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var export struct {
+		Posts []struct {
+			Title     string `json:"title"`
+			LikeCount int    `json:"like_count"`
+		} `json:"posts"`
+		LikesGiven    []map[string]interface{} `json:"likes_given"`
+		LikesReceived []map[string]interface{} `json:"likes_received"`
+		Notifications []map[string]interface{} `json:"notifications"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	// В выгрузку попадают все посты автора, включая приватные, в порядке создания
+	if len(export.Posts) != 4 || export.Posts[0].Title != "Public" || export.Posts[0].LikeCount != 1 {
+		t.Errorf("неожиданные посты: %+v", export.Posts)
+	}
+	if len(export.LikesGiven) != 1 || export.LikesGiven[0]["post_title"] != "Bob" {
+		t.Errorf("неожиданные поставленные лайки: %v", export.LikesGiven)
+	}
+	if len(export.LikesReceived) != 1 || export.LikesReceived[0]["user_id"] != float64(2) {
+		t.Errorf("неожиданные полученные лайки: %v", export.LikesReceived)
+	}
+	if len(export.Notifications) != 1 {
+		t.Errorf("неожиданные уведомления: %v", export.Notifications)
+	}
+}
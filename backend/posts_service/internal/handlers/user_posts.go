@@ -1,70 +1,56 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
-	"os"
-	"posts_service/internal/database"
-	"strconv"
+
+	"blog/pkg/apierror"
+	"posts_service/internal/middlewares"
+	"posts_service/internal/repository"
 
 	"github.com/gorilla/mux"
 )
 
-func FetchUserPosts(db *sql.DB) http.HandlerFunc {
+func FetchUserPosts(posts repository.PostRepository, users repository.UserRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		username := vars["username"]
 
-		// Получаем userID по username через Users Service
-		userID, err := fetchUserIDByUsername(username)
+		// Получаем userID по username
+		userID, err := users.FindUserIDByUsername(username)
 		if err != nil {
-			http.Error(w, "Failed to find user by username", http.StatusNotFound)
+			log.Printf("Failed to find user: %v", err)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch posts")
+			return
+		}
+		if userID == 0 {
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+
+		// Заблокированный пользователь не видит посты автора, как если бы автора не было:
+		// ответ не отличается от ответа для неизвестного имени
+		viewerID, _ := r.Context().Value(middlewares.UserIDKey).(int)
+		blocked, err := users.IsBlocked(userID, viewerID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch posts")
+			return
+		}
+		if blocked {
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
 			return
 		}
 
 		// Получаем посты пользователя
-		posts, err := database.FetchUserPosts(db, userID)
+		userPosts, err := posts.FetchUserPosts(userID, viewerID)
 		if err != nil {
-			http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch posts")
 			return
 		}
 
 		// Отправляем ответ
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(posts)
+		json.NewEncoder(w).Encode(userPosts)
 	}
 }
-
-// fetchUserIDByUsername запрашивает userID из Users Service по username.
-func fetchUserIDByUsername(username string) (int, error) {
-	userServiceURL := os.Getenv("USERS_SERVICE_URL")
-	if userServiceURL == "" {
-		return 0, errNoUserService
-	}
-
-	url := userServiceURL + "/api/users/by_username?username=" + username
-	resp, err := http.Get(url)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, errUserNotFound
-	}
-
-	var user struct {
-		ID int `json:"id"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		return 0, err
-	}
-	return user.ID, nil
-}
-
-// Ошибки
-var (
-	errNoUserService = strconv.ErrSyntax
-	errUserNotFound  = strconv.ErrRange
-)
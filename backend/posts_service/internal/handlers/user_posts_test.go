@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"posts_service/internal/middlewares"
+	"posts_service/internal/repository"
+)
+
+func TestFetchUserPostsBlockedLooksLikeUnknownUser(t *testing.T) {
+	s := newTestServer(t)
+	s.repo.AddRelation(1, 3, repository.RelationBlock)
+
+	// Заблокированный читатель не должен понять, что автор существует
+	detail := func(path string) string {
+		w := s.do(http.MethodGet, path, s.token(t, 3), "")
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: ожидался код %d, получен %d", path, http.StatusNotFound, w.Code)
+		}
+		var problem struct {
+			Detail string `json:"detail"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatal(err)
+		}
+		return problem.Detail
+	}
+	if blocked, unknown := detail("/profile/alice/posts"), detail("/profile/dave/posts"); blocked != unknown {
+		t.Errorf("ответы различаются: %q и %q", blocked, unknown)
+	}
+}
+
+func TestFetchUserPosts(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		token      func(s *testServer) string
+		wantStatus int
+		wantTitles []string
+	}{
+		{
+			name:       "анонимный читатель",
+			path:       "/profile/alice/posts",
+			token:      func(s *testServer) string { return "" },
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Public"},
+		},
+		{
+			name:       "подписчик",
+			path:       "/profile/alice/posts",
+			token:      func(s *testServer) string { return s.token(t, 3) },
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Friends", "Public"},
+		},
+		{
+			name:       "автор видит все свои посты",
+			path:       "/profile/alice/posts",
+			token:      func(s *testServer) string { return s.token(t, 1) },
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Private", "Friends", "Unlisted", "Public"},
+		},
+		{
+			name:       "пользователь без постов",
+			path:       "/profile/carol/posts",
+			token:      func(s *testServer) string { return "" },
+			wantStatus: http.StatusOK,
+			wantTitles: []string{},
+		},
+		{
+			name: "автор заблокировал читателя",
+			path: "/profile/alice/posts",
+			token: func(s *testServer) string {
+				s.repo.AddRelation(1, 3, repository.RelationBlock)
+				return s.token(t, 3)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "блокировка не скрывает посты от других читателей",
+			path: "/profile/alice/posts",
+			token: func(s *testServer) string {
+				s.repo.AddRelation(1, 2, repository.RelationBlock)
+				return s.token(t, 3)
+			},
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Friends", "Public"},
+		},
+		{
+			name: "скрытый автор доступен в профиле",
+			path: "/profile/alice/posts",
+			token: func(s *testServer) string {
+				s.repo.AddRelation(3, 1, repository.RelationMute)
+				return s.token(t, 3)
+			},
+			wantStatus: http.StatusOK,
+			wantTitles: []string{"Friends", "Public"},
+		},
+		{
+			name:       "неизвестный пользователь",
+			path:       "/profile/dave/posts",
+			token:      func(s *testServer) string { return "" },
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "персональный токен без права чтения",
+			path:       "/profile/alice/posts",
+			token:      func(s *testServer) string { return s.personalToken(3, middlewares.ScopeLikesWrite) },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "недействительный токен",
+			path:       "/profile/alice/posts",
+			token:      func(s *testServer) string { return "not-a-jwt" },
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			w := s.do(http.MethodGet, tt.path, tt.token(s), "")
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantTitles != nil {
+				if got := titles(t, w.Body.String()); strings.Join(got, ",") != strings.Join(tt.wantTitles, ",") {
+					t.Errorf("ожидались посты %v, получены %v", tt.wantTitles, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+// Package metrics содержит бизнес-метрики posts_service; метрики HTTP и пула соединений собирает blog/pkg/metrics
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// PostsCreated — число созданных постов
+	PostsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "blog_posts_created_total",
+		Help: "Posts created.",
+	})
+	// LikesToggled — поставленные (like) и снятые (unlike) лайки
+	LikesToggled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "blog_likes_toggled_total",
+		Help: "Likes added and removed, by action.",
+	}, []string{"action"})
+)
@@ -2,11 +2,14 @@ package middlewares
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"log"
 	"net/http"
-	"os"
+	"strings"
 
-	"github.com/dgrijalva/jwt-go"
+	"blog/pkg/apierror"
+	"blog/pkg/jwks"
 )
 
 type ContextKey string
@@ -14,59 +17,86 @@ type ContextKey string
 const (
 	UserIDKey ContextKey = "user_id"
 	TokenKey  ContextKey = "token"
+	// ScopesKey присутствует в контексте только для персональных токенов
+	ScopesKey ContextKey = "scopes"
 )
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Исключить эндпоинты /health и /ready
-		if r.URL.Path == "/health" || r.URL.Path == "/ready" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		tokenString := r.Header.Get("Authorization")
+// PersonalAccessTokenPrefix отличает персональные токены от JWT
+const PersonalAccessTokenPrefix = "blog_pat_"
 
-		if tokenString == "" {
-			http.Error(w, "Authorization token missing", http.StatusUnauthorized)
-			return
-		}
+// PersonalTokenLookup ищет действующий персональный токен по SHA-256 хэшу.
+// Возвращает ok=false, если токен не найден, отозван или истёк.
+type PersonalTokenLookup func(tokenHash string) (userID int, scopes []string, ok bool, err error)
 
-		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-			tokenString = tokenString[7:]
-		}
-
-		claims := jwt.MapClaims{}
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			log.Println("JWT_SECRET not found in environment")
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
+// RequireAuth пропускает к обработчику только запросы с действующим JWT или персональным токеном
+// и кладёт ID пользователя в контекст запроса
+func RequireAuth(verifier *jwks.Verifier, lookupPAT PersonalTokenLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				apierror.Error(w, r, http.StatusUnauthorized, "Authorization token missing")
+				return
+			}
+			if r, ok := authenticate(w, r, verifier, lookupPAT); ok {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
+// OptionalAuth пропускает анонимные запросы без ID пользователя в контексте.
+// Если токен передан, он проверяется так же строго, как в RequireAuth: недействительный токен — это 401,
+// а не анонимный доступ.
+func OptionalAuth(verifier *jwks.Verifier, lookupPAT PersonalTokenLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r, ok := authenticate(w, r, verifier, lookupPAT); ok {
+				next.ServeHTTP(w, r)
+			}
 		})
-		if err != nil || !token.Valid {
-			log.Println("AuthMiddleware: Invalid token")
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
+	}
+}
 
-		log.Printf("Claims: %v", claims)
+// authenticate проверяет токен из заголовка Authorization и возвращает запрос с ID пользователя в контексте.
+// При ошибке ответ уже записан в w.
+func authenticate(w http.ResponseWriter, r *http.Request, verifier *jwks.Verifier, lookupPAT PersonalTokenLookup) (*http.Request, bool) {
+	tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
 
-		userIDFloat, ok := claims["user_id"].(float64)
+	if strings.HasPrefix(tokenString, PersonalAccessTokenPrefix) {
+		sum := sha256.Sum256([]byte(tokenString))
+		userID, scopes, ok, err := lookupPAT(hex.EncodeToString(sum[:]))
+		if err != nil {
+			log.Printf("AuthMiddleware: failed to look up personal access token: %v", err)
+			apierror.Error(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return nil, false
+		}
 		if !ok {
-			log.Println("AuthMiddleware: user_id not found in claims")
-			http.Error(w, "Invalid user ID in token", http.StatusUnauthorized)
-			return
+			log.Println("AuthMiddleware: Invalid personal access token")
+			apierror.Error(w, r, http.StatusUnauthorized, "Invalid token")
+			return nil, false
 		}
-		userID := int(userIDFloat)
 
-		log.Printf("Authorized user: %d", userID)
+		log.Printf("Authorized user %d with personal access token", userID)
 
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		ctx = context.WithValue(ctx, TokenKey, tokenString)
+		ctx = context.WithValue(ctx, ScopesKey, scopes)
+		return r.WithContext(ctx), true
+	}
+
+	userID, _, err := verifier.Verify(tokenString)
+	if err != nil {
+		log.Printf("AuthMiddleware: %v", err)
+		apierror.Error(w, r, http.StatusUnauthorized, "Invalid token")
+		return nil, false
+	}
+
+	log.Printf("Authorized user: %d", userID)
 
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	ctx := context.WithValue(r.Context(), UserIDKey, userID)
+	ctx = context.WithValue(ctx, TokenKey, tokenString)
+	return r.WithContext(ctx), true
 }
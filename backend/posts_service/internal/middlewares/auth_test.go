@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"blog/pkg/jwks"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type staticKeys map[string]*rsa.PublicKey
+
+func (s staticKeys) Key(kid string) (*rsa.PublicKey, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	return nil, jwks.ErrUnknownKey
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRequireAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &jwks.Verifier{
+		Keys:     staticKeys{"k1": &key.PublicKey},
+		Issuer:   "auth-service",
+		Audience: "blog-api",
+	}
+	lookup := func(hash string) (int, []string, bool, error) {
+		return 5, []string{ScopePostsRead}, true, nil
+	}
+
+	valid := jwt.MapClaims{
+		"user_id": 1,
+		"iss":     "auth-service",
+		"aud":     "blog-api",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	withClaims := func(mod func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{}
+		for k, v := range valid {
+			c[k] = v
+		}
+		mod(c)
+		return c
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		scope      string
+		wantStatus int
+	}{
+		{"без токена", "", ScopePostsRead, http.StatusUnauthorized},
+		{"валидный JWT", "Bearer " + signToken(t, key, "k1", valid), ScopePostsWrite, http.StatusOK},
+		{"неизвестный kid", "Bearer " + signToken(t, key, "k2", valid), ScopePostsRead, http.StatusUnauthorized},
+		{"чужой issuer", "Bearer " + signToken(t, key, "k1", withClaims(func(c jwt.MapClaims) { c["iss"] = "evil" })), ScopePostsRead, http.StatusUnauthorized},
+		{"чужая аудитория", "Bearer " + signToken(t, key, "k1", withClaims(func(c jwt.MapClaims) { c["aud"] = "blog-api:2fa" })), ScopePostsRead, http.StatusUnauthorized},
+		{"без exp", "Bearer " + signToken(t, key, "k1", withClaims(func(c jwt.MapClaims) { delete(c, "exp") })), ScopePostsRead, http.StatusUnauthorized},
+		{"истёкший", "Bearer " + signToken(t, key, "k1", withClaims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })), ScopePostsRead, http.StatusUnauthorized},
+		{"персональный токен с правом", "Bearer blog_pat_abc", ScopePostsRead, http.StatusOK},
+		{"персональный токен без права", "Bearer blog_pat_abc", ScopePostsWrite, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireAuth(verifier, lookup)(RequireScope(tt.scope, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestOptionalAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &jwks.Verifier{
+		Keys:     staticKeys{"k1": &key.PublicKey},
+		Issuer:   "auth-service",
+		Audience: "blog-api",
+	}
+	lookup := func(hash string) (int, []string, bool, error) {
+		return 0, nil, false, nil
+	}
+	valid := jwt.MapClaims{
+		"user_id": 1,
+		"iss":     "auth-service",
+		"aud":     "blog-api",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantUserID int
+	}{
+		{"аноним", "", http.StatusOK, 0},
+		{"валидный JWT", "Bearer " + signToken(t, key, "k1", valid), http.StatusOK, 1},
+		{"недействительный JWT", "Bearer " + signToken(t, key, "k2", valid), http.StatusUnauthorized, 0},
+		{"неизвестный персональный токен", "Bearer blog_pat_abc", http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID int
+			handler := OptionalAuth(verifier, lookup)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = r.Context().Value(UserIDKey).(int)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d", tt.wantStatus, w.Code)
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("ожидался пользователь %d, получен %d", tt.wantUserID, gotUserID)
+			}
+		})
+	}
+}
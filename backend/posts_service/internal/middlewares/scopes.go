@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"blog/pkg/apierror"
+)
+
+// Права персональных токенов доступа
+const (
+	ScopePostsRead  = "posts:read"
+	ScopePostsWrite = "posts:write"
+	ScopeLikesWrite = "likes:write"
+)
+
+// HasScope проверяет, есть ли у запроса указанное право.
+// Запросы с JWT пользователя имеют все права, персональные токены — только выданные.
+func HasScope(r *http.Request, scope string) bool {
+	scopes, limited := r.Context().Value(ScopesKey).([]string)
+	if !limited {
+		return true
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope пропускает запрос к обработчику, только если у него есть нужное право
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !HasScope(r, scope) {
+			apierror.Error(w, r, http.StatusForbidden, "Token does not have the required scope: "+scope)
+			return
+		}
+		next(w, r)
+	}
+}
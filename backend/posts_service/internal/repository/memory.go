@@ -0,0 +1,485 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"posts_service/internal/database"
+)
+
+// Виды отношений между пользователями (таблица user_relations)
+const (
+	RelationFollow = "follow"
+	RelationMute   = "mute"
+	RelationBlock  = "block"
+)
+
+// Memory — хранилище в памяти с той же семантикой, что и SQL-запросы пакета database:
+// видимость постов, блокировки, каскадное удаление лайков и уведомлений.
+// Используется в тестах обработчиков.
+type Memory struct {
+	mu            sync.RWMutex
+	users         map[int]string
+	relations     map[relation]bool
+	tokens        map[string]database.PersonalAccessToken
+	posts         []memoryPost
+	likes         []memoryLike
+	notifications []memoryNotification
+	lastID        int
+}
+
+type relation struct {
+	userID, targetID int
+	kind             string
+}
+
+type memoryPost struct {
+	id         int
+	title      string
+	content    string
+	authorID   int
+	visibility string
+	createdAt  time.Time
+}
+
+type memoryLike struct {
+	id, postID, userID int
+}
+
+type memoryNotification struct {
+	database.ExportedNotification
+	userID int
+}
+
+// NewMemory создаёт пустое хранилище
+func NewMemory() *Memory {
+	return &Memory{
+		users:     make(map[int]string),
+		relations: make(map[relation]bool),
+		tokens:    make(map[string]database.PersonalAccessToken),
+	}
+}
+
+// AddUser добавляет пользователя (в Postgres пользователей создаёт users_service)
+func (m *Memory) AddUser(id int, username string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[id] = username
+}
+
+// AddRelation добавляет подписку, скрытие или блокировку userID → targetID
+func (m *Memory) AddRelation(userID, targetID int, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.relations[relation{userID, targetID, kind}] = true
+}
+
+// AddPersonalAccessToken добавляет действующий персональный токен по его SHA-256 хэшу
+func (m *Memory) AddPersonalAccessToken(tokenHash string, userID int, scopes []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastID++
+	m.tokens[tokenHash] = database.PersonalAccessToken{ID: m.lastID, UserID: userID, Scopes: scopes}
+}
+
+// AddNotification добавляет уведомление о лайке (в Postgres их создаёт notification_service)
+func (m *Memory) AddNotification(userID, postID, likerID int, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastID++
+	m.notifications = append(m.notifications, memoryNotification{
+		ExportedNotification: database.ExportedNotification{
+			ID:      m.lastID,
+			Type:    "like",
+			Message: message,
+			PostID:  &postID,
+			LikerID: &likerID,
+		},
+		userID: userID,
+	})
+}
+
+func (m *Memory) FetchPosts(viewerID int) ([]database.Post, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	posts := []database.Post{}
+	for i := len(m.posts) - 1; i >= 0; i-- {
+		p := &m.posts[i]
+		if m.relations[relation{viewerID, p.authorID, RelationMute}] ||
+			m.relations[relation{viewerID, p.authorID, RelationBlock}] ||
+			m.relations[relation{p.authorID, viewerID, RelationBlock}] {
+			continue
+		}
+		if post, ok := m.visiblePost(p, viewerID, true); ok {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (m *Memory) CreatePost(title, content string, authorID int, visibility string) (*database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	username, ok := m.users[authorID]
+	if !ok {
+		// В Postgres вставку отклоняет внешний ключ на users
+		return nil, errors.New("failed to insert post: author does not exist")
+	}
+	m.lastID++
+	m.posts = append(m.posts, memoryPost{
+		id:         m.lastID,
+		title:      title,
+		content:    content,
+		authorID:   authorID,
+		visibility: visibility,
+		createdAt:  time.Now(),
+	})
+	return &database.Post{
+		ID:             m.lastID,
+		Title:          title,
+		Content:        content,
+		AuthorID:       authorID,
+		AuthorUsername: username,
+		Visibility:     visibility,
+		Likes:          []interface{}{},
+	}, nil
+}
+
+func (m *Memory) FetchPostByID(postID, viewerID int) (*database.Post, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p := m.findPost(postID)
+	if p == nil || m.relations[relation{p.authorID, viewerID, RelationBlock}] {
+		return nil, nil
+	}
+	post, ok := m.visiblePost(p, viewerID, false)
+	if !ok {
+		return nil, nil
+	}
+	return &post, nil
+}
+
+func (m *Memory) GetPostOwner(postID int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if p := m.findPost(postID); p != nil {
+		return p.authorID, nil
+	}
+	return 0, nil
+}
+
+func (m *Memory) DeletePost(postID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deletePosts(func(p *memoryPost) bool { return p.id == postID })
+	return nil
+}
+
+func (m *Memory) FetchUserPosts(userID, viewerID int) ([]database.Post, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	posts := []database.Post{}
+	for i := len(m.posts) - 1; i >= 0; i-- {
+		if m.posts[i].authorID != userID {
+			continue
+		}
+		if post, ok := m.visiblePost(&m.posts[i], viewerID, true); ok {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (m *Memory) CanViewPost(postID, viewerID int) (int, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p := m.findPost(postID)
+	if p == nil {
+		return 0, false, nil
+	}
+	return p.authorID, m.visible(p, viewerID, false), nil
+}
+
+func (m *Memory) FetchUserStats(userID int) (*database.UserStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stats database.UserStats
+	for _, p := range m.posts {
+		if p.authorID == userID && p.visibility == database.VisibilityPublic {
+			stats.PostCount++
+		}
+	}
+	for _, l := range m.likes {
+		if p := m.findPost(l.postID); p != nil && p.authorID == userID && p.visibility == database.VisibilityPublic {
+			stats.LikesReceived++
+		}
+		if l.userID == userID {
+			stats.LikesGiven++
+		}
+	}
+	return &stats, nil
+}
+
+func (m *Memory) ExportUserContent(userID int) (*database.UserContentExport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	export := database.UserContentExport{
+		Posts:         []database.ExportedPost{},
+		LikesGiven:    []database.ExportedLike{},
+		LikesReceived: []database.ExportedLike{},
+		Notifications: []database.ExportedNotification{},
+	}
+	for _, p := range m.posts {
+		if p.authorID != userID {
+			continue
+		}
+		likeCount := 0
+		for _, l := range m.likes {
+			if l.postID == p.id {
+				likeCount++
+			}
+		}
+		export.Posts = append(export.Posts, database.ExportedPost{
+			ID:         p.id,
+			Title:      p.title,
+			Content:    p.content,
+			Visibility: p.visibility,
+			CreatedAt:  p.createdAt,
+			LikeCount:  likeCount,
+		})
+	}
+	for _, l := range m.likes {
+		p := m.findPost(l.postID)
+		like := database.ExportedLike{PostID: l.postID, PostTitle: p.title, UserID: l.userID}
+		if l.userID == userID {
+			export.LikesGiven = append(export.LikesGiven, like)
+		}
+		if p.authorID == userID {
+			export.LikesReceived = append(export.LikesReceived, like)
+		}
+	}
+	for _, n := range m.notifications {
+		if n.userID == userID {
+			export.Notifications = append(export.Notifications, n.ExportedNotification)
+		}
+	}
+	return &export, nil
+}
+
+func (m *Memory) DeleteUserContent(userID int) (*database.DeletedUserContent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted database.DeletedUserContent
+	owned := func(postID *int) bool {
+		if postID == nil {
+			return false
+		}
+		p := m.findPost(*postID)
+		return p != nil && p.authorID == userID
+	}
+
+	notifications := m.notifications[:0]
+	for _, n := range m.notifications {
+		if n.userID == userID || (n.LikerID != nil && *n.LikerID == userID) || owned(n.PostID) {
+			deleted.Notifications++
+			continue
+		}
+		notifications = append(notifications, n)
+	}
+	m.notifications = notifications
+
+	likes := m.likes[:0]
+	for _, l := range m.likes {
+		postID := l.postID
+		if l.userID == userID || owned(&postID) {
+			deleted.Likes++
+			continue
+		}
+		likes = append(likes, l)
+	}
+	m.likes = likes
+
+	deleted.Posts = int64(m.deletePosts(func(p *memoryPost) bool { return p.authorID == userID }))
+	return &deleted, nil
+}
+
+func (m *Memory) AddLike(postID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.findPost(postID) == nil {
+		return errors.New("failed to add like: post does not exist")
+	}
+	for _, l := range m.likes {
+		if l.postID == postID && l.userID == userID {
+			return nil
+		}
+	}
+	m.lastID++
+	m.likes = append(m.likes, memoryLike{id: m.lastID, postID: postID, userID: userID})
+	return nil
+}
+
+func (m *Memory) RemoveLike(postID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, l := range m.likes {
+		if l.postID == postID && l.userID == userID {
+			m.likes = append(m.likes[:i], m.likes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetLikes(postID int) ([]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var userIDs []int
+	for _, l := range m.likes {
+		if l.postID == postID {
+			userIDs = append(userIDs, l.userID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (m *Memory) FindUserIDByUsername(username string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for id, name := range m.users {
+		if name == username {
+			return id, nil
+		}
+	}
+	return 0, nil
+}
+
+func (m *Memory) FetchUsernames(userIDs []int) (map[int]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	usernames := make(map[int]string, len(userIDs))
+	for _, id := range userIDs {
+		if name, ok := m.users[id]; ok {
+			usernames[id] = name
+		}
+	}
+	return usernames, nil
+}
+
+func (m *Memory) IsBlocked(userID, targetID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.relations[relation{userID, targetID, RelationBlock}], nil
+}
+
+func (m *Memory) FindPersonalAccessToken(tokenHash string) (*database.PersonalAccessToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+func (m *Memory) findPost(postID int) *memoryPost {
+	for i := range m.posts {
+		if m.posts[i].id == postID {
+			return &m.posts[i]
+		}
+	}
+	return nil
+}
+
+// visible повторяет условие visibleTo из пакета database
+func (m *Memory) visible(p *memoryPost, viewerID int, listed bool) bool {
+	switch {
+	case p.visibility == database.VisibilityPublic, p.authorID == viewerID:
+		return true
+	case p.visibility == database.VisibilityUnlisted:
+		return !listed
+	case p.visibility == database.VisibilityFollowers:
+		return m.relations[relation{viewerID, p.authorID, RelationFollow}]
+	}
+	return false
+}
+
+// visiblePost собирает пост с автором и лайками, если он виден читателю.
+// Пост без автора в users не возвращается, как при JOIN в Postgres.
+func (m *Memory) visiblePost(p *memoryPost, viewerID int, listed bool) (database.Post, bool) {
+	username, ok := m.users[p.authorID]
+	if !ok || !m.visible(p, viewerID, listed) {
+		return database.Post{}, false
+	}
+
+	likes := []interface{}{}
+	for _, l := range m.likes {
+		if l.postID != p.id {
+			continue
+		}
+		var likerName interface{}
+		if name, ok := m.users[l.userID]; ok {
+			likerName = name
+		}
+		likes = append(likes, map[string]interface{}{"id": l.userID, "username": likerName})
+	}
+	return database.Post{
+		ID:             p.id,
+		Title:          p.title,
+		Content:        p.content,
+		AuthorID:       p.authorID,
+		AuthorUsername: username,
+		Visibility:     p.visibility,
+		Likes:          likes,
+	}, true
+}
+
+// deletePosts удаляет подходящие посты вместе с их лайками и уведомлениями (ON DELETE CASCADE)
+// и возвращает количество удалённых постов
+func (m *Memory) deletePosts(match func(p *memoryPost) bool) int {
+	removed := make(map[int]bool)
+	posts := m.posts[:0]
+	for i := range m.posts {
+		if match(&m.posts[i]) {
+			removed[m.posts[i].id] = true
+			continue
+		}
+		posts = append(posts, m.posts[i])
+	}
+	m.posts = posts
+
+	likes := m.likes[:0]
+	for _, l := range m.likes {
+		if !removed[l.postID] {
+			likes = append(likes, l)
+		}
+	}
+	m.likes = likes
+
+	notifications := m.notifications[:0]
+	for _, n := range m.notifications {
+		if n.PostID == nil || !removed[*n.PostID] {
+			notifications = append(notifications, n)
+		}
+	}
+	m.notifications = notifications
+
+	return len(removed)
+}
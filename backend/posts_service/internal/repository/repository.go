@@ -0,0 +1,64 @@
+// Package repository описывает хранилище данных posts_service.
+// Обработчики работают только с интерфейсами: в сервисе используется SQL (Postgres или SQLite),
+// в тестах — Memory.
+package repository
+
+import "posts_service/internal/database"
+
+// PostRepository хранит посты и связанные с ними данные пользователя
+type PostRepository interface {
+	// FetchPosts возвращает ленту читателя viewerID (0 — анонимный читатель), новые посты первыми
+	FetchPosts(viewerID int) ([]database.Post, error)
+	CreatePost(title, content string, authorID int, visibility string) (*database.Post, error)
+	// FetchPostByID возвращает nil, если пост не найден или недоступен читателю
+	FetchPostByID(postID, viewerID int) (*database.Post, error)
+	// GetPostOwner возвращает 0, если пост не найден
+	GetPostOwner(postID int) (int, error)
+	DeletePost(postID int) error
+	// FetchUserPosts возвращает посты автора userID, которые видны читателю в ленте
+	FetchUserPosts(userID, viewerID int) ([]database.Post, error)
+	// CanViewPost возвращает автора поста (0, если поста нет) и доступность поста по прямой ссылке
+	CanViewPost(postID, viewerID int) (authorID int, visible bool, err error)
+	FetchUserStats(userID int) (*database.UserStats, error)
+	ExportUserContent(userID int) (*database.UserContentExport, error)
+	DeleteUserContent(userID int) (*database.DeletedUserContent, error)
+}
+
+// LikeRepository хранит лайки постов
+type LikeRepository interface {
+	// AddLike идемпотентен: повторный лайк ничего не меняет
+	AddLike(postID, userID int) error
+	RemoveLike(postID, userID int) error
+	// GetLikes возвращает ID пользователей, лайкнувших пост, в порядке лайков
+	GetLikes(postID int) ([]int, error)
+}
+
+// UserRepository читает данные пользователей, которыми владеют users_service и auth_service
+type UserRepository interface {
+	// FindUserIDByUsername возвращает 0, если пользователь не найден
+	FindUserIDByUsername(username string) (int, error)
+	// FetchUsernames возвращает имена найденных пользователей
+	FetchUsernames(userIDs []int) (map[int]string, error)
+	// IsBlocked сообщает, заблокировал ли userID пользователя targetID
+	IsBlocked(userID, targetID int) (bool, error)
+	// FindPersonalAccessToken возвращает nil, если токен не найден, отозван или истёк
+	FindPersonalAccessToken(tokenHash string) (*database.PersonalAccessToken, error)
+}
+
+// Repository объединяет все хранилища сервиса: SQL и Memory реализуют их вместе
+type Repository interface {
+	PostRepository
+	LikeRepository
+	UserRepository
+}
+
+var (
+	_ Repository     = (*SQL)(nil)
+	_ Repository     = (*Memory)(nil)
+	_ PostRepository = (*SQL)(nil)
+	_ LikeRepository = (*SQL)(nil)
+	_ UserRepository = (*SQL)(nil)
+	_ PostRepository = (*Memory)(nil)
+	_ LikeRepository = (*Memory)(nil)
+	_ UserRepository = (*Memory)(nil)
+)
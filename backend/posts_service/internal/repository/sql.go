@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"database/sql"
+
+	"posts_service/internal/database"
+)
+
+// SQL реализует все репозитории поверх общей базы Postgres или SQLite (DB_DRIVER)
+type SQL struct {
+	db *sql.DB
+}
+
+// NewSQL создаёт репозитории на подключении к базе
+func NewSQL(db *sql.DB) *SQL {
+	return &SQL{db: db}
+}
+
+func (s *SQL) FetchPosts(viewerID int) ([]database.Post, error) {
+	return database.FetchPosts(s.db, viewerID)
+}
+
+func (s *SQL) CreatePost(title, content string, authorID int, visibility string) (*database.Post, error) {
+	return database.CreatePost(s.db, title, content, authorID, visibility)
+}
+
+func (s *SQL) FetchPostByID(postID, viewerID int) (*database.Post, error) {
+	return database.FetchPostByID(s.db, postID, viewerID)
+}
+
+func (s *SQL) GetPostOwner(postID int) (int, error) {
+	return database.GetPostOwner(s.db, postID)
+}
+
+func (s *SQL) DeletePost(postID int) error {
+	return database.DeletePost(s.db, postID)
+}
+
+func (s *SQL) FetchUserPosts(userID, viewerID int) ([]database.Post, error) {
+	return database.FetchUserPosts(s.db, userID, viewerID)
+}
+
+func (s *SQL) CanViewPost(postID, viewerID int) (int, bool, error) {
+	return database.CanViewPost(s.db, postID, viewerID)
+}
+
+func (s *SQL) FetchUserStats(userID int) (*database.UserStats, error) {
+	return database.FetchUserStats(s.db, userID)
+}
+
+func (s *SQL) ExportUserContent(userID int) (*database.UserContentExport, error) {
+	return database.ExportUserContent(s.db, userID)
+}
+
+func (s *SQL) DeleteUserContent(userID int) (*database.DeletedUserContent, error) {
+	return database.DeleteUserContent(s.db, userID)
+}
+
+func (s *SQL) AddLike(postID, userID int) error {
+	return database.AddLike(s.db, postID, userID)
+}
+
+func (s *SQL) RemoveLike(postID, userID int) error {
+	return database.RemoveLike(s.db, postID, userID)
+}
+
+func (s *SQL) GetLikes(postID int) ([]int, error) {
+	return database.GetLikes(s.db, postID)
+}
+
+func (s *SQL) FindUserIDByUsername(username string) (int, error) {
+	return database.FindUserIDByUsername(s.db, username)
+}
+
+func (s *SQL) FetchUsernames(userIDs []int) (map[int]string, error) {
+	return database.FetchUsernames(s.db, userIDs)
+}
+
+func (s *SQL) IsBlocked(userID, targetID int) (bool, error) {
+	return database.IsBlocked(s.db, userID, targetID)
+}
+
+func (s *SQL) FindPersonalAccessToken(tokenHash string) (*database.PersonalAccessToken, error) {
+	return database.FindPersonalAccessToken(s.db, tokenHash)
+}
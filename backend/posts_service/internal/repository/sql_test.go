@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newMock(t *testing.T) (*SQL, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return NewSQL(db), mock
+}
+
+var postColumns = []string{"id", "title", "content", "author_id", "author_username", "visibility"}
+
+func TestSQLFetchPosts(t *testing.T) {
+	repo, mock := newMock(t)
+
+	mock.ExpectQuery("FROM posts").WithArgs(0).WillReturnRows(sqlmock.NewRows(postColumns))
+	mock.ExpectQuery("FROM posts").WithArgs(0).WillReturnRows(sqlmock.NewRows(postColumns).
+		AddRow(1, "Title", "Content", 2, "alice", "public").
+		AddRow(4, "Other", "Content", 2, "alice", "public"))
+	// Лайки всех постов ленты загружаются одним запросом
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE likes.post_id IN ($1, $2)")).WithArgs(1, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "user_id", "username"}).AddRow(1, 3, "bob").AddRow(1, 5, nil))
+
+	// Пустая лента — пустой список, а не nil
+	posts, err := repo.FetchPosts(0)
+	if err != nil || posts == nil || len(posts) != 0 {
+		t.Fatalf("ожидался пустой список, получено %v, %v", posts, err)
+	}
+
+	posts, err = repo.FetchPosts(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 || len(posts[0].Likes) != 2 || posts[0].AuthorUsername != "alice" {
+		t.Fatalf("неожиданные посты: %+v", posts)
+	}
+	if likes := posts[1].Likes; likes == nil || len(likes) != 0 {
+		t.Errorf("у поста без лайков ожидался пустой список, получено %v", likes)
+	}
+}
+
+func TestSQLFetchPostByIDChecksVisibility(t *testing.T) {
+	repo, mock := newMock(t)
+
+	// Запрос учитывает видимость для читателя: пустой результат означает, что пост недоступен
+	mock.ExpectQuery("posts.visibility IN \\('public', 'unlisted'\\)").
+		WithArgs(7, 2).
+		WillReturnRows(sqlmock.NewRows(postColumns))
+
+	post, err := repo.FetchPostByID(7, 2)
+	if err != nil || post != nil {
+		t.Errorf("ожидался nil, получено %v, %v", post, err)
+	}
+}
+
+func TestSQLCanViewPostAndIsBlocked(t *testing.T) {
+	repo, mock := newMock(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT posts.author_id,")).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"author_id", "visible"}).AddRow(5, false))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM user_relations WHERE user_id = $1 AND target_id = $2 AND kind = 'block'")).
+		WithArgs(5, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	authorID, visible, err := repo.CanViewPost(1, 2)
+	if err != nil || authorID != 5 || visible {
+		t.Errorf("неожиданный результат: %d, %v, %v", authorID, visible, err)
+	}
+	blocked, err := repo.IsBlocked(5, 2)
+	if err != nil || !blocked {
+		t.Errorf("ожидалась блокировка: %v, %v", blocked, err)
+	}
+}
+
+func TestSQLLikes(t *testing.T) {
+	repo, mock := newMock(t)
+
+	// Повторный лайк упирается в уникальный индекс (post_id, user_id)
+	mock.ExpectExec(regexp.QuoteMeta("ON CONFLICT (post_id, user_id) DO NOTHING")).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT user_id FROM likes WHERE post_id = $1 ORDER BY id")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE id IN ($1, $2) AND deleted_at IS NULL")).
+		WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "bob"))
+
+	if err := repo.AddLike(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	userIDs, err := repo.GetLikes(1)
+	if err != nil || len(userIDs) != 2 {
+		t.Fatalf("неожиданные лайки: %v, %v", userIDs, err)
+	}
+	usernames, err := repo.FetchUsernames(userIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := usernames[3]; ok || usernames[2] != "bob" {
+		t.Errorf("неожиданные имена: %v", usernames)
+	}
+}
+
+func TestSQLDeleteUserContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		failSQL bool
+	}{
+		{name: "уведомления, лайки и посты удаляются в одной транзакции"},
+		{name: "ошибка откатывает транзакцию", failSQL: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMock(t)
+
+			mock.ExpectBegin()
+			notifications := mock.ExpectExec(regexp.QuoteMeta("DELETE FROM notifications")).WithArgs(1)
+			if tt.failSQL {
+				notifications.WillReturnError(sqlmock.ErrCancelled)
+				mock.ExpectRollback()
+			} else {
+				notifications.WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM likes")).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM posts WHERE author_id = $1")).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			deleted, err := repo.DeleteUserContent(1)
+			if tt.failSQL {
+				if err == nil {
+					t.Error("ожидалась ошибка")
+				}
+				return
+			}
+			if err != nil || deleted.Posts != 1 || deleted.Likes != 3 || deleted.Notifications != 2 {
+				t.Errorf("неожиданный результат: %+v, %v", deleted, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"blog/pkg/migrate"
+	"posts_service/internal/database"
+	"posts_service/migrations"
+)
+
+// usersSchema — таблицы users_service, которые читает posts_service.
+// В сервисе их создают миграции users_service в том же файле базы.
+const usersSchema = `
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username VARCHAR(255) NOT NULL UNIQUE,
+		deleted_at TIMESTAMP
+	);
+	CREATE TABLE user_relations (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		target_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind VARCHAR(16) NOT NULL,
+		PRIMARY KEY (user_id, target_id, kind)
+	);
+	CREATE TABLE personal_access_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash CHARACTER(64) NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		expires_at TIMESTAMP,
+		last_used_at TIMESTAMP,
+		revoked_at TIMESTAMP
+	);
+`
+
+// repositories — все репозитории одного хранилища
+type repositories interface {
+	PostRepository
+	LikeRepository
+	UserRepository
+}
+
+// fixture заполняет таблицы, которыми posts_service не владеет
+type fixture interface {
+	AddUser(id int, username string)
+	AddRelation(userID, targetID int, kind string)
+	AddPersonalAccessToken(tokenHash string, userID int, scopes []string)
+}
+
+// sqliteFixture пишет данные users_service напрямую в базу SQLite
+type sqliteFixture struct {
+	t  *testing.T
+	db *sql.DB
+}
+
+func (f sqliteFixture) exec(query string, args ...interface{}) {
+	f.t.Helper()
+	if _, err := f.db.Exec(query, args...); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f sqliteFixture) AddUser(id int, username string) {
+	f.exec("INSERT INTO users (id, username) VALUES ($1, $2)", id, username)
+}
+
+func (f sqliteFixture) AddRelation(userID, targetID int, kind string) {
+	f.exec("INSERT INTO user_relations (user_id, target_id, kind) VALUES ($1, $2, $3)", userID, targetID, kind)
+}
+
+func (f sqliteFixture) AddPersonalAccessToken(tokenHash string, userID int, scopes []string) {
+	f.exec("INSERT INTO personal_access_tokens (user_id, token_hash, scopes) VALUES ($1, $2, $3)", userID, tokenHash, strings.Join(scopes, " "))
+}
+
+// newSQLite создаёт базу SQLite во временном каталоге и применяет к ней миграции сервиса
+func newSQLite(t *testing.T) (*SQL, sqliteFixture) {
+	t.Helper()
+
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "blog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(usersSchema); err != nil {
+		t.Fatalf("не удалось создать таблицы users_service: %v", err)
+	}
+	list, err := migrate.Load(migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &migrate.Migrator{DB: db, Service: "posts-service", Migrations: list, SQLite: true}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("миграции не применились: %v", err)
+	}
+	return NewSQL(db), sqliteFixture{t: t, db: db}
+}
+
+// TestRepositories проверяет, что SQL-запросы на SQLite и хранилище в памяти ведут себя одинаково
+func TestRepositories(t *testing.T) {
+	stores := []struct {
+		name string
+		open func(t *testing.T) (repositories, fixture)
+	}{
+		{name: "sqlite", open: func(t *testing.T) (repositories, fixture) { return newSQLite(t) }},
+		{name: "memory", open: func(t *testing.T) (repositories, fixture) {
+			m := NewMemory()
+			return m, m
+		}},
+	}
+
+	for _, store := range stores {
+		t.Run(store.name, func(t *testing.T) {
+			repo, fx := store.open(t)
+			testRepositories(t, repo, fx)
+		})
+	}
+}
+
+func testRepositories(t *testing.T, repo repositories, fx fixture) {
+	// Те же данные, что в тестах обработчиков: alice (1), bob (2), carol (3), carol подписана на alice
+	fx.AddUser(1, "alice")
+	fx.AddUser(2, "bob")
+	fx.AddUser(3, "carol")
+	fx.AddRelation(3, 1, RelationFollow)
+	fx.AddPersonalAccessToken("hash", 2, []string{"posts:read", "likes:write"})
+
+	for _, p := range []struct{ title, visibility string }{
+		{"Public", "public"}, {"Unlisted", "unlisted"}, {"Friends", "followers"}, {"Private", "private"},
+	} {
+		if _, err := repo.CreatePost(p.title, "Text", 1, p.visibility); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bobPost, err := repo.CreatePost("Bob", "Text", 2, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bobPost.AuthorUsername != "bob" || bobPost.Likes == nil {
+		t.Errorf("неожиданный новый пост: %+v", bobPost)
+	}
+	if _, err := repo.CreatePost("Ghost", "Text", 42, "public"); err == nil {
+		t.Error("пост несуществующего автора должен отклоняться")
+	}
+	publicID := findPost(t, repo, 1, "Public")
+
+	titles := func(posts []database.Post) string {
+		var result []string
+		for _, p := range posts {
+			result = append(result, p.Title)
+		}
+		return strings.Join(result, ",")
+	}
+	feeds := []struct {
+		viewer int
+		want   string
+	}{
+		{viewer: 0, want: "Bob,Public"},
+		{viewer: 1, want: "Bob,Private,Friends,Unlisted,Public"},
+		{viewer: 3, want: "Bob,Friends,Public"},
+	}
+	for _, f := range feeds {
+		posts, err := repo.FetchPosts(f.viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := titles(posts); got != f.want {
+			t.Errorf("лента читателя %d: ожидалось %s, получено %s", f.viewer, f.want, got)
+		}
+	}
+
+	// Повторный лайк не дублируется
+	for _, userID := range []int{2, 2, 3} {
+		if err := repo.AddLike(publicID, userID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if likes, err := repo.GetLikes(publicID); err != nil || len(likes) != 2 || likes[0] != 2 || likes[1] != 3 {
+		t.Errorf("неожиданные лайки: %v, %v", likes, err)
+	}
+	post, err := repo.FetchPostByID(publicID, 0)
+	if err != nil || post == nil {
+		t.Fatalf("публичный пост недоступен: %v", err)
+	}
+	if len(post.Likes) != 2 {
+		t.Errorf("ожидалось 2 лайка, получено %v", post.Likes)
+	}
+
+	usernames, err := repo.FetchUsernames([]int{2, 3, 99})
+	if err != nil || len(usernames) != 2 || usernames[2] != "bob" || usernames[3] != "carol" {
+		t.Errorf("неожиданные имена: %v, %v", usernames, err)
+	}
+	if id, err := repo.FindUserIDByUsername("bob"); err != nil || id != 2 {
+		t.Errorf("ожидался ID 2, получено %d, %v", id, err)
+	}
+	if id, err := repo.FindUserIDByUsername("nobody"); err != nil || id != 0 {
+		t.Errorf("ожидался ID 0, получено %d, %v", id, err)
+	}
+
+	token, err := repo.FindPersonalAccessToken("hash")
+	if err != nil || token == nil || token.UserID != 2 || strings.Join(token.Scopes, " ") != "posts:read likes:write" {
+		t.Errorf("неожиданный токен: %+v, %v", token, err)
+	}
+	if token, err := repo.FindPersonalAccessToken("unknown"); err != nil || token != nil {
+		t.Errorf("неизвестный токен найден: %+v, %v", token, err)
+	}
+
+	friendsID := findPost(t, repo, 1, "Friends")
+	if authorID, visible, err := repo.CanViewPost(friendsID, 2); err != nil || authorID != 1 || visible {
+		t.Errorf("пост для подписчиков виден не подписчику: %d, %v, %v", authorID, visible, err)
+	}
+	if authorID, visible, err := repo.CanViewPost(friendsID, 3); err != nil || authorID != 1 || !visible {
+		t.Errorf("пост для подписчиков не виден подписчику: %d, %v, %v", authorID, visible, err)
+	}
+
+	fx.AddRelation(1, 2, RelationBlock)
+	if blocked, err := repo.IsBlocked(1, 2); err != nil || !blocked {
+		t.Errorf("ожидалась блокировка: %v, %v", blocked, err)
+	}
+	if post, err := repo.FetchPostByID(publicID, 2); err != nil || post != nil {
+		t.Errorf("заблокированному читателю доступен пост: %+v, %v", post, err)
+	}
+
+	// Из четырёх постов alice в статистику попадает только публичный
+	stats, err := repo.FetchUserStats(1)
+	if err != nil || stats.PostCount != 1 || stats.LikesReceived != 2 || stats.LikesGiven != 0 {
+		t.Errorf("неожиданная статистика: %+v, %v", stats, err)
+	}
+	export, err := repo.ExportUserContent(2)
+	if err != nil || len(export.Posts) != 1 || len(export.LikesGiven) != 1 || export.Posts[0].CreatedAt.IsZero() {
+		t.Errorf("неожиданная выгрузка: %+v, %v", export, err)
+	}
+
+	deleted, err := repo.DeleteUserContent(1)
+	if err != nil || deleted.Posts != 4 || deleted.Likes != 2 {
+		t.Errorf("неожиданный результат удаления: %+v, %v", deleted, err)
+	}
+	if owner, err := repo.GetPostOwner(publicID); err != nil || owner != 0 {
+		t.Errorf("пост удалённого пользователя остался: %d, %v", owner, err)
+	}
+	posts, err := repo.FetchUserPosts(2, 0)
+	if err != nil || titles(posts) != "Bob" {
+		t.Errorf("неожиданные посты bob: %v, %v", posts, err)
+	}
+}
+
+// findPost возвращает ID поста автора по заголовку
+func findPost(t *testing.T, repo repositories, authorID int, title string) int {
+	t.Helper()
+	posts, err := repo.FetchUserPosts(authorID, authorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range posts {
+		if p.Title == title {
+			return p.ID
+		}
+	}
+	t.Fatalf("пост %q не найден", title)
+	return 0
+}
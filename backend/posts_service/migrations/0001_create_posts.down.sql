@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.notifications;
+DROP TABLE IF EXISTS public.likes;
+DROP TABLE IF EXISTS public.posts;
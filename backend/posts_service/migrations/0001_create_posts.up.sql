@@ -0,0 +1,30 @@
+--
+-- Исходная схема постов, лайков и уведомлений (как в sql/backup). IF NOT EXISTS позволяет
+-- применить миграцию к базе, восстановленной из дампа: существующие таблицы не трогаются.
+-- Таблица users принадлежит users_service: его миграции применяются первыми.
+--
+
+CREATE TABLE IF NOT EXISTS public.posts (
+    id serial PRIMARY KEY,
+    user_id integer CONSTRAINT posts_user_id_fkey REFERENCES public.users(id) ON DELETE CASCADE,
+    title character varying(255) NOT NULL,
+    content text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS public.likes (
+    id serial PRIMARY KEY,
+    post_id integer CONSTRAINT likes_post_id_fkey REFERENCES public.posts(id) ON DELETE CASCADE,
+    user_id integer CONSTRAINT likes_user_id_fkey REFERENCES public.users(id) ON DELETE CASCADE
+);
+
+-- Уведомления читает и пишет notifications_service; posts_service удаляет и выгружает их
+-- вместе с данными пользователя, поэтому таблица создаётся здесь
+CREATE TABLE IF NOT EXISTS public.notifications (
+    id serial PRIMARY KEY,
+    user_id integer CONSTRAINT notifications_user_id_fkey REFERENCES public.users(id) ON DELETE CASCADE,
+    post_id integer CONSTRAINT notifications_post_id_fkey REFERENCES public.posts(id) ON DELETE CASCADE,
+    message text NOT NULL,
+    is_read boolean DEFAULT false,
+    liker_id integer CONSTRAINT notifications_liker_id_fkey REFERENCES public.users(id) ON DELETE CASCADE,
+    type character varying(255) NOT NULL
+);
@@ -0,0 +1,10 @@
+ALTER TABLE public.posts DROP COLUMN IF EXISTS created_at;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'posts_author_id_fkey') THEN
+        ALTER TABLE public.posts RENAME CONSTRAINT posts_author_id_fkey TO posts_user_id_fkey;
+    END IF;
+END $$;
+
+ALTER TABLE public.posts RENAME COLUMN author_id TO user_id;
@@ -0,0 +1,21 @@
+--
+-- Код работает с posts.author_id и posts.created_at, а в дампе колонка называется user_id
+-- и времени создания нет. Переименование выполняется, только если оно ещё не сделано вручную.
+--
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = 'public' AND table_name = 'posts' AND column_name = 'user_id')
+       AND NOT EXISTS (SELECT 1 FROM information_schema.columns
+                       WHERE table_schema = 'public' AND table_name = 'posts' AND column_name = 'author_id') THEN
+        ALTER TABLE public.posts RENAME COLUMN user_id TO author_id;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'posts_user_id_fkey') THEN
+        ALTER TABLE public.posts RENAME CONSTRAINT posts_user_id_fkey TO posts_author_id_fkey;
+    END IF;
+END $$;
+
+-- У существующих постов точного времени нет: они получают время применения миграции
+ALTER TABLE public.posts ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now() NOT NULL;
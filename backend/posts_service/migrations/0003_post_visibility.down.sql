@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.posts_author_visibility_idx;
+ALTER TABLE public.posts DROP CONSTRAINT IF EXISTS posts_visibility_check;
+ALTER TABLE public.posts DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,11 @@
+--
+-- Видимость постов
+--
+
+-- public — всем; unlisted — по прямой ссылке, но не в лентах;
+-- followers — только подписчикам автора; private — только автору
+ALTER TABLE public.posts ADD COLUMN IF NOT EXISTS visibility character varying(16) DEFAULT 'public' NOT NULL;
+ALTER TABLE public.posts DROP CONSTRAINT IF EXISTS posts_visibility_check;
+ALTER TABLE public.posts ADD CONSTRAINT posts_visibility_check CHECK (visibility IN ('public', 'unlisted', 'followers', 'private'));
+
+CREATE INDEX IF NOT EXISTS posts_author_visibility_idx ON public.posts (author_id, visibility);
@@ -0,0 +1,28 @@
+-- Откат в обратном порядке. Удалённые миграцией лайки и посты без автора не восстанавливаются.
+
+DROP INDEX IF EXISTS public.notifications_liker_id_idx;
+DROP INDEX IF EXISTS public.notifications_post_id_idx;
+DROP INDEX IF EXISTS public.notifications_user_id_idx;
+DROP INDEX IF EXISTS public.likes_user_id_idx;
+DROP INDEX IF EXISTS public.posts_author_created_at_idx;
+DROP INDEX IF EXISTS public.posts_created_at_idx;
+
+-- Уникальность лайков и внешние ключи, добавленные миграцией (помечены комментарием 'migration 0004').
+-- Внешние ключи из исходной схемы комментария не имеют и остаются.
+DO $$
+DECLARE
+    c record;
+BEGIN
+    FOR c IN
+        SELECT conrelid::regclass AS tbl, conname
+        FROM pg_constraint
+        WHERE conrelid IN ('public.posts'::regclass, 'public.likes'::regclass)
+          AND obj_description(oid, 'pg_constraint') = 'migration 0004'
+    LOOP
+        EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', c.tbl, c.conname);
+    END LOOP;
+END $$;
+
+ALTER TABLE public.posts ALTER COLUMN author_id DROP NOT NULL;
+ALTER TABLE public.likes ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE public.likes ALTER COLUMN post_id DROP NOT NULL;
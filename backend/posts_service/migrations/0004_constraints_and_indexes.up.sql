@@ -0,0 +1,53 @@
+--
+-- Ограничения, на которые опирается код, и индексы под частые запросы
+--
+
+-- Лайк и пост без автора не имеют смысла
+DELETE FROM public.likes WHERE post_id IS NULL OR user_id IS NULL;
+ALTER TABLE public.likes ALTER COLUMN post_id SET NOT NULL;
+ALTER TABLE public.likes ALTER COLUMN user_id SET NOT NULL;
+DELETE FROM public.posts WHERE author_id IS NULL;
+ALTER TABLE public.posts ALTER COLUMN author_id SET NOT NULL;
+
+-- addLike использует ON CONFLICT (post_id, user_id): без уникального ограничения запрос падает.
+-- Перед добавлением ограничения удаляются повторные лайки, если они успели появиться.
+-- Ограничения, которые добавляет эта миграция, помечаются комментарием: по нему
+-- откат удаляет ровно их и не трогает существовавшие раньше.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'likes_unique_post_user') THEN
+        DELETE FROM public.likes a USING public.likes b
+        WHERE a.post_id = b.post_id AND a.user_id = b.user_id AND a.id > b.id;
+        ALTER TABLE public.likes ADD CONSTRAINT likes_unique_post_user UNIQUE (post_id, user_id);
+        COMMENT ON CONSTRAINT likes_unique_post_user ON public.likes IS 'migration 0004';
+    END IF;
+
+    -- Внешние ключи могли не попасть в базы, созданные не из дампа
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'posts_author_id_fkey') THEN
+        ALTER TABLE public.posts ADD CONSTRAINT posts_author_id_fkey
+            FOREIGN KEY (author_id) REFERENCES public.users(id) ON DELETE CASCADE;
+        COMMENT ON CONSTRAINT posts_author_id_fkey ON public.posts IS 'migration 0004';
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'likes_post_id_fkey') THEN
+        ALTER TABLE public.likes ADD CONSTRAINT likes_post_id_fkey
+            FOREIGN KEY (post_id) REFERENCES public.posts(id) ON DELETE CASCADE;
+        COMMENT ON CONSTRAINT likes_post_id_fkey ON public.likes IS 'migration 0004';
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'likes_user_id_fkey') THEN
+        ALTER TABLE public.likes ADD CONSTRAINT likes_user_id_fkey
+            FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+        COMMENT ON CONSTRAINT likes_user_id_fkey ON public.likes IS 'migration 0004';
+    END IF;
+END $$;
+
+-- Лента и посты пользователя сортируются по времени создания
+CREATE INDEX IF NOT EXISTS posts_created_at_idx ON public.posts (created_at DESC);
+CREATE INDEX IF NOT EXISTS posts_author_created_at_idx ON public.posts (author_id, created_at DESC);
+
+-- Лайки пользователя (статистика, выгрузка, удаление аккаунта); лайки поста покрывает likes_unique_post_user
+CREATE INDEX IF NOT EXISTS likes_user_id_idx ON public.likes (user_id);
+
+-- Уведомления получателя и удаление уведомлений вместе с лайками и постами
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON public.notifications (user_id);
+CREATE INDEX IF NOT EXISTS notifications_post_id_idx ON public.notifications (post_id);
+CREATE INDEX IF NOT EXISTS notifications_liker_id_idx ON public.notifications (liker_id);
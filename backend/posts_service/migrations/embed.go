@@ -0,0 +1,20 @@
+// Package migrations содержит SQL-миграции posts_service.
+// Файлы именуются 0001_name.up.sql / 0001_name.down.sql и применяются подкомандой migrate.
+// Миграции для SQLite (DB_DRIVER=sqlite) лежат в каталоге sqlite и нумеруются отдельно.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS — встроенные в бинарник файлы миграций для Postgres
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLite — встроенные в бинарник файлы миграций для SQLite
+var SQLite, _ = fs.Sub(sqliteFS, "sqlite")
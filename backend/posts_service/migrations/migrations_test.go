@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"io/fs"
+	"testing"
+
+	"blog/pkg/migrate"
+)
+
+// TestMigrations проверяет, что миграции сервиса собираются без пропусков версий
+func TestMigrations(t *testing.T) {
+	for name, fsys := range map[string]fs.FS{"postgres": FS, "sqlite": SQLite} {
+		t.Run(name, func(t *testing.T) {
+			list, err := migrate.Load(fsys)
+			if err != nil {
+				t.Fatalf("Миграции не загрузились: %v", err)
+			}
+			if len(list) == 0 {
+				t.Fatal("Миграций нет")
+			}
+			for i, m := range list {
+				if m.Version != int64(i+1) {
+					t.Fatalf("Ожидалась версия %d, получено %d (%s)", i+1, m.Version, m.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+--
+-- Схема posts_service для SQLite (DB_DRIVER=sqlite): та же, что дают миграции Postgres 0001–0004.
+-- Таблица users принадлежит users_service: его миграции применяются первыми к тому же файлу базы.
+-- Новая миграция Postgres добавляется и сюда, следующим номером.
+--
+
+CREATE TABLE posts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    author_id INTEGER NOT NULL CONSTRAINT posts_author_id_fkey REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')) NOT NULL,
+    visibility VARCHAR(16) DEFAULT 'public' NOT NULL
+        CONSTRAINT posts_visibility_check CHECK (visibility IN ('public', 'unlisted', 'followers', 'private'))
+);
+
+CREATE INDEX posts_author_visibility_idx ON posts (author_id, visibility);
+CREATE INDEX posts_created_at_idx ON posts (created_at DESC);
+CREATE INDEX posts_author_created_at_idx ON posts (author_id, created_at DESC);
+
+CREATE TABLE likes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL CONSTRAINT likes_post_id_fkey REFERENCES posts(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL CONSTRAINT likes_user_id_fkey REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT likes_unique_post_user UNIQUE (post_id, user_id)
+);
+
+CREATE INDEX likes_user_id_idx ON likes (user_id);
+
+-- Уведомления читает и пишет notifications_service; posts_service удаляет и выгружает их
+-- вместе с данными пользователя, поэтому таблица создаётся здесь
+CREATE TABLE notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER CONSTRAINT notifications_user_id_fkey REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER CONSTRAINT notifications_post_id_fkey REFERENCES posts(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    is_read BOOLEAN DEFAULT FALSE,
+    liker_id INTEGER CONSTRAINT notifications_liker_id_fkey REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id);
+CREATE INDEX notifications_post_id_idx ON notifications (post_id);
+CREATE INDEX notifications_liker_id_idx ON notifications (liker_id);
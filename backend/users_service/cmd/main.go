@@ -1,14 +1,30 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"blog/pkg/apierror"
+	"blog/pkg/health"
+	"blog/pkg/jwks"
+	"blog/pkg/metrics"
+	"blog/pkg/migrate"
+	"blog/pkg/openapi"
+	"blog/pkg/server"
+	"blog/pkg/serviceauth"
+	"users_service/internal/apispec"
+	"users_service/internal/config"
 	"users_service/internal/database"
+	"users_service/internal/events"
+	"users_service/internal/export"
 	"users_service/internal/handlers"
-
-	"github.com/gorilla/mux"
+	"users_service/internal/storage"
+	"users_service/migrations"
 )
 
 func loggingMiddleware(next http.Handler) http.Handler {
@@ -24,42 +40,155 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func readyHandler(w http.ResponseWriter, r *http.Request) {
-	// Readiness Probe: сервис готов обслуживать запросы
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Ready"))
+// newMigrator собирает миграции сервиса из встроенных файлов для выбранного драйвера базы
+func newMigrator(db *sql.DB, driver string) *migrate.Migrator {
+	sqlite := driver == database.DriverSQLite
+	var files fs.FS = migrations.FS
+	if sqlite {
+		files = migrations.SQLite
+	}
+	list, err := migrate.Load(files)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	return &migrate.Migrator{DB: db, Service: "users-service", Migrations: list, Log: log.Printf, SQLite: sqlite}
+}
+
+// runMigrate выполняет команду migrate: users-service migrate up | down [N] | status | to VERSION.
+// Ей нужно только подключение к базе, остальные настройки не читаются.
+func runMigrate(args []string) {
+	dbConfig, err := config.LoadDatabase()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	db, err := database.Connect(dbConfig)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	if err := migrate.Run(context.Background(), newMigrator(db, dbConfig.Driver), args, os.Stdout); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
 }
 
 func main() {
-	db, err := database.Connect()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	// Настройки проверяются при старте: без обязательных значений сервис не запускается
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	log.Printf("Effective config:\n%s", cfg)
+
+	// OpenAPI-документ проверяется при старте: сервис с битым документом не запускается
+	doc, err := apispec.Load()
+	if err != nil {
+		log.Fatalf("Failed to load OpenAPI document: %v", err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		log.Fatalf("Failed to build OpenAPI validator: %v", err)
+	}
+	validator.ValidateResponses = cfg.OpenAPI.ValidateResponses
+
+	db, err := database.Connect(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
+	metrics.RegisterDB("users", db)
+
+	// В окружениях без отдельного шага миграций схема обновляется при старте
+	if cfg.MigrateOnStart {
+		if err := newMigrator(db, cfg.Database.Driver).Up(context.Background()); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+	}
+
+	// Ключи для проверки JWT публикуются auth_service
+	verifier := &jwks.Verifier{
+		Keys:     jwks.NewCache(cfg.JWT.JWKSURL, 10*time.Minute),
+		Issuer:   cfg.JWT.Issuer,
+		Audience: cfg.JWT.Audience,
+	}
+
+	// Подпись запросов между сервисами; исходящие запросы попадают в метрики
+	serviceKeys, err := serviceauth.ParseKeyRing("users-service", cfg.ServiceAuth.Keys, cfg.ServiceAuth.ActiveKeyID)
+	if err != nil {
+		log.Fatalf("Failed to load service keys: %v", err)
+	}
+	serviceClient := serviceauth.NewClient(serviceKeys, metrics.Transport(nil))
 
-	r := mux.NewRouter()
+	avatars, err := storage.NewLocalDisk(cfg.Storage.AvatarDir)
+	if err != nil {
+		log.Fatalf("Failed to initialize avatar storage: %v", err)
+	}
 
-	// Добавляем middleware для логирования
-	r.Use(loggingMiddleware)
+	// Потребители событий аккаунта: USER_EVENT_CONSUMERS="name=url,...",
+	// по умолчанию — posts_service, который удаляет посты, лайки и уведомления пользователя
+	consumers, err := events.ParseConsumers(cfg.EventConsumers)
+	if err != nil {
+		log.Fatalf("Failed to parse USER_EVENT_CONSUMERS: %v", err)
+	}
+	dispatcher := &events.Dispatcher{
+		DB:        db,
+		Consumers: consumers,
+		Client:    serviceClient,
+	}
+	dispatcherTask := server.Go(dispatcher.Run)
 
-	// Endpoints for probes
-	r.HandleFunc("/health", healthHandler).Methods("GET")
-	r.HandleFunc("/ready", readyHandler).Methods("GET")
+	// Выгрузка персональных данных
+	exports, err := storage.NewLocalDisk(cfg.Storage.ExportDir)
+	if err != nil {
+		log.Fatalf("Failed to initialize export storage: %v", err)
+	}
+	exportLinks := &export.LinkSigner{Secret: []byte(cfg.Export.LinkSecret), TTL: 15 * time.Minute}
+	exportWorker := &export.Worker{
+		DB:              db,
+		Store:           exports,
+		PostsServiceURL: cfg.PostsServiceURL,
+		Client:          serviceClient,
+	}
+	exportTask := server.Go(exportWorker.Run)
 
-	// User service endpoints
-	r.HandleFunc("/api/users/register", handlers.RegisterUser(db)).Methods("POST")
-	r.HandleFunc("/api/users/by_email", handlers.GetUserByEmail(db)).Methods("GET")
-	r.HandleFunc("/api/users/by_username", handlers.GetUserByUsername(db)).Methods("GET")
-	r.HandleFunc("/api/users/{id:[0-9]+}", handlers.GetUserByID(db)).Methods("GET")
-	r.HandleFunc("/api/users/{id:[0-9]+}", handlers.UpdateUser(db)).Methods("PATCH")
-	r.HandleFunc("/api/users/{id:[0-9]+}/password", handlers.UpdateUserPassword(db)).Methods("PATCH")
-	r.HandleFunc("/api/users/{id:[0-9]+}", handlers.DeleteUser(db)).Methods("DELETE")
-	r.HandleFunc("/api/users", handlers.ListUsers(db)).Methods("GET")
+	// Readiness Probe: при остановке /ready первым начинает отвечать 503.
+	// Без базы сервис не работает; без posts_service профили отдаются без статистики,
+	// а ключи JWT кэшируются, поэтому эти зависимости видны только в /health/details.
+	ready := &server.Readiness{Checks: health.NewChecker(
+		health.Check{Name: "database", Critical: true, Run: db.PingContext},
+		health.Check{Name: "auth_service", Run: health.HTTP(http.DefaultClient, cfg.JWT.JWKSURL)},
+		health.Check{Name: "posts_service", Run: health.HTTP(http.DefaultClient, cfg.PostsServiceURL+"/health")},
+	)}
+	r := routes{
+		spec:           openapi.Handler(doc),
+		ready:          ready,
+		db:             db,
+		verifier:       verifier,
+		serviceKeys:    serviceKeys,
+		avatars:        avatars,
+		exports:        exports,
+		exportLinks:    exportLinks,
+		eventConsumers: dispatcher.ConsumerNames(),
+		totpIssuer:     cfg.TOTPIssuer,
+		posts:          handlers.PostsService{URL: cfg.PostsServiceURL, Client: serviceClient},
+	}.router()
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8084"
+	// Метрики запросов считаются снаружи, чтобы в них попадали и отклонённые проверкой OpenAPI
+	handler := metrics.Middleware(metrics.MuxRoute(r), apierror.WithRequestID(validator.Middleware(r)))
+	srv := server.New(":"+cfg.Port, handler, cfg.Server)
+	log.Printf("Users Service running on port %s", cfg.Port)
+	// После текущих запросов останавливаются фоновые задачи, которые пишут в базу, и только затем закрывается пул соединений
+	err = server.Run(srv, ready, cfg.Server,
+		server.Hook{Name: "event dispatcher", Stop: dispatcherTask.Stop},
+		server.Hook{Name: "export worker", Stop: exportTask.Stop},
+		server.Hook{Name: "database", Stop: func(context.Context) error { return db.Close() }},
+	)
+	if err != nil {
+		log.Fatalf("Users Service stopped with error: %v", err)
 	}
-	log.Printf("Users Service running on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	log.Println("Users Service stopped")
 }
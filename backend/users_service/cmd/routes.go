@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"blog/pkg/apierror"
+	"blog/pkg/jwks"
+	"blog/pkg/metrics"
+	"blog/pkg/server"
+	"blog/pkg/serviceauth"
+	"users_service/internal/database"
+	"users_service/internal/export"
+	"users_service/internal/handlers"
+	"users_service/internal/middlewares"
+	"users_service/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// routes — зависимости обработчиков сервиса
+type routes struct {
+	// spec отдаёт OpenAPI-документ
+	spec        http.Handler
+	ready       *server.Readiness
+	db          *sql.DB
+	verifier    *jwks.Verifier
+	serviceKeys *serviceauth.KeyRing
+	avatars     storage.Storage
+	exports     storage.Storage
+	exportLinks *export.LinkSigner
+	// eventConsumers — сервисы, которые должны подтвердить удаление аккаунта
+	eventConsumers []string
+	totpIssuer     string
+	posts          handlers.PostsService
+}
+
+// router регистрирует все маршруты сервиса. routes_test.go сверяет их с OpenAPI-документом.
+func (d routes) router() *mux.Router {
+	r := mux.NewRouter()
+
+	// Добавляем middleware для логирования
+	r.Use(loggingMiddleware)
+	// Ошибки маршрутизации тоже в формате problem+json
+	r.NotFoundHandler = apierror.NotFoundHandler()
+	r.MethodNotAllowedHandler = apierror.MethodNotAllowedHandler()
+
+	authenticated := middlewares.AuthMiddleware(d.verifier)
+	fromAuthService := serviceauth.Middleware(d.serviceKeys, "auth-service")
+
+	// Endpoints for probes
+	r.HandleFunc("/health", healthHandler).Methods("GET")
+	r.HandleFunc("/ready", d.ready.Handler()).Methods("GET")
+	r.HandleFunc("/health/details", d.ready.DetailsHandler()).Methods("GET")
+	r.Handle("/openapi.json", d.spec).Methods("GET")
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// User service endpoints
+	r.Handle("/api/users/register", fromAuthService(handlers.RegisterUser(d.db))).Methods("POST")
+	r.Handle("/api/users/by_email", fromAuthService(handlers.GetUserByEmail(d.db))).Methods("GET")
+	r.HandleFunc("/api/users/by_username", handlers.GetUserByUsername(d.db)).Methods("GET")
+	r.HandleFunc("/api/users/search", handlers.SearchUsers(d.db)).Methods("GET")
+	r.HandleFunc("/api/users/{id:[0-9]+}", handlers.GetUserByID(d.db)).Methods("GET")
+	r.Handle("/api/users/{id:[0-9]+}", authenticated(middlewares.RequireOwnerOrAdmin(handlers.UpdateUser(d.db)))).Methods("PATCH")
+	r.Handle("/api/users/{id:[0-9]+}/password", authenticated(middlewares.RequireOwnerOrAdmin(handlers.UpdateUserPassword(d.db)))).Methods("PATCH")
+	r.Handle("/api/users/{id:[0-9]+}", authenticated(middlewares.RequireOwnerOrAdmin(handlers.DeleteUser(d.db, d.avatars, d.eventConsumers)))).Methods("DELETE")
+	r.HandleFunc("/api/users", handlers.ListUsers(d.db)).Methods("GET")
+
+	// Ход удаления аккаунта
+	r.Handle("/api/users/{id:[0-9]+}/deletion", authenticated(middlewares.RequireOwnerOrAdmin(handlers.GetAccountDeletion(d.db)))).Methods("GET")
+	r.Handle("/api/users/{id:[0-9]+}/deletion/retry", authenticated(middlewares.RequireAdmin(handlers.RetryAccountDeletion(d.db)))).Methods("POST")
+
+	// Выгрузка персональных данных
+	r.Handle("/api/users/{id:[0-9]+}/export", authenticated(middlewares.RequireOwner(handlers.StartDataExport(d.db)))).Methods("POST")
+	r.Handle("/api/users/{id:[0-9]+}/export/{exportId:[0-9a-f]{32}}", authenticated(middlewares.RequireOwner(handlers.GetDataExport(d.db, d.exportLinks)))).Methods("GET")
+	r.HandleFunc("/api/users/{id:[0-9]+}/export/{exportId:[0-9a-f]{32}}/download", handlers.DownloadDataExport(d.db, d.exports, d.exportLinks)).Methods("GET")
+
+	// Блокировка, скрытие пользователей и подписки
+	for path, kind := range map[string]string{"blocks": database.RelationBlock, "mutes": database.RelationMute, "following": database.RelationFollow} {
+		r.Handle("/api/users/{id:[0-9]+}/"+path, authenticated(middlewares.RequireOwner(handlers.ListRelations(d.db, kind)))).Methods("GET")
+		r.Handle("/api/users/{id:[0-9]+}/"+path+"/{targetId:[0-9]+}", authenticated(middlewares.RequireOwner(handlers.AddRelation(d.db, kind)))).Methods("PUT")
+		r.Handle("/api/users/{id:[0-9]+}/"+path+"/{targetId:[0-9]+}", authenticated(middlewares.RequireOwner(handlers.RemoveRelation(d.db, kind)))).Methods("DELETE")
+	}
+	r.Handle("/api/users/{id:[0-9]+}/followers", authenticated(middlewares.RequireOwner(handlers.ListFollowers(d.db)))).Methods("GET")
+
+	// Профили пользователей
+	r.Handle("/api/users/{id:[0-9]+}/profile", authenticated(middlewares.RequireOwnerOrAdmin(handlers.GetProfile(d.db)))).Methods("GET")
+	r.Handle("/api/users/{id:[0-9]+}/profile", authenticated(middlewares.RequireOwnerOrAdmin(handlers.UpdateProfile(d.db)))).Methods("PUT")
+	r.HandleFunc("/api/profiles/{username}", handlers.GetPublicProfile(d.db, d.posts)).Methods("GET")
+
+	// Аватары
+	r.Handle("/api/users/{id:[0-9]+}/avatar", authenticated(middlewares.RequireOwnerOrAdmin(handlers.UploadAvatar(d.db, d.avatars)))).Methods("PUT")
+	r.HandleFunc("/api/users/{id:[0-9]+}/avatar", handlers.GetAvatar(d.db, d.avatars)).Methods("GET")
+
+	// Внутренние эндпоинты для других сервисов (только подписанные запросы)
+	internal := r.PathPrefix("/internal").Subrouter()
+	internal.Use(fromAuthService)
+	internal.HandleFunc("/credentials/verify", handlers.VerifyCredentials(d.db)).Methods("POST")
+	internal.HandleFunc("/users/{id:[0-9]+}/totp/verify", handlers.VerifyTOTP(d.db)).Methods("POST")
+
+	// Двухфакторная аутентификация
+	r.Handle("/api/users/{id:[0-9]+}/totp/enroll", authenticated(middlewares.RequireOwner(handlers.EnrollTOTP(d.db, d.totpIssuer)))).Methods("POST")
+	r.Handle("/api/users/{id:[0-9]+}/totp/confirm", authenticated(middlewares.RequireOwner(handlers.ConfirmTOTP(d.db)))).Methods("POST")
+
+	// Персональные токены доступа
+	r.Handle("/api/users/{id:[0-9]+}/tokens", authenticated(middlewares.RequireOwner(handlers.CreatePersonalAccessToken(d.db)))).Methods("POST")
+	r.Handle("/api/users/{id:[0-9]+}/tokens", authenticated(middlewares.RequireOwner(handlers.ListPersonalAccessTokens(d.db)))).Methods("GET")
+	r.Handle("/api/users/{id:[0-9]+}/tokens/{tokenId:[0-9]+}", authenticated(middlewares.RequireOwner(handlers.RevokePersonalAccessToken(d.db)))).Methods("DELETE")
+	return r
+}
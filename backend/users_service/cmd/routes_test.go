@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"blog/pkg/openapi"
+	"blog/pkg/server"
+	"users_service/internal/apispec"
+)
+
+// TestRoutesDocumented проверяет, что каждый маршрут сервиса описан в OpenAPI-документе:
+// маршрут без описания валидатор пропускает без проверки
+func TestRoutesDocumented(t *testing.T) {
+	doc, err := apispec.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := routes{spec: openapi.Handler(doc), ready: &server.Readiness{}}.router()
+
+	missing, err := openapi.Undocumented(doc, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) > 0 {
+		t.Errorf("маршруты не описаны в OpenAPI-документе: %s", strings.Join(missing, ", "))
+	}
+}
@@ -0,0 +1,175 @@
+package apispec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blog/pkg/apierror"
+	"blog/pkg/openapi"
+)
+
+func newTestValidator(t *testing.T, validateResponses bool) *openapi.Validator {
+	t.Helper()
+	doc, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.ValidateResponses = validateResponses
+	return v
+}
+
+func jsonHandler(status int, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name              string
+		validateResponses bool
+		method            string
+		path              string
+		contentType       string
+		body              string
+		handler           http.Handler
+		expectedStatus    int
+		expectedCode      string
+	}{
+		{
+			name:           "Корректный запрос и ответ",
+			method:         http.MethodPost,
+			path:           "/api/users/register",
+			contentType:    "application/json",
+			body:           `{"username":"alice","email":"alice@example.com","password":"secret123"}`,
+			handler:        jsonHandler(http.StatusCreated, `{"message":"User registered successfully"}`),
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Поле неверного типа",
+			method:         http.MethodPost,
+			path:           "/api/users/register",
+			contentType:    "application/json",
+			body:           `{"username":42}`,
+			handler:        jsonHandler(http.StatusCreated, `{"message":"ok"}`),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   apierror.CodeValidationFailed,
+		},
+		{
+			name:           "Неподдерживаемый Content-Type",
+			method:         http.MethodPost,
+			path:           "/api/users/register",
+			contentType:    "text/plain",
+			body:           `hello`,
+			handler:        jsonHandler(http.StatusCreated, `{"message":"ok"}`),
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   apierror.CodeUnsupportedMediaType,
+		},
+		{
+			name:           "Неверный параметр пути",
+			method:         http.MethodGet,
+			path:           "/api/users/1/avatar?size=100",
+			handler:        jsonHandler(http.StatusOK, `{}`),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   apierror.CodeValidationFailed,
+		},
+		{
+			name:              "Ответ расходится с документом",
+			validateResponses: true,
+			method:            http.MethodGet,
+			path:              "/api/users/1",
+			handler:           jsonHandler(http.StatusOK, `{"id":"1","username":"alice"}`),
+			expectedStatus:    http.StatusInternalServerError,
+			expectedCode:      apierror.CodeInternal,
+		},
+		{
+			name:           "Ответ без проверки не меняется",
+			method:         http.MethodGet,
+			path:           "/api/users/1",
+			handler:        jsonHandler(http.StatusOK, `{"id":"1","username":"alice"}`),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "Состояние зависимостей соответствует документу",
+			validateResponses: true,
+			method:            http.MethodGet,
+			path:              "/health/details",
+			handler:           jsonHandler(http.StatusServiceUnavailable, `{"status":"down","checks":{"database":{"status":"down","critical":true,"latency_ms":2000,"checked_at":"2026-10-19T10:00:00Z","last_error":"context deadline exceeded","last_error_at":"2026-10-19T10:00:00Z"},"posts_service":{"status":"unknown","critical":false,"latency_ms":0}}}`),
+			expectedStatus:    http.StatusServiceUnavailable,
+		},
+		{
+			name:              "Маршрут не описан в документе",
+			validateResponses: true,
+			method:            http.MethodGet,
+			path:              "/api/undocumented",
+			handler:           jsonHandler(http.StatusOK, `{}`),
+			expectedStatus:    http.StatusInternalServerError,
+			expectedCode:      apierror.CodeInternal,
+		},
+		{
+			name:              "Неизвестный маршрут отвечает 404",
+			validateResponses: true,
+			method:            http.MethodGet,
+			path:              "/api/undocumented",
+			handler:           apierror.NotFoundHandler(),
+			expectedStatus:    http.StatusNotFound,
+			expectedCode:      apierror.CodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestValidator(t, tt.validateResponses).Middleware(tt.handler)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode == "" {
+				return
+			}
+			var p apierror.Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Code != tt.expectedCode {
+				t.Errorf("ожидался код ошибки %q, получен %q", tt.expectedCode, p.Code)
+			}
+		})
+	}
+}
+
+func TestHandlerServesDocument(t *testing.T) {
+	doc, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	openapi.Handler(doc).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var body struct {
+		OpenAPI string                 `json:"openapi"`
+		Paths   map[string]interface{} `json:"paths"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.OpenAPI == "" || body.Paths["/api/users/{id}"] == nil {
+		t.Errorf("ожидался OpenAPI-документ с путями сервиса, получено %+v", body)
+	}
+}
@@ -0,0 +1,110 @@
+// Package avatar проверяет загруженные изображения и готовит квадратные миниатюры
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// Регистрация поддерживаемых форматов для image.Decode
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Sizes — стороны генерируемых миниатюр в пикселях
+var Sizes = []int{64, 128, 256}
+
+// DefaultSize используется, если размер не указан в запросе
+const DefaultSize = 128
+
+const (
+	// MaxUploadBytes — максимальный размер загружаемого файла
+	MaxUploadBytes = 5 << 20
+	// maxPixels защищает от «бомб» с огромными размерами при маленьком файле
+	maxPixels   = 40_000_000
+	jpegQuality = 85
+)
+
+var (
+	// ErrUnsupportedFormat — формат изображения не PNG, JPEG или WebP
+	ErrUnsupportedFormat = errors.New("unsupported image format, use PNG, JPEG or WebP")
+	// ErrTooLarge — изображение превышает допустимые размеры
+	ErrTooLarge = errors.New("image is too large")
+)
+
+var allowedFormats = map[string]bool{"png": true, "jpeg": true, "webp": true}
+
+// IsValidSize проверяет, что размер входит в список генерируемых миниатюр
+func IsValidSize(size int) bool {
+	for _, s := range Sizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// Process декодирует изображение и возвращает JPEG-миниатюры для каждого размера из Sizes.
+// Изображение полностью перекодируется, поэтому EXIF и прочие метаданные исходного файла
+// в результат не попадают.
+func Process(data []byte) (map[int][]byte, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !allowedFormats[format] {
+		return nil, ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+
+	square := cropSquare(src)
+
+	thumbs := make(map[int][]byte, len(Sizes))
+	for _, size := range Sizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		// Прозрачные области заливаются белым, так как JPEG не поддерживает альфа-канал
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, square, draw.Over, nil)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+		}
+		thumbs[size] = buf.Bytes()
+	}
+	return thumbs, nil
+}
+
+// ReadLimited читает не более MaxUploadBytes байт
+func ReadLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxUploadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxUploadBytes {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// cropSquare возвращает центральный квадрат изображения
+func cropSquare(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	return image.Rect(x0, y0, x0+side, y0+side)
+}
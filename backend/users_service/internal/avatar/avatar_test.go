@@ -0,0 +1,58 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessProducesSquareThumbnails(t *testing.T) {
+	thumbs, err := Process(encodePNG(t, 300, 200))
+	if err != nil {
+		t.Fatalf("Process вернул ошибку: %v", err)
+	}
+
+	for _, size := range Sizes {
+		data, ok := thumbs[size]
+		if !ok {
+			t.Fatalf("нет миниатюры размера %d", size)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("миниатюра %d не является JPEG: %v", size, err)
+		}
+		if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+			t.Errorf("миниатюра %d имеет размер %dx%d", size, b.Dx(), b.Dy())
+		}
+		// Перекодированный JPEG не содержит сегмента APP1 (EXIF)
+		if bytes.Contains(data, []byte("Exif\x00\x00")) {
+			t.Errorf("миниатюра %d содержит EXIF", size)
+		}
+	}
+}
+
+func TestProcessRejectsUnsupportedFormat(t *testing.T) {
+	_, err := Process([]byte("GIF89a not really an image"))
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("ожидалась ErrUnsupportedFormat, получено %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SetAvatarUpdatedAt отмечает время загрузки нового аватара (используется как версия для URL и кэша)
+func SetAvatarUpdatedAt(db *sql.DB, userID int, updatedAt time.Time) error {
+	_, err := db.Exec("UPDATE users SET avatar_updated_at = $1 WHERE id = $2", updatedAt.UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update avatar timestamp: %w", err)
+	}
+	return nil
+}
+
+// GetAvatarUpdatedAt возвращает время загрузки аватара.
+// found=false означает, что пользователь не найден; nil-время — что аватара нет.
+func GetAvatarUpdatedAt(db *sql.DB, userID int) (updatedAt *time.Time, found bool, err error) {
+	err = db.QueryRow("SELECT avatar_updated_at FROM users WHERE id = $1", userID).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch avatar timestamp: %w", err)
+	}
+	return updatedAt, true, nil
+}
@@ -3,19 +3,26 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"os"
+
+	"users_service/internal/config"
 
 	_ "github.com/lib/pq"
 )
 
-// Connect подключается к базе данных и возвращает соединение
-func Connect() (*sql.DB, error) {
-	host := os.Getenv("POSTGRES_HOST")
-	user := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	dbname := os.Getenv("POSTGRES_DB")
+// Connect подключается к базе данных, выбранной в настройках, и возвращает соединение
+func Connect(cfg config.Database) (*sql.DB, error) {
+	switch cfg.Driver {
+	case DriverPostgres:
+		return connectPostgres(cfg)
+	case DriverSQLite:
+		return connectSQLite(cfg.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q (expected %s or %s)", cfg.Driver, DriverPostgres, DriverSQLite)
+	}
+}
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=disable", host, user, password, dbname)
+func connectPostgres(cfg config.Database) (*sql.DB, error) {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=disable", cfg.Host, cfg.User, cfg.Password, cfg.Name)
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, err
@@ -31,14 +38,20 @@ type User struct {
 	ID           int    `json:"id"`
 	Username     string `json:"username"`
 	Email        string `json:"email"`
-	PasswordHash string `json:"password_hash"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"`
+	TOTPEnabled  bool   `json:"totp_enabled"`
 }
 
 // GetUserByEmail выполняет запрос к базе данных для получения пользователя по email
 func GetUserByEmail(db *sql.DB, email string) (*User, error) {
 	var user User
-	err := db.QueryRow("SELECT id, username, email, password_hash FROM users WHERE email = $1", email).
-		Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash)
+	err := db.QueryRow(`
+		SELECT users.id, users.username, users.email, users.password_hash, users.role, COALESCE(user_totp.enabled, FALSE)
+		FROM users
+		LEFT JOIN user_totp ON user_totp.user_id = users.id
+		WHERE users.email = $1
+	`, email).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.TOTPEnabled)
 
 	if err == sql.ErrNoRows {
 		return nil, nil // Пользователь не найден
@@ -48,6 +61,24 @@ func GetUserByEmail(db *sql.DB, email string) (*User, error) {
 	return &user, nil // Пользователь найден
 }
 
+// GetUserByID выполняет запрос к базе данных для получения пользователя по ID
+func GetUserByID(db *sql.DB, id int) (*User, error) {
+	var user User
+	err := db.QueryRow(`
+		SELECT users.id, users.username, users.email, users.password_hash, users.role, COALESCE(user_totp.enabled, FALSE)
+		FROM users
+		LEFT JOIN user_totp ON user_totp.user_id = users.id
+		WHERE users.id = $1 AND users.deleted_at IS NULL
+	`, id).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.TOTPEnabled)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // Пользователь не найден
+	} else if err != nil {
+		return nil, err // Ошибка базы данных
+	}
+	return &user, nil
+}
+
 // SaveUser сохраняет нового пользователя в базе данных
 func SaveUser(db *sql.DB, username, email, passwordHash string) error {
 	_, err := db.Exec(`
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"users_service/internal/config"
+
+	"modernc.org/sqlite"
+)
+
+// Драйверы базы данных (переменная DB_DRIVER)
+const (
+	DriverPostgres = config.DriverPostgres
+	DriverSQLite   = config.DriverSQLite
+)
+
+// sqliteNow — текущее время UTC в том же текстовом виде, в котором драйвер SQLite сохраняет time.Time.
+// Время в SQLite хранится строкой, поэтому оно сравнивается лексикографически и должно быть в UTC.
+const sqliteNow = "strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')"
+
+// OpenSQLite открывает файл базы SQLite с внешними ключами и журналом WAL.
+// Транзакции сразу берут блокировку записи (_txlock=immediate) и ждут друг друга до busy_timeout:
+// так они выполняются по одной и заменяют SELECT ... FOR UPDATE.
+func OpenSQLite(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+	// time.Time сохраняется как "2006-01-02 15:04:05.999999999-07:00", а не в формате String()
+	params.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	return db, nil
+}
+
+func connectSQLite(path string) (*sql.DB, error) {
+	db, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// isSQLite сообщает, открыто ли соединение драйвером SQLite
+func isSQLite(db *sql.DB) bool {
+	_, ok := db.Driver().(*sqlite.Driver)
+	return ok
+}
+
+// sqliteReplacer переводит конструкции Postgres, у которых в SQLite есть прямая замена.
+// Блокировки строк не нужны: транзакции SQLite выполняются по одной (см. OpenSQLite).
+var sqliteReplacer = strings.NewReplacer(
+	"now()", sqliteNow,
+	"NOW()", sqliteNow,
+	" FOR UPDATE SKIP LOCKED", "",
+	" FOR UPDATE", "",
+)
+
+// portable возвращает запрос в диалекте соединения. Запросы пишутся для Postgres;
+// плейсхолдеры $1, $2 и ON CONFLICT ... DO SQLite понимает сам.
+func portable(db *sql.DB, query string) string {
+	if !isSQLite(db) {
+		return query
+	}
+	return sqliteReplacer.Replace(query)
+}
+
+// utc приводит записываемое время к UTC: SQLite сравнивает время как строки,
+// поэтому все значения должны быть в одном часовом поясе. Для timestamptz в Postgres это ничего не меняет.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// uniqueConstraintFields сопоставляет уникальные ограничения таблицы users с полями API
+var uniqueConstraintFields = map[string]string{
+	"users_username_key": "username",
+	"users_email_key":    "email",
+}
+
+// uniqueColumnFields — то же для SQLite: в ошибке указывается не ограничение, а колонка
+var uniqueColumnFields = map[string]string{
+	"users.username": "username",
+	"users.email":    "email",
+}
+
+// UniqueViolationField возвращает поле, нарушившее ограничение уникальности, если ошибка такова
+func UniqueViolationField(err error) (string, bool) {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteUniqueViolationField(sqliteErr)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return "", false
+	}
+	field, ok := uniqueConstraintFields[pqErr.Constraint]
+	if !ok {
+		return "", true
+	}
+	return field, true
+}
+
+// sqliteUniqueViolationField разбирает ошибку вида "UNIQUE constraint failed: users.username"
+func sqliteUniqueViolationField(err *sqlite.Error) (string, bool) {
+	if err.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE && err.Code() != sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return "", false
+	}
+	const marker = "constraint failed: "
+	msg := err.Error()
+	i := strings.LastIndex(msg, marker)
+	if i < 0 {
+		return "", true
+	}
+	columns, _, _ := strings.Cut(msg[i+len(marker):], " (")
+	return uniqueColumnFields[columns], true
+}
@@ -0,0 +1,286 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EventUserDeleted публикуется при удалении аккаунта
+const EventUserDeleted = "user.deleted"
+
+// Статусы доставки события потребителю
+const (
+	DeliveryPending = "pending"
+	DeliveryDone    = "done"
+	DeliveryFailed  = "failed"
+)
+
+// ErrDeletionInProgress возвращается, если аккаунт уже удаляется
+var ErrDeletionInProgress = errors.New("account deletion is already in progress")
+
+// Event — событие из таблицы event_outbox
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	UserID    int             `json:"user_id"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"occurred_at"`
+}
+
+// Delivery — состояние доставки события одному потребителю
+type Delivery struct {
+	EventID       int64      `json:"-"`
+	Consumer      string     `json:"consumer"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     *string    `json:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+// DueDelivery — доставка, взятая в работу диспетчером, вместе с событием
+type DueDelivery struct {
+	Event    Event
+	Consumer string
+	Attempts int
+}
+
+// StartAccountDeletion обезличивает аккаунт, удаляет данные users_service и в той же транзакции
+// записывает событие user.deleted с доставкой каждому потребителю.
+// found=false означает, что пользователь не найден.
+func StartAccountDeletion(db *sql.DB, userID int, consumers []string) (eventID int64, found bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt *time.Time
+	err = tx.QueryRow(portable(db, "SELECT deleted_at FROM users WHERE id = $1 FOR UPDATE"), userID).Scan(&deletedAt)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fmt.Errorf("failed to lock user: %w", err)
+	}
+	if deletedAt != nil {
+		return 0, true, ErrDeletionInProgress
+	}
+
+	// Дефис недопустим в именах пользователей, поэтому обезличенное имя не пересечётся с настоящими
+	_, err = tx.Exec(portable(db, `
+		UPDATE users
+		SET username = 'deleted-' || id, email = 'deleted-' || id || '@deleted.invalid',
+		    password_hash = '', avatar_updated_at = NULL, deleted_at = now()
+		WHERE id = $1
+	`), userID)
+	if err != nil {
+		return 0, true, fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	for _, table := range []string{"users_information", "user_recovery_codes", "user_totp", "personal_access_tokens", "user_relations"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE user_id = $1", userID); err != nil {
+			return 0, true, fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+
+	// Готовые архивы истекают сразу (файлы удалит фоновая очистка), незавершённые выгрузки отменяются
+	_, err = tx.Exec(portable(db, `
+		UPDATE data_exports
+		SET expires_at = now(),
+		    status = CASE WHEN status = 'ready' THEN 'ready' ELSE 'failed' END,
+		    error = CASE WHEN status = 'ready' THEN error ELSE 'account deleted' END
+		WHERE user_id = $1 AND (status <> 'ready' OR expires_at > now())
+	`), userID)
+	if err != nil {
+		return 0, true, fmt.Errorf("failed to expire data exports: %w", err)
+	}
+
+	payload, _ := json.Marshal(map[string]int{"user_id": userID})
+	err = tx.QueryRow(`
+		INSERT INTO event_outbox (event_type, user_id, payload)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, EventUserDeleted, userID, payload).Scan(&eventID)
+	if err != nil {
+		return 0, true, fmt.Errorf("failed to write event: %w", err)
+	}
+
+	for _, consumer := range consumers {
+		if _, err := tx.Exec("INSERT INTO event_deliveries (event_id, consumer) VALUES ($1, $2)", eventID, consumer); err != nil {
+			return 0, true, fmt.Errorf("failed to schedule delivery: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, true, fmt.Errorf("failed to commit account deletion: %w", err)
+	}
+	return eventID, true, nil
+}
+
+// FinalizeAccountDeletion удаляет строку пользователя, когда все потребители обработали событие.
+// Возвращает true, если аккаунт удалён окончательно.
+func FinalizeAccountDeletion(db *sql.DB, eventID int64) (bool, error) {
+	res, err := db.Exec(`
+		DELETE FROM users
+		WHERE id = (SELECT user_id FROM event_outbox WHERE id = $1 AND event_type = $2)
+		  AND deleted_at IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM event_deliveries WHERE event_id = $1 AND status <> 'done')
+	`, eventID, EventUserDeleted)
+	if err != nil {
+		return false, fmt.Errorf("failed to finalize account deletion: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ClaimDueDeliveries берёт в работу доставки, время которых подошло.
+// Время следующей попытки сдвигается на lease, чтобы другие экземпляры сервиса не взяли их повторно.
+func ClaimDueDeliveries(db *sql.DB, limit int, lease time.Duration) ([]DueDelivery, error) {
+	if isSQLite(db) {
+		return claimDueDeliveriesSQLite(db, limit, lease)
+	}
+
+	rows, err := db.Query(`
+		UPDATE event_deliveries d
+		SET next_attempt_at = now() + $2 * interval '1 second'
+		FROM event_outbox e
+		WHERE e.id = d.event_id
+		  AND (d.event_id, d.consumer) IN (
+		      SELECT event_id, consumer FROM event_deliveries
+		      WHERE status = 'pending' AND next_attempt_at <= now()
+		      ORDER BY next_attempt_at
+		      LIMIT $1
+		      FOR UPDATE SKIP LOCKED
+		  )
+		RETURNING e.id, e.event_type, e.user_id, e.payload, e.created_at, d.consumer, d.attempts
+	`, limit, int(lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	return scanDueDeliveries(rows)
+}
+
+// claimDueDeliveriesSQLite — ClaimDueDeliveries для SQLite: в RETURNING нельзя ссылаться на таблицы
+// из FROM, поэтому поля события выбираются подзапросами. SKIP LOCKED не нужен: запрос выполняется
+// в одной пишущей транзакции.
+func claimDueDeliveriesSQLite(db *sql.DB, limit int, lease time.Duration) ([]DueDelivery, error) {
+	rows, err := db.Query(`
+		UPDATE event_deliveries
+		SET next_attempt_at = $2
+		WHERE (event_id, consumer) IN (
+		      SELECT event_id, consumer FROM event_deliveries
+		      WHERE status = 'pending' AND next_attempt_at <= `+sqliteNow+`
+		      ORDER BY next_attempt_at
+		      LIMIT $1
+		  )
+		RETURNING event_id,
+		          (SELECT event_type FROM event_outbox WHERE id = event_id),
+		          (SELECT user_id FROM event_outbox WHERE id = event_id),
+		          (SELECT payload FROM event_outbox WHERE id = event_id),
+		          (SELECT created_at FROM event_outbox WHERE id = event_id),
+		          consumer, attempts
+	`, limit, time.Now().Add(lease).UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	return scanDueDeliveries(rows)
+}
+
+func scanDueDeliveries(rows *sql.Rows) ([]DueDelivery, error) {
+	defer rows.Close()
+
+	var due []DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		if err := rows.Scan(&d.Event.ID, &d.Event.Type, &d.Event.UserID, &d.Event.Payload, &d.Event.CreatedAt, &d.Consumer, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// MarkDeliveryDone отмечает успешную доставку
+func MarkDeliveryDone(db *sql.DB, eventID int64, consumer string) error {
+	_, err := db.Exec(portable(db, `
+		UPDATE event_deliveries
+		SET status = 'done', attempts = attempts + 1, last_error = NULL, completed_at = now()
+		WHERE event_id = $1 AND consumer = $2
+	`), eventID, consumer)
+	if err != nil {
+		return fmt.Errorf("failed to mark delivery done: %w", err)
+	}
+	return nil
+}
+
+// MarkDeliveryFailed сохраняет ошибку и планирует повтор; при final=true доставка прекращается
+func MarkDeliveryFailed(db *sql.DB, eventID int64, consumer, lastError string, nextAttemptAt time.Time, final bool) error {
+	status := DeliveryPending
+	if final {
+		status = DeliveryFailed
+	}
+	_, err := db.Exec(`
+		UPDATE event_deliveries
+		SET status = $3, attempts = attempts + 1, last_error = $4, next_attempt_at = $5
+		WHERE event_id = $1 AND consumer = $2
+	`, eventID, consumer, status, lastError, nextAttemptAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to mark delivery failed: %w", err)
+	}
+	return nil
+}
+
+// GetAccountDeletion возвращает последнее событие удаления аккаунта и состояние его доставок.
+// Если аккаунт не удалялся, возвращается nil.
+func GetAccountDeletion(db *sql.DB, userID int) (*Event, []Delivery, error) {
+	var e Event
+	err := db.QueryRow(`
+		SELECT id, event_type, user_id, payload, created_at
+		FROM event_outbox
+		WHERE user_id = $1 AND event_type = $2
+		ORDER BY id DESC
+		LIMIT 1
+	`, userID, EventUserDeleted).Scan(&e.ID, &e.Type, &e.UserID, &e.Payload, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch deletion event: %w", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT event_id, consumer, status, attempts, last_error, next_attempt_at, completed_at
+		FROM event_deliveries
+		WHERE event_id = $1
+		ORDER BY consumer
+	`, e.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.EventID, &d.Consumer, &d.Status, &d.Attempts, &d.LastError, &d.NextAttemptAt, &d.CompletedAt); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return &e, deliveries, rows.Err()
+}
+
+// RetryFailedDeliveries возвращает в очередь доставки, исчерпавшие попытки
+func RetryFailedDeliveries(db *sql.DB, eventID int64) (int64, error) {
+	res, err := db.Exec(portable(db, `
+		UPDATE event_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE event_id = $1 AND status = 'failed'
+	`), eventID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reschedule deliveries: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Статусы выгрузки персональных данных
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// ErrExportInProgress возвращается, если у пользователя уже есть незавершённая выгрузка
+var ErrExportInProgress = errors.New("data export is already in progress")
+
+// ErrExportNotRunning возвращается, если выгрузка уже не выполняется: её удалили вместе
+// с аккаунтом или она завершилась иначе, пока собирался архив
+var ErrExportNotRunning = errors.New("data export is not running")
+
+// DataExport — задача выгрузки персональных данных
+type DataExport struct {
+	ID          string     `json:"id"`
+	UserID      int        `json:"user_id"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error,omitempty"`
+	StorageKey  *string    `json:"-"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+const dataExportColumns = "id, user_id, status, error, storage_key, size_bytes, created_at, completed_at, expires_at"
+
+func scanDataExport(row interface{ Scan(...interface{}) error }) (*DataExport, error) {
+	var e DataExport
+	err := row.Scan(&e.ID, &e.UserID, &e.Status, &e.Error, &e.StorageKey, &e.SizeBytes, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// CreateDataExport ставит выгрузку в очередь, если у пользователя нет незавершённой
+func CreateDataExport(db *sql.DB, userID int, id string) (*DataExport, error) {
+	row := db.QueryRow(`
+		INSERT INTO data_exports (id, user_id)
+		SELECT $1, $2
+		WHERE NOT EXISTS (
+			SELECT 1 FROM data_exports WHERE user_id = $2 AND status IN ('pending', 'running')
+		)
+		RETURNING `+dataExportColumns, id, userID)
+	export, err := scanDataExport(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrExportInProgress
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to create data export: %w", err)
+	}
+	return export, nil
+}
+
+// GetDataExport возвращает выгрузку пользователя или nil, если она не найдена
+func GetDataExport(db *sql.DB, userID int, id string) (*DataExport, error) {
+	row := db.QueryRow("SELECT "+dataExportColumns+" FROM data_exports WHERE id = $1 AND user_id = $2", id, userID)
+	export, err := scanDataExport(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch data export: %w", err)
+	}
+	return export, nil
+}
+
+// ClaimDataExport берёт в работу самую старую ожидающую выгрузку.
+// Выгрузки, зависшие в статусе running дольше staleAfter (например, после падения сервиса), берутся повторно.
+func ClaimDataExport(db *sql.DB, staleAfter time.Duration) (*DataExport, error) {
+	if isSQLite(db) {
+		return claimDataExportSQLite(db, staleAfter)
+	}
+
+	row := db.QueryRow(`
+		UPDATE data_exports
+		SET status = 'running', started_at = now()
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = 'pending'
+			   OR (status = 'running' AND started_at < now() - $1 * interval '1 second')
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+dataExportColumns, int(staleAfter.Seconds()))
+	return claimedDataExport(row)
+}
+
+// claimDataExportSQLite — ClaimDataExport для SQLite: вместо интервала граница зависших выгрузок
+// вычисляется заранее, а SKIP LOCKED не нужен, потому что запрос выполняется в одной пишущей транзакции
+func claimDataExportSQLite(db *sql.DB, staleAfter time.Duration) (*DataExport, error) {
+	row := db.QueryRow(`
+		UPDATE data_exports
+		SET status = 'running', started_at = `+sqliteNow+`
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = 'pending'
+			   OR (status = 'running' AND started_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+		)
+		RETURNING `+dataExportColumns, time.Now().Add(-staleAfter).UTC())
+	return claimedDataExport(row)
+}
+
+func claimedDataExport(row *sql.Row) (*DataExport, error) {
+	export, err := scanDataExport(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to claim data export: %w", err)
+	}
+	return export, nil
+}
+
+// CompleteDataExport отмечает, что архив готов и доступен до expiresAt.
+// Если выгрузка уже не в статусе running, возвращается ErrExportNotRunning.
+func CompleteDataExport(db *sql.DB, id, storageKey string, size int64, expiresAt time.Time) error {
+	result, err := db.Exec(portable(db, `
+		UPDATE data_exports
+		SET status = 'ready', storage_key = $2, size_bytes = $3, completed_at = now(), expires_at = $4, error = NULL
+		WHERE id = $1 AND status = 'running'
+	`), id, storageKey, size, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+	if affected == 0 {
+		return ErrExportNotRunning
+	}
+	return nil
+}
+
+// ReleaseDataExport возвращает прерванную выгрузку в очередь
+func ReleaseDataExport(db *sql.DB, id string) error {
+	_, err := db.Exec("UPDATE data_exports SET status = 'pending', started_at = NULL WHERE id = $1 AND status = 'running'", id)
+	if err != nil {
+		return fmt.Errorf("failed to release data export: %w", err)
+	}
+	return nil
+}
+
+// FailDataExport сохраняет причину ошибки выгрузки; запись хранится до expiresAt
+func FailDataExport(db *sql.DB, id, message string, expiresAt time.Time) error {
+	_, err := db.Exec(portable(db, "UPDATE data_exports SET status = 'failed', error = $2, completed_at = now(), expires_at = $3 WHERE id = $1"), id, message, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to mark data export failed: %w", err)
+	}
+	return nil
+}
+
+// ExpiredDataExports возвращает готовые и неудавшиеся выгрузки с истёкшим сроком хранения
+func ExpiredDataExports(db *sql.DB, limit int) ([]DataExport, error) {
+	rows, err := db.Query(portable(db, "SELECT "+dataExportColumns+" FROM data_exports WHERE status IN ('ready', 'failed') AND expires_at < now() ORDER BY expires_at LIMIT $1"), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch expired data exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data export: %w", err)
+		}
+		exports = append(exports, *export)
+	}
+	return exports, rows.Err()
+}
+
+// DeleteDataExport удаляет запись о выгрузке
+func DeleteDataExport(db *sql.DB, id string) error {
+	if _, err := db.Exec("DELETE FROM data_exports WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete data export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Profile представляет расширенную информацию о пользователе (таблица users_information)
+type Profile struct {
+	UserID     int     `json:"user_id"`
+	FirstName  string  `json:"first_name"`
+	SecondName string  `json:"second_name"`
+	Birthdate  *string `json:"birthdate"`
+	Bio        string  `json:"bio"`
+	Website    string  `json:"website"`
+	Location   string  `json:"location"`
+}
+
+// GetProfile возвращает профиль пользователя или nil, если профиль ещё не заполнен
+func GetProfile(db *sql.DB, userID int) (*Profile, error) {
+	var (
+		p         Profile
+		birthdate sql.NullTime
+	)
+	err := db.QueryRow(`
+		SELECT user_id, COALESCE(first_name, ''), COALESCE(second_name, ''), birthdate,
+		       COALESCE(bio, ''), COALESCE(website, ''), COALESCE(location, '')
+		FROM users_information
+		WHERE user_id = $1
+	`, userID).Scan(&p.UserID, &p.FirstName, &p.SecondName, &birthdate, &p.Bio, &p.Website, &p.Location)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch profile: %w", err)
+	}
+
+	if birthdate.Valid {
+		s := birthdate.Time.Format("2006-01-02")
+		p.Birthdate = &s
+	}
+	return &p, nil
+}
+
+// SaveProfile создаёт или обновляет профиль пользователя
+func SaveProfile(db *sql.DB, p *Profile) error {
+	_, err := db.Exec(`
+		INSERT INTO users_information (user_id, first_name, second_name, birthdate, bio, website, location)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE
+		SET first_name = EXCLUDED.first_name,
+		    second_name = EXCLUDED.second_name,
+		    birthdate = EXCLUDED.birthdate,
+		    bio = EXCLUDED.bio,
+		    website = EXCLUDED.website,
+		    location = EXCLUDED.location
+	`, p.UserID, p.FirstName, p.SecondName, p.Birthdate, p.Bio, p.Website, p.Location)
+	if err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Виды отношений между пользователями
+const (
+	RelationBlock  = "block"
+	RelationMute   = "mute"
+	RelationFollow = "follow"
+)
+
+// RelatedUser — пользователь из списка заблокированных, скрытых, подписок или подписчиков
+type RelatedUser struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddRelation создаёт отношение; повторный вызов ничего не меняет.
+// Блокировка в той же транзакции отменяет подписки пользователей друг на друга.
+func AddRelation(db *sql.DB, userID, targetID int, kind string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO user_relations (user_id, target_id, kind)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, target_id, kind) DO NOTHING
+	`, userID, targetID, kind)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", kind, err)
+	}
+
+	if kind == RelationBlock {
+		_, err = tx.Exec(`
+			DELETE FROM user_relations
+			WHERE kind = 'follow'
+			  AND ((user_id = $1 AND target_id = $2) OR (user_id = $2 AND target_id = $1))
+		`, userID, targetID)
+		if err != nil {
+			return fmt.Errorf("failed to remove follows: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// HasBlock сообщает, заблокировал ли кто-либо из двух пользователей другого
+func HasBlock(db *sql.DB, userID, targetID int) (bool, error) {
+	var blocked bool
+	err := db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM user_relations
+			WHERE kind = 'block'
+			  AND ((user_id = $1 AND target_id = $2) OR (user_id = $2 AND target_id = $1))
+		)
+	`, userID, targetID).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+	return blocked, nil
+}
+
+// RemoveRelation удаляет отношение и сообщает, существовало ли оно
+func RemoveRelation(db *sql.DB, userID, targetID int, kind string) (bool, error) {
+	res, err := db.Exec("DELETE FROM user_relations WHERE user_id = $1 AND target_id = $2 AND kind = $3", userID, targetID, kind)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove %s: %w", kind, err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListRelations возвращает пользователей, к которым у userID есть отношение kind, начиная с новых
+func ListRelations(db *sql.DB, userID int, kind string) ([]RelatedUser, error) {
+	rows, err := db.Query(`
+		SELECT u.id, u.username, r.created_at
+		FROM user_relations r
+		JOIN users u ON u.id = r.target_id
+		WHERE r.user_id = $1 AND r.kind = $2 AND u.deleted_at IS NULL
+		ORDER BY r.created_at DESC
+	`, userID, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s relations: %w", kind, err)
+	}
+	defer rows.Close()
+
+	users := []RelatedUser{}
+	for rows.Next() {
+		var u RelatedUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan relation: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// ListRelatedBy возвращает пользователей, у которых есть отношение kind к targetID (например, подписчиков)
+func ListRelatedBy(db *sql.DB, targetID int, kind string) ([]RelatedUser, error) {
+	rows, err := db.Query(`
+		SELECT u.id, u.username, r.created_at
+		FROM user_relations r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.target_id = $1 AND r.kind = $2 AND u.deleted_at IS NULL
+		ORDER BY r.created_at DESC
+	`, targetID, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s relations: %w", kind, err)
+	}
+	defer rows.Close()
+
+	users := []RelatedUser{}
+	for rows.Next() {
+		var u RelatedUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan relation: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UserSearchResult — публичная проекция пользователя для поиска и списка (без email)
+type UserSearchResult struct {
+	ID              int
+	Username        string
+	FirstName       string
+	SecondName      string
+	AvatarUpdatedAt *time.Time
+}
+
+// searchUsersQuery — поиск в Postgres: похожие имена находятся по триграммам (pg_trgm)
+const searchUsersQuery = `
+		SELECT u.id, u.username, COALESCE(ui.first_name, ''), COALESCE(ui.second_name, ''), u.avatar_updated_at
+		FROM users u
+		LEFT JOIN users_information ui ON ui.user_id = u.id
+		WHERE u.deleted_at IS NULL AND (lower(u.username) LIKE $1 OR lower(u.username) % $2)
+		ORDER BY lower(u.username) LIKE $1 DESC, similarity(lower(u.username), $2) DESC, u.username ASC
+		LIMIT $3
+	`
+
+// searchUsersQuerySQLite — поиск в SQLite: триграмм нет, поэтому после совпадений по префиксу
+// идут имена, содержащие запрос, от коротких к длинным. У LIKE в SQLite нет экранирующего символа по умолчанию.
+const searchUsersQuerySQLite = `
+		SELECT u.id, u.username, COALESCE(ui.first_name, ''), COALESCE(ui.second_name, ''), u.avatar_updated_at
+		FROM users u
+		LEFT JOIN users_information ui ON ui.user_id = u.id
+		WHERE u.deleted_at IS NULL AND (lower(u.username) LIKE $1 ESCAPE '\' OR instr(lower(u.username), $2) > 0)
+		ORDER BY lower(u.username) LIKE $1 ESCAPE '\' DESC, length(u.username) ASC, u.username ASC
+		LIMIT $3
+	`
+
+// SearchUsers ищет пользователей по имени без учёта регистра.
+// Сначала идут совпадения по префиксу, затем похожие имена.
+func SearchUsers(db *sql.DB, query string, limit int) ([]UserSearchResult, error) {
+	query = strings.ToLower(query)
+	prefix := escapeLike(query) + "%"
+
+	statement := searchUsersQuery
+	if isSQLite(db) {
+		statement = searchUsersQuerySQLite
+	}
+	rows, err := db.Query(statement, prefix, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	results := []UserSearchResult{}
+	for rows.Next() {
+		var u UserSearchResult
+		if err := rows.Scan(&u.ID, &u.Username, &u.FirstName, &u.SecondName, &u.AvatarUpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		results = append(results, u)
+	}
+	return results, rows.Err()
+}
+
+// listUsersQuery возвращает ту же публичную проекцию, что и поиск, для всех пользователей
+const listUsersQuery = `
+		SELECT u.id, u.username, COALESCE(ui.first_name, ''), COALESCE(ui.second_name, ''), u.avatar_updated_at
+		FROM users u
+		LEFT JOIN users_information ui ON ui.user_id = u.id
+		WHERE u.deleted_at IS NULL
+		ORDER BY u.id ASC
+	`
+
+// ListUsers возвращает всех пользователей в порядке ID
+func ListUsers(db *sql.DB) ([]UserSearchResult, error) {
+	rows, err := db.Query(listUsersQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	results := []UserSearchResult{}
+	for rows.Next() {
+		var u UserSearchResult
+		if err := rows.Scan(&u.ID, &u.Username, &u.FirstName, &u.SecondName, &u.AvatarUpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		results = append(results, u)
+	}
+	return results, rows.Err()
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE (экранирующий символ по умолчанию — обратный слэш)
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,336 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"blog/pkg/migrate"
+	"users_service/migrations"
+)
+
+// newSQLite создаёт базу SQLite во временном каталоге и применяет к ней миграции сервиса
+func newSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "blog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	list, err := migrate.Load(migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &migrate.Migrator{DB: db, Service: "users-service", Migrations: list, SQLite: true}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Миграции не применились: %v", err)
+	}
+	return db
+}
+
+// addUser создаёт пользователя и возвращает его ID
+func addUser(t *testing.T, db *sql.DB, username string) int {
+	t.Helper()
+	if err := SaveUser(db, username, username+"@example.com", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	user, err := GetUserByEmail(db, username+"@example.com")
+	if err != nil || user == nil {
+		t.Fatalf("Пользователь %s не найден: %v", username, err)
+	}
+	return user.ID
+}
+
+func TestSQLiteUniqueViolationField(t *testing.T) {
+	db := newSQLite(t)
+	addUser(t, db, "alice")
+
+	tests := []struct {
+		name      string
+		username  string
+		email     string
+		wantField string
+	}{
+		{name: "Занятое имя", username: "alice", email: "other@example.com", wantField: "username"},
+		{name: "Занятый email", username: "bob", email: "alice@example.com", wantField: "email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SaveUser(db, tt.username, tt.email, "hash")
+			field, ok := UniqueViolationField(err)
+			if !ok || field != tt.wantField {
+				t.Errorf("Ожидалось поле %q, получено %q, %v (%v)", tt.wantField, field, ok, err)
+			}
+		})
+	}
+}
+
+func TestSQLiteProfileAndTOTP(t *testing.T) {
+	db := newSQLite(t)
+	userID := addUser(t, db, "alice")
+
+	// Повторное сохранение обновляет профиль (ON CONFLICT ... DO UPDATE)
+	birthdate := "1990-05-17"
+	for _, bio := range []string{"first", "second"} {
+		if err := SaveProfile(db, &Profile{UserID: userID, FirstName: "Alice", Birthdate: &birthdate, Bio: bio}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profile, err := GetProfile(db, userID)
+	if err != nil || profile == nil || profile.Bio != "second" || profile.Birthdate == nil || *profile.Birthdate != birthdate {
+		t.Errorf("Неожиданный профиль: %+v, %v", profile, err)
+	}
+
+	if err := SaveTOTPSecret(db, userID, "SECRET1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveTOTPSecret(db, userID, "SECRET2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnableTOTP(db, userID, 10, []string{"code-hash"}); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := GetTOTP(db, userID)
+	if err != nil || settings == nil || settings.Secret != "SECRET2" || !settings.Enabled {
+		t.Errorf("Неожиданные настройки TOTP: %+v, %v", settings, err)
+	}
+	if ok, err := UpdateTOTPStep(db, userID, 10); err != nil || ok {
+		t.Errorf("Использованный шаг принят повторно: %v, %v", ok, err)
+	}
+
+	codes, err := GetUnusedRecoveryCodes(db, userID)
+	if err != nil || len(codes) != 1 {
+		t.Fatalf("Неожиданные коды восстановления: %v, %v", codes, err)
+	}
+	if ok, err := MarkRecoveryCodeUsed(db, codes[0].ID); err != nil || !ok {
+		t.Errorf("Код не отмечен использованным: %v, %v", ok, err)
+	}
+	if ok, err := MarkRecoveryCodeUsed(db, codes[0].ID); err != nil || ok {
+		t.Errorf("Код использован повторно: %v, %v", ok, err)
+	}
+}
+
+func TestSQLiteTOTPFailures(t *testing.T) {
+	db := newSQLite(t)
+	userID := addUser(t, db, "alice")
+	if err := SaveTOTPSecret(db, userID, "SECRET"); err != nil {
+		t.Fatal(err)
+	}
+
+	lockUntil := time.Now().Add(15 * time.Minute)
+	for i := 1; i <= 3; i++ {
+		locked, err := RecordTOTPFailure(db, userID, 3, lockUntil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked != (i == 3) {
+			t.Errorf("Попытка %d: блокировка %v", i, locked)
+		}
+	}
+	settings, err := GetTOTP(db, userID)
+	if err != nil || settings.FailedAttempts != 0 || settings.LockedUntil == nil || settings.LockedUntil.Sub(lockUntil).Abs() > time.Millisecond {
+		t.Errorf("Ожидалась блокировка до %s со сброшенным счётчиком: %+v, %v", lockUntil, settings, err)
+	}
+
+	// После блокировки счётчик начинается заново, а успешный вход его сбрасывает
+	if _, err := RecordTOTPFailure(db, userID, 3, lockUntil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResetTOTPFailures(db, userID); err != nil {
+		t.Fatal(err)
+	}
+	if settings, err := GetTOTP(db, userID); err != nil || settings.FailedAttempts != 0 || settings.LockedUntil != nil {
+		t.Errorf("Счётчик не сброшен: %+v, %v", settings, err)
+	}
+}
+
+func TestSQLitePersonalAccessTokens(t *testing.T) {
+	db := newSQLite(t)
+	userID := addUser(t, db, "alice")
+
+	// Время в другом часовом поясе сохраняется в UTC
+	expiresAt := time.Now().Add(time.Hour).In(time.FixedZone("MSK", 3*60*60))
+	token, err := CreatePersonalAccessToken(db, userID, "ci", "blog_pat_abc", "hash", []string{"posts:read"}, &expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.ID == 0 || token.CreatedAt.IsZero() {
+		t.Errorf("Не заполнены ID и время создания: %+v", token)
+	}
+
+	tokens, err := ListPersonalAccessTokens(db, userID)
+	if err != nil || len(tokens) != 1 || tokens[0].ExpiresAt == nil || !tokens[0].ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Неожиданный список токенов: %+v, %v", tokens, err)
+	}
+
+	// Чужой токен не отзывается, даже если известен его ID
+	bobID := addUser(t, db, "bob")
+	if ok, err := RevokePersonalAccessToken(db, bobID, token.ID); err != nil || ok {
+		t.Errorf("Отозван чужой токен: %v, %v", ok, err)
+	}
+	if tokens, _ := ListPersonalAccessTokens(db, userID); len(tokens) != 1 || tokens[0].RevokedAt != nil {
+		t.Errorf("Чужой запрос изменил токен: %+v", tokens)
+	}
+
+	if ok, err := RevokePersonalAccessToken(db, userID, token.ID); err != nil || !ok {
+		t.Errorf("Токен не отозван: %v, %v", ok, err)
+	}
+	if ok, err := RevokePersonalAccessToken(db, userID, token.ID); err != nil || ok {
+		t.Errorf("Токен отозван повторно: %v, %v", ok, err)
+	}
+}
+
+func TestSQLiteRelationsAndSearch(t *testing.T) {
+	db := newSQLite(t)
+	alice := addUser(t, db, "alice")
+	bob := addUser(t, db, "bob")
+	addUser(t, db, "malice")
+	addUser(t, db, "alicewonder")
+
+	for _, kind := range []string{RelationFollow, RelationFollow} {
+		if err := AddRelation(db, alice, bob, kind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if following, err := ListRelations(db, alice, RelationFollow); err != nil || len(following) != 1 || following[0].Username != "bob" {
+		t.Errorf("Неожиданные подписки: %+v, %v", following, err)
+	}
+
+	// Блокировка отменяет подписки
+	if err := AddRelation(db, bob, alice, RelationBlock); err != nil {
+		t.Fatal(err)
+	}
+	if following, err := ListRelations(db, alice, RelationFollow); err != nil || len(following) != 0 {
+		t.Errorf("Подписка осталась после блокировки: %+v, %v", following, err)
+	}
+	if blocked, err := HasBlock(db, alice, bob); err != nil || !blocked {
+		t.Errorf("Ожидалась блокировка: %v, %v", blocked, err)
+	}
+
+	// Сначала совпадения по префиксу, затем имена, содержащие запрос
+	results, err := SearchUsers(db, "ALICE", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Username)
+	}
+	if len(names) != 3 || names[0] != "alice" || names[1] != "alicewonder" || names[2] != "malice" {
+		t.Errorf("Неожиданный порядок результатов: %v", names)
+	}
+	if results, err := SearchUsers(db, "a_", 10); err != nil || len(results) != 0 {
+		t.Errorf("Символ _ должен экранироваться: %+v, %v", results, err)
+	}
+}
+
+func TestSQLiteAccountDeletion(t *testing.T) {
+	db := newSQLite(t)
+	userID := addUser(t, db, "alice")
+	consumers := []string{"posts-service", "notifications-service"}
+
+	eventID, found, err := StartAccountDeletion(db, userID, consumers)
+	if err != nil || !found {
+		t.Fatalf("Удаление не началось: %v, %v", found, err)
+	}
+	if _, _, err := StartAccountDeletion(db, userID, consumers); !errors.Is(err, ErrDeletionInProgress) {
+		t.Errorf("Ожидалась ErrDeletionInProgress, получено %v", err)
+	}
+	if user, err := GetUserByID(db, userID); err != nil || user != nil {
+		t.Errorf("Удаляемый пользователь найден: %+v, %v", user, err)
+	}
+
+	due, err := ClaimDueDeliveries(db, 10, time.Minute)
+	if err != nil || len(due) != 2 {
+		t.Fatalf("Ожидались 2 доставки, получено %+v, %v", due, err)
+	}
+	if e := due[0].Event; e.ID != eventID || e.Type != EventUserDeleted || e.UserID != userID || string(e.Payload) == "" || e.CreatedAt.IsZero() {
+		t.Errorf("Неожиданное событие: %+v", e)
+	}
+	// Взятые доставки не выдаются повторно до истечения lease
+	if again, err := ClaimDueDeliveries(db, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Errorf("Доставки выданы повторно: %+v, %v", again, err)
+	}
+
+	if err := MarkDeliveryDone(db, eventID, consumers[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := MarkDeliveryFailed(db, eventID, consumers[1], "timeout", time.Now().Add(-time.Second), false); err != nil {
+		t.Fatal(err)
+	}
+	if finalized, err := FinalizeAccountDeletion(db, eventID); err != nil || finalized {
+		t.Errorf("Аккаунт удалён до обработки всеми потребителями: %v, %v", finalized, err)
+	}
+
+	due, err = ClaimDueDeliveries(db, 10, time.Minute)
+	if err != nil || len(due) != 1 || due[0].Consumer != consumers[1] || due[0].Attempts != 1 {
+		t.Fatalf("Ожидался повтор доставки, получено %+v, %v", due, err)
+	}
+	if err := MarkDeliveryDone(db, eventID, consumers[1]); err != nil {
+		t.Fatal(err)
+	}
+	if finalized, err := FinalizeAccountDeletion(db, eventID); err != nil || !finalized {
+		t.Errorf("Аккаунт не удалён: %v, %v", finalized, err)
+	}
+}
+
+func TestSQLiteDataExports(t *testing.T) {
+	db := newSQLite(t)
+	userID := addUser(t, db, "alice")
+
+	export, err := CreateDataExport(db, userID, "export1")
+	if err != nil || export.Status != ExportPending {
+		t.Fatalf("Выгрузка не создана: %+v, %v", export, err)
+	}
+	if _, err := CreateDataExport(db, userID, "export2"); !errors.Is(err, ErrExportInProgress) {
+		t.Errorf("Ожидалась ErrExportInProgress, получено %v", err)
+	}
+
+	claimed, err := ClaimDataExport(db, time.Hour)
+	if err != nil || claimed == nil || claimed.ID != "export1" || claimed.Status != ExportRunning {
+		t.Fatalf("Выгрузка не взята в работу: %+v, %v", claimed, err)
+	}
+	if claimed, err := ClaimDataExport(db, time.Hour); err != nil || claimed != nil {
+		t.Errorf("Выполняемая выгрузка взята повторно: %+v, %v", claimed, err)
+	}
+	// Прерванная остановкой сервиса выгрузка возвращается в очередь
+	if err := ReleaseDataExport(db, "export1"); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := ClaimDataExport(db, time.Hour); err != nil || claimed == nil || claimed.ID != "export1" {
+		t.Errorf("Освобождённая выгрузка не взята повторно: %+v, %v", claimed, err)
+	}
+	// Зависшая выгрузка берётся повторно
+	if claimed, err := ClaimDataExport(db, -time.Second); err != nil || claimed == nil {
+		t.Errorf("Зависшая выгрузка не взята повторно: %+v, %v", claimed, err)
+	}
+
+	if err := CompleteDataExport(db, "export1", "key", 42, time.Now().Add(-2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// Завершить можно только выполняемую выгрузку
+	if err := CompleteDataExport(db, "export1", "key", 42, time.Now()); !errors.Is(err, ErrExportNotRunning) {
+		t.Errorf("Ожидалась ErrExportNotRunning, получено %v", err)
+	}
+
+	// Записи о неудавшихся выгрузках тоже удаляются по истечении срока хранения
+	if _, err := CreateDataExport(db, userID, "export2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ClaimDataExport(db, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := FailDataExport(db, "export2", "posts_service unavailable", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := ExpiredDataExports(db, 10)
+	if err != nil || len(expired) != 2 || expired[0].ID != "export1" || expired[1].ID != "export2" {
+		t.Errorf("Ожидались истёкшие выгрузки export1 и export2, получено %+v, %v", expired, err)
+	}
+}
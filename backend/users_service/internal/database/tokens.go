@@ -0,0 +1,87 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PersonalAccessToken представляет персональный токен доступа (без самого секрета)
+type PersonalAccessToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// CreatePersonalAccessToken сохраняет хэш нового токена и возвращает его метаданные
+func CreatePersonalAccessToken(db *sql.DB, userID int, name, prefix, tokenHash string, scopes []string, expiresAt *time.Time) (*PersonalAccessToken, error) {
+	t := PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	err := db.QueryRow(`
+		INSERT INTO personal_access_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, userID, name, prefix, tokenHash, strings.Join(scopes, " "), utc(expiresAt)).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create personal access token: %w", err)
+	}
+	return &t, nil
+}
+
+// ListPersonalAccessTokens возвращает все токены пользователя, включая отозванные
+func ListPersonalAccessTokens(db *sql.DB, userID int) ([]PersonalAccessToken, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name, token_prefix, scopes, created_at, expires_at, last_used_at, revoked_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personal access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []PersonalAccessToken{}
+	for rows.Next() {
+		var (
+			t      PersonalAccessToken
+			scopes string
+		)
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &scopes, &t.CreatedAt, &t.ExpiresAt, &t.LastUsedAt, &t.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan personal access token: %w", err)
+		}
+		t.Scopes = strings.Fields(scopes)
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokePersonalAccessToken отзывает токен пользователя.
+// Возвращает false, если токен не найден или уже отозван.
+func RevokePersonalAccessToken(db *sql.DB, userID, tokenID int) (bool, error) {
+	res, err := db.Exec(portable(db, `
+		UPDATE personal_access_tokens
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`), tokenID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke personal access token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke personal access token: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TOTP представляет настройки двухфакторной аутентификации пользователя
+type TOTP struct {
+	UserID       int
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+	// FailedAttempts — неверные коды подряд с последнего успешного входа или блокировки
+	FailedAttempts int
+	// LockedUntil — до этого времени проверка кодов отклоняется
+	LockedUntil *time.Time
+}
+
+// RecoveryCode представляет хэшированный код восстановления
+type RecoveryCode struct {
+	ID       int
+	CodeHash string
+}
+
+// GetTOTP возвращает настройки TOTP пользователя или nil, если их нет
+func GetTOTP(db *sql.DB, userID int) (*TOTP, error) {
+	var t TOTP
+	err := db.QueryRow(`
+		SELECT user_id, secret, enabled, last_used_step, failed_attempts, locked_until
+		FROM user_totp
+		WHERE user_id = $1
+	`, userID).Scan(&t.UserID, &t.Secret, &t.Enabled, &t.LastUsedStep, &t.FailedAttempts, &t.LockedUntil)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch totp settings: %w", err)
+	}
+	return &t, nil
+}
+
+// SaveTOTPSecret сохраняет новый, ещё не подтверждённый секрет
+func SaveTOTPSecret(db *sql.DB, userID int, secret string) error {
+	_, err := db.Exec(`
+		INSERT INTO user_totp (user_id, secret, enabled, last_used_step)
+		VALUES ($1, $2, FALSE, 0)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, enabled = FALSE, last_used_step = 0, confirmed_at = NULL
+	`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	return nil
+}
+
+// EnableTOTP включает двухфакторную аутентификацию и заменяет коды восстановления
+func EnableTOTP(db *sql.DB, userID int, step int64, codeHashes []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(portable(db, `
+		UPDATE user_totp
+		SET enabled = TRUE, last_used_step = $2, confirmed_at = NOW()
+		WHERE user_id = $1
+	`), userID, step); err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`
+			INSERT INTO user_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`, userID, hash); err != nil {
+			return fmt.Errorf("failed to save recovery code: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateTOTPStep запоминает последний использованный шаг, чтобы код нельзя было применить повторно.
+// Возвращает false, если шаг уже был использован.
+func UpdateTOTPStep(db *sql.DB, userID int, step int64) (bool, error) {
+	res, err := db.Exec(`
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to update totp step: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update totp step: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RecordTOTPFailure учитывает неверный код. После maxAttempts неверных кодов подряд проверка
+// блокируется до lockUntil, а счётчик начинается заново. Возвращает true, если наступила блокировка.
+func RecordTOTPFailure(db *sql.DB, userID, maxAttempts int, lockUntil time.Time) (bool, error) {
+	var attempts int
+	err := db.QueryRow(`
+		UPDATE user_totp
+		SET failed_attempts = failed_attempts + 1, locked_until = NULL
+		WHERE user_id = $1
+		RETURNING failed_attempts
+	`, userID).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to record totp failure: %w", err)
+	}
+	if attempts < maxAttempts {
+		return false, nil
+	}
+
+	if _, err := db.Exec("UPDATE user_totp SET failed_attempts = 0, locked_until = $2 WHERE user_id = $1", userID, utc(&lockUntil)); err != nil {
+		return false, fmt.Errorf("failed to lock totp verification: %w", err)
+	}
+	return true, nil
+}
+
+// ResetTOTPFailures сбрасывает счётчик неверных кодов после успешного входа
+func ResetTOTPFailures(db *sql.DB, userID int) error {
+	if _, err := db.Exec("UPDATE user_totp SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to reset totp failures: %w", err)
+	}
+	return nil
+}
+
+// GetUnusedRecoveryCodes возвращает неиспользованные коды восстановления пользователя
+func GetUnusedRecoveryCodes(db *sql.DB, userID int) ([]RecoveryCode, error) {
+	rows, err := db.Query(`
+		SELECT id, code_hash
+		FROM user_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recovery codes: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []RecoveryCode
+	for rows.Next() {
+		var c RecoveryCode
+		if err := rows.Scan(&c.ID, &c.CodeHash); err != nil {
+			return nil, fmt.Errorf("failed to scan recovery code: %w", err)
+		}
+		codes = append(codes, c)
+	}
+	return codes, rows.Err()
+}
+
+// MarkRecoveryCodeUsed помечает код восстановления использованным.
+// Возвращает false, если код уже был использован параллельным запросом.
+func MarkRecoveryCodeUsed(db *sql.DB, codeID int) (bool, error) {
+	res, err := db.Exec(portable(db, `
+		UPDATE user_recovery_codes
+		SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL
+	`), codeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark recovery code used: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark recovery code used: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,221 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"users_service/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Параметры доставки по умолчанию
+const (
+	DefaultInterval    = 5 * time.Second
+	DefaultBatchSize   = 20
+	DefaultMaxAttempts = 10
+	// leaseDuration защищает взятую доставку от повторного захвата, пока идёт запрос
+	leaseDuration = time.Minute
+	baseBackoff   = 10 * time.Second
+	maxBackoff    = time.Hour
+)
+
+// Consumer — сервис, которому доставляются события
+type Consumer struct {
+	Name string
+	URL  string
+}
+
+// ParseConsumers разбирает список вида "posts-service=http://posts:8083/internal/events,..."
+func ParseConsumers(spec string) ([]Consumer, error) {
+	var consumers []Consumer
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(part, "=")
+		name, url = strings.TrimSpace(name), strings.TrimSpace(url)
+		if !ok || name == "" || !(strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")) {
+			return nil, fmt.Errorf("invalid event consumer %q, expected name=http(s)://url", part)
+		}
+		consumers = append(consumers, Consumer{Name: name, URL: url})
+	}
+	return consumers, nil
+}
+
+// Backoff возвращает задержку перед следующей попыткой: 10s, 20s, 40s ... но не больше часа
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := baseBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// Dispatcher доставляет события из event_outbox потребителям с повторами
+type Dispatcher struct {
+	DB          *sql.DB
+	Consumers   []Consumer
+	Client      *http.Client
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	Logger      *logrus.Logger
+}
+
+// ConsumerNames возвращает имена потребителей, для которых создаются доставки
+func (d *Dispatcher) ConsumerNames() []string {
+	names := make([]string, 0, len(d.Consumers))
+	for _, c := range d.Consumers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// Run периодически доставляет события, пока не отменён ctx
+func (d *Dispatcher) Run(ctx context.Context) {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.DispatchOnce(ctx); err != nil {
+			d.logger().WithError(err).Error("Users-Service: Event dispatch failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce обрабатывает одну пачку доставок, время которых подошло
+func (d *Dispatcher) DispatchOnce(ctx context.Context) error {
+	batch := d.BatchSize
+	if batch <= 0 {
+		batch = DefaultBatchSize
+	}
+	due, err := database.ClaimDueDeliveries(d.DB, batch, leaseDuration)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			// Остальные доставки вернутся в работу по истечении lease
+			return nil
+		}
+		d.deliver(ctx, delivery)
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery database.DueDelivery) {
+	logger := d.logger().WithFields(logrus.Fields{
+		"event_id": delivery.Event.ID,
+		"type":     delivery.Event.Type,
+		"consumer": delivery.Consumer,
+	})
+
+	// Начатая доставка завершается и при остановке сервиса, иначе потребитель может
+	// обработать событие, а попытка будет записана как неудачная
+	err := d.send(context.WithoutCancel(ctx), delivery)
+	if err != nil {
+		attempt := delivery.Attempts + 1
+		maxAttempts := d.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = DefaultMaxAttempts
+		}
+		final := attempt >= maxAttempts
+		if markErr := database.MarkDeliveryFailed(d.DB, delivery.Event.ID, delivery.Consumer, err.Error(), time.Now().Add(Backoff(attempt)), final); markErr != nil {
+			logger.WithError(markErr).Error("Users-Service: Failed to record delivery failure")
+		}
+		logger.WithError(err).WithField("attempt", attempt).Warn("Users-Service: Event delivery failed")
+		return
+	}
+
+	if err := database.MarkDeliveryDone(d.DB, delivery.Event.ID, delivery.Consumer); err != nil {
+		logger.WithError(err).Error("Users-Service: Failed to record delivery")
+		return
+	}
+	logger.Info("Users-Service: Event delivered")
+
+	if delivery.Event.Type == database.EventUserDeleted {
+		finalized, err := database.FinalizeAccountDeletion(d.DB, delivery.Event.ID)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to finalize account deletion")
+		} else if finalized {
+			logger.WithField("user_id", delivery.Event.UserID).Info("Users-Service: Account deleted")
+		}
+	}
+}
+
+// send отправляет событие потребителю; успехом считается любой ответ 2xx
+func (d *Dispatcher) send(ctx context.Context, delivery database.DueDelivery) error {
+	var url string
+	for _, c := range d.Consumers {
+		if c.Name == delivery.Consumer {
+			url = c.URL
+		}
+	}
+	if url == "" {
+		return fmt.Errorf("consumer %q is not configured", delivery.Consumer)
+	}
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", strconv.FormatInt(delivery.Event.ID, 10))
+	req.Header.Set("X-Event-Type", delivery.Event.Type)
+
+	client := http.DefaultClient
+	if d.Client != nil {
+		client = d.Client
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("consumer responded with %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (d *Dispatcher) logger() *logrus.Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+	return logrus.StandardLogger()
+}
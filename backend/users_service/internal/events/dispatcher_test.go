@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, ожидалось %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestParseConsumers(t *testing.T) {
+	consumers, err := ParseConsumers("posts-service=http://posts:8083/internal/events, notifications-service=http://notifications:8085/internal/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(consumers) != 2 || consumers[1].Name != "notifications-service" {
+		t.Errorf("неожиданный результат: %+v", consumers)
+	}
+
+	for _, spec := range []string{"posts-service", "=http://x", "posts-service=ftp://x"} {
+		if _, err := ParseConsumers(spec); err == nil {
+			t.Errorf("ParseConsumers(%q): ожидалась ошибка", spec)
+		}
+	}
+}
+
+func claimedRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "event_type", "user_id", "payload", "created_at", "consumer", "attempts"}).
+		AddRow(7, "user.deleted", 1, []byte(`{"user_id":1}`), time.Now(), "posts-service", 2)
+}
+
+func TestDispatchOnceDeliversAndFinalizes(t *testing.T) {
+	var gotEventID string
+	consumer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEventID = r.Header.Get("X-Event-Id")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer consumer.Close()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE event_deliveries d")).WillReturnRows(claimedRows())
+	mock.ExpectExec(regexp.QuoteMeta("SET status = 'done'")).WithArgs(7, "posts-service").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users")).WithArgs(7, "user.deleted").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	d := &Dispatcher{DB: db, Consumers: []Consumer{{Name: "posts-service", URL: consumer.URL}}}
+	if err := d.DispatchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if gotEventID != "7" {
+		t.Errorf("ожидался заголовок X-Event-Id=7, получено %q", gotEventID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDispatchOnceSchedulesRetry(t *testing.T) {
+	consumer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database is down", http.StatusServiceUnavailable)
+	}))
+	defer consumer.Close()
+
+	tests := []struct {
+		name        string
+		maxAttempts int
+		wantStatus  string
+	}{
+		{"повтор по расписанию", 10, "pending"},
+		{"попытки исчерпаны", 3, "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta("UPDATE event_deliveries d")).WillReturnRows(claimedRows())
+			mock.ExpectExec(regexp.QuoteMeta("SET status = $3")).
+				WithArgs(7, "posts-service", tt.wantStatus, sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			d := &Dispatcher{DB: db, Consumers: []Consumer{{Name: "posts-service", URL: consumer.URL}}, MaxAttempts: tt.maxAttempts}
+			if err := d.DispatchOnce(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"users_service/internal/database"
+)
+
+// Account — учётные данные пользователя в архиве (без хэша пароля и секретов 2FA)
+type Account struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	Role        string `json:"role"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+}
+
+// Post, Like и Notification повторяют ответ posts_service /internal/users/{id}/export
+type Post struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Visibility string    `json:"visibility"`
+	CreatedAt  time.Time `json:"created_at"`
+	LikeCount  int       `json:"like_count"`
+}
+
+type Like struct {
+	PostID    int    `json:"post_id"`
+	PostTitle string `json:"post_title"`
+	UserID    int    `json:"user_id"`
+}
+
+type Notification struct {
+	ID      int    `json:"id"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	PostID  *int   `json:"post_id"`
+	LikerID *int   `json:"liker_id"`
+	IsRead  bool   `json:"is_read"`
+}
+
+// Content — данные пользователя, которыми владеет posts_service
+type Content struct {
+	Posts         []Post         `json:"posts"`
+	LikesGiven    []Like         `json:"likes_given"`
+	LikesReceived []Like         `json:"likes_received"`
+	Notifications []Notification `json:"notifications"`
+}
+
+// Archive — всё, что попадает в выгрузку персональных данных
+type Archive struct {
+	GeneratedAt  time.Time
+	Account      Account
+	Profile      *database.Profile
+	AccessTokens []database.PersonalAccessToken
+	Content      Content
+}
+
+// WriteZip записывает архив: машиночитаемые JSON-файлы и Markdown для чтения человеком
+func WriteZip(w io.Writer, a *Archive) error {
+	zw := zip.NewWriter(w)
+
+	jsonFiles := []struct {
+		name string
+		data interface{}
+	}{
+		{"account.json", a.Account},
+		{"profile.json", a.Profile},
+		{"access_tokens.json", a.AccessTokens},
+		{"posts.json", a.Content.Posts},
+		{"likes_given.json", a.Content.LikesGiven},
+		{"likes_received.json", a.Content.LikesReceived},
+		{"notifications.json", a.Content.Notifications},
+	}
+	for _, f := range jsonFiles {
+		data, err := json.MarshalIndent(f.data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", f.name, err)
+		}
+		if err := writeFile(zw, f.name, a.GeneratedAt, data); err != nil {
+			return err
+		}
+	}
+
+	markdownFiles := []struct {
+		name string
+		text string
+	}{
+		{"README.md", readme(a)},
+		{"profile.md", profileMarkdown(a)},
+		{"posts.md", postsMarkdown(a)},
+	}
+	for _, f := range markdownFiles {
+		if err := writeFile(zw, f.name, a.GeneratedAt, []byte(f.text)); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func readme(a *Archive) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Data export for @%s\n\n", a.Account.Username)
+	fmt.Fprintf(&b, "Generated at %s.\n\n", a.GeneratedAt.UTC().Format(time.RFC3339))
+	b.WriteString("| File | Contents |\n|---|---|\n")
+	b.WriteString("| account.json | Account identity and settings |\n")
+	b.WriteString("| profile.json, profile.md | Profile information |\n")
+	b.WriteString("| access_tokens.json | Personal access tokens (metadata only, no secrets) |\n")
+	fmt.Fprintf(&b, "| posts.json, posts.md | %d authored posts |\n", len(a.Content.Posts))
+	fmt.Fprintf(&b, "| likes_given.json | %d likes you gave |\n", len(a.Content.LikesGiven))
+	fmt.Fprintf(&b, "| likes_received.json | %d likes on your posts |\n", len(a.Content.LikesReceived))
+	fmt.Fprintf(&b, "| notifications.json | %d notifications |\n", len(a.Content.Notifications))
+	return b.String()
+}
+
+func profileMarkdown(a *Archive) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# @%s\n\n", a.Account.Username)
+	fmt.Fprintf(&b, "- **Email:** %s\n", a.Account.Email)
+	fmt.Fprintf(&b, "- **Role:** %s\n", a.Account.Role)
+	fmt.Fprintf(&b, "- **Two-factor authentication:** %t\n", a.Account.TOTPEnabled)
+	if p := a.Profile; p != nil {
+		fmt.Fprintf(&b, "- **Name:** %s\n", strings.TrimSpace(p.FirstName+" "+p.SecondName))
+		if p.Birthdate != nil {
+			fmt.Fprintf(&b, "- **Birthdate:** %s\n", *p.Birthdate)
+		}
+		fmt.Fprintf(&b, "- **Location:** %s\n", p.Location)
+		fmt.Fprintf(&b, "- **Website:** %s\n", p.Website)
+		if p.Bio != "" {
+			fmt.Fprintf(&b, "\n## Bio\n\n%s\n", p.Bio)
+		}
+	}
+	return b.String()
+}
+
+func postsMarkdown(a *Archive) string {
+	var b strings.Builder
+	b.WriteString("# Posts\n")
+	if len(a.Content.Posts) == 0 {
+		b.WriteString("\nNo posts.\n")
+	}
+	for _, p := range a.Content.Posts {
+		fmt.Fprintf(&b, "\n## %s\n\n", p.Title)
+		fmt.Fprintf(&b, "_Published %s · %s · %d likes_\n\n", p.CreatedAt.UTC().Format("2006-01-02 15:04"), p.Visibility, p.LikeCount)
+		b.WriteString(p.Content)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
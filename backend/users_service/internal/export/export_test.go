@@ -0,0 +1,207 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/pkg/migrate"
+	"users_service/internal/database"
+	"users_service/internal/storage"
+	"users_service/migrations"
+)
+
+func TestWriteZip(t *testing.T) {
+	archive := &Archive{
+		GeneratedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Account:     Account{ID: 1, Username: "alice", Email: "alice@example.com", Role: "user"},
+		Profile:     &database.Profile{UserID: 1, FirstName: "Alice", Bio: "Hello"},
+		Content: Content{
+			Posts:      []Post{{ID: 10, Title: "First post", Content: "Body", LikeCount: 2}},
+			LikesGiven: []Like{{PostID: 20, PostTitle: "Other", UserID: 1}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteZip(&buf, archive); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"README.md", "account.json", "profile.json", "profile.md", "access_tokens.json",
+		"posts.json", "posts.md", "likes_given.json", "likes_received.json", "notifications.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("в архиве нет файла %s", name)
+		}
+	}
+
+	var posts []Post
+	if err := json.Unmarshal([]byte(files["posts.json"]), &posts); err != nil || len(posts) != 1 || posts[0].Title != "First post" {
+		t.Errorf("posts.json = %s (%v)", files["posts.json"], err)
+	}
+	if !strings.Contains(files["posts.md"], "## First post") {
+		t.Errorf("posts.md не содержит заголовок поста: %s", files["posts.md"])
+	}
+	if strings.Contains(files["account.json"], "password") {
+		t.Error("account.json не должен содержать пароль")
+	}
+}
+
+func TestLinkSigner(t *testing.T) {
+	signer := &LinkSigner{Secret: []byte(strings.Repeat("s", 32)), TTL: 15 * time.Minute}
+	now := time.Now()
+	link, expiresAt := signer.URL(1, "abc", now)
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+
+	tests := []struct {
+		name     string
+		userID   int
+		exportID string
+		sig      string
+		at       time.Time
+		want     bool
+	}{
+		{"действующая ссылка", 1, "abc", signature, now, true},
+		{"истёкшая ссылка", 1, "abc", signature, expiresAt.Add(time.Second), false},
+		{"чужой пользователь", 2, "abc", signature, now, false},
+		{"другая выгрузка", 1, "abd", signature, now, false},
+		{"подделанная подпись", 1, "abc", strings.Repeat("0", len(signature)), now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signer.Verify(tt.userID, tt.exportID, expires, tt.sig, tt.at); got != tt.want {
+				t.Errorf("Verify() = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newWorker создаёт обработчик выгрузок на SQLite с одной выгрузкой в очереди.
+// posts отвечает вместо posts_service.
+func newWorker(t *testing.T, posts http.HandlerFunc) (*Worker, *sql.DB, *database.DataExport) {
+	t.Helper()
+
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "blog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	list, err := migrate.Load(migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &migrate.Migrator{DB: db, Service: "users-service", Migrations: list, SQLite: true}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.SaveUser(db, "alice", "alice@example.com", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	user, err := database.GetUserByEmail(db, "alice@example.com")
+	if err != nil || user == nil {
+		t.Fatalf("Пользователь не создан: %v", err)
+	}
+	job, err := database.CreateDataExport(db, user.ID, strings.Repeat("a", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := storage.NewLocalDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(posts)
+	t.Cleanup(srv.Close)
+	return &Worker{DB: db, Store: store, PostsServiceURL: srv.URL}, db, job
+}
+
+func TestWorkerProcessOnce(t *testing.T) {
+	w, db, job := newWorker(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"posts": [{"id": 1, "title": "First post"}]}`))
+	})
+
+	if processed, err := w.ProcessOnce(context.Background()); !processed || err != nil {
+		t.Fatalf("Выгрузка не обработана: %v, %v", processed, err)
+	}
+	got, err := database.GetDataExport(db, job.UserID, job.ID)
+	if err != nil || got == nil || got.Status != database.ExportReady || got.StorageKey == nil {
+		t.Fatalf("Ожидалась готовая выгрузка, получено %+v, %v", got, err)
+	}
+	file, _, err := w.Store.Get(context.Background(), *got.StorageKey)
+	if err != nil {
+		t.Fatalf("Архив не сохранён: %v", err)
+	}
+	file.Close()
+}
+
+func TestWorkerDiscardsArchiveOfRemovedExport(t *testing.T) {
+	var db *sql.DB
+	var job *database.DataExport
+	w, db, job := newWorker(t, func(w http.ResponseWriter, r *http.Request) {
+		// Пока собирается архив, пользователь удаляет аккаунт вместе с выгрузкой
+		if err := database.DeleteDataExport(db, job.ID); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	if processed, err := w.ProcessOnce(context.Background()); !processed || err != nil {
+		t.Fatalf("Выгрузка не обработана: %v, %v", processed, err)
+	}
+	if _, _, err := w.Store.Get(context.Background(), StorageKey(job.UserID, job.ID)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Архив удалённой выгрузки должен быть удалён, получено %v", err)
+	}
+}
+
+func TestWorkerCleansUpFailedExports(t *testing.T) {
+	w, db, job := newWorker(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	w.Retention = time.Millisecond
+
+	if processed, err := w.ProcessOnce(context.Background()); !processed || err == nil {
+		t.Fatalf("Ожидалась ошибка выгрузки: %v, %v", processed, err)
+	}
+	if got, _ := database.GetDataExport(db, job.UserID, job.ID); got == nil || got.Status != database.ExportFailed {
+		t.Fatalf("Ожидалась неудавшаяся выгрузка, получено %+v", got)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := w.CleanupExpired(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := database.GetDataExport(db, job.UserID, job.ID); err != nil || got != nil {
+		t.Errorf("Запись о неудавшейся выгрузке должна быть удалена, получено %+v, %v", got, err)
+	}
+}
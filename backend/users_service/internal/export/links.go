@@ -0,0 +1,43 @@
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// LinkSigner подписывает ссылки на скачивание архива, чтобы их можно было открыть без токена
+type LinkSigner struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+func (s *LinkSigner) signature(userID int, exportID string, expires int64) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	fmt.Fprintf(mac, "%d\n%s\n%d", userID, exportID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// URL возвращает подписанную ссылку на скачивание и время её истечения
+func (s *LinkSigner) URL(userID int, exportID string, now time.Time) (string, time.Time) {
+	expiresAt := now.Add(s.TTL)
+	expires := expiresAt.Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", s.signature(userID, exportID, expires))
+	return fmt.Sprintf("/api/users/%d/export/%s/download?%s", userID, exportID, q.Encode()), time.Unix(expires, 0)
+}
+
+// Verify проверяет подпись и срок действия ссылки
+func (s *LinkSigner) Verify(userID int, exportID, expires, signature string, now time.Time) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > exp {
+		return false
+	}
+	expected := s.signature(userID, exportID, exp)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,228 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"users_service/internal/database"
+	"users_service/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Параметры обработки выгрузок по умолчанию
+const (
+	DefaultInterval  = 5 * time.Second
+	DefaultRetention = 24 * time.Hour
+	// staleAfter — через сколько выгрузка в статусе running считается брошенной
+	staleAfter = 10 * time.Minute
+)
+
+// Worker собирает архивы персональных данных в фоне и удаляет просроченные
+type Worker struct {
+	DB              *sql.DB
+	Store           storage.Storage
+	PostsServiceURL string
+	Client          *http.Client
+	Interval        time.Duration
+	Retention       time.Duration
+	Logger          *logrus.Logger
+}
+
+// StorageKey возвращает ключ архива в хранилище
+func StorageKey(userID int, exportID string) string {
+	return fmt.Sprintf("%d/%s.zip", userID, exportID)
+}
+
+// Run обрабатывает очередь выгрузок, пока не отменён ctx
+func (w *Worker) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			processed, err := w.ProcessOnce(ctx)
+			if err != nil {
+				w.logger().WithError(err).Error("Users-Service: Data export failed")
+			}
+			if !processed || ctx.Err() != nil {
+				break
+			}
+		}
+		if err := w.CleanupExpired(ctx); err != nil {
+			w.logger().WithError(err).Error("Users-Service: Failed to clean up data exports")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessOnce собирает одну выгрузку из очереди. processed=false означает, что очередь пуста.
+func (w *Worker) ProcessOnce(ctx context.Context) (processed bool, err error) {
+	job, err := database.ClaimDataExport(w.DB, staleAfter)
+	if err != nil || job == nil {
+		return false, err
+	}
+	logger := w.logger().WithFields(logrus.Fields{"export_id": job.ID, "user_id": job.UserID})
+
+	retention := w.Retention
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+
+	size, err := w.build(ctx, job)
+	if err != nil && ctx.Err() != nil {
+		// Сервис останавливается: выгрузка возвращается в очередь и будет собрана заново
+		if releaseErr := database.ReleaseDataExport(w.DB, job.ID); releaseErr != nil {
+			logger.WithError(releaseErr).Error("Users-Service: Failed to release data export")
+		}
+		return true, nil
+	}
+	if err != nil {
+		if failErr := database.FailDataExport(w.DB, job.ID, err.Error(), time.Now().Add(retention)); failErr != nil {
+			logger.WithError(failErr).Error("Users-Service: Failed to record data export failure")
+		}
+		return true, fmt.Errorf("export %s: %w", job.ID, err)
+	}
+
+	key := StorageKey(job.UserID, job.ID)
+	err = database.CompleteDataExport(w.DB, job.ID, key, size, time.Now().Add(retention))
+	if errors.Is(err, database.ErrExportNotRunning) {
+		// Запись удалена вместе с аккаунтом или выгрузка уже завершилась: архив никому не достанется
+		logger.Warn("Users-Service: Data export is no longer running, discarding archive")
+		if err := w.Store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return true, fmt.Errorf("failed to delete archive %s: %w", job.ID, err)
+		}
+		return true, nil
+	} else if err != nil {
+		return true, err
+	}
+	logger.WithField("size_bytes", size).Info("Users-Service: Data export ready")
+	return true, nil
+}
+
+func (w *Worker) build(ctx context.Context, job *database.DataExport) (int64, error) {
+	archive, err := w.collect(ctx, job.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	if err := WriteZip(&buf, archive); err != nil {
+		return 0, err
+	}
+	size := int64(buf.Len())
+	if err := w.Store.Put(ctx, StorageKey(job.UserID, job.ID), &buf); err != nil {
+		return 0, fmt.Errorf("failed to store archive: %w", err)
+	}
+	return size, nil
+}
+
+// collect собирает данные из users_service и posts_service
+func (w *Worker) collect(ctx context.Context, userID int) (*Archive, error) {
+	user, err := database.GetUserByID(w.DB, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	profile, err := database.GetProfile(w.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := database.ListPersonalAccessTokens(w.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := w.fetchContent(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		GeneratedAt: time.Now().UTC(),
+		Account: Account{
+			ID:          user.ID,
+			Username:    user.Username,
+			Email:       user.Email,
+			Role:        user.Role,
+			TOTPEnabled: user.TOTPEnabled,
+		},
+		Profile:      profile,
+		AccessTokens: tokens,
+		Content:      *content,
+	}, nil
+}
+
+// fetchContent запрашивает посты, лайки и уведомления пользователя у posts_service
+func (w *Worker) fetchContent(ctx context.Context, userID int) (*Content, error) {
+	if w.PostsServiceURL == "" {
+		return nil, fmt.Errorf("POSTS_SERVICE_URL not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/internal/users/%d/export", w.PostsServiceURL, userID), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := http.DefaultClient
+	if w.Client != nil {
+		client = w.Client
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch posts data: status %d", resp.StatusCode)
+	}
+
+	var content Content
+	if err := json.NewDecoder(resp.Body).Decode(&content); err != nil {
+		return nil, fmt.Errorf("failed to decode posts data: %w", err)
+	}
+	return &content, nil
+}
+
+// CleanupExpired удаляет архивы и записи о неудавшихся выгрузках с истёкшим сроком хранения
+func (w *Worker) CleanupExpired(ctx context.Context) error {
+	expired, err := database.ExpiredDataExports(w.DB, 100)
+	if err != nil {
+		return err
+	}
+	for _, e := range expired {
+		if e.StorageKey != nil {
+			if err := w.Store.Delete(ctx, *e.StorageKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("failed to delete archive %s: %w", e.ID, err)
+			}
+		}
+		if err := database.DeleteDataExport(w.DB, e.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) logger() *logrus.Logger {
+	if w.Logger != nil {
+		return w.Logger
+	}
+	return logrus.StandardLogger()
+}
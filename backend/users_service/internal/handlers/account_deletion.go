@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Итоговые статусы удаления аккаунта
+const (
+	deletionInProgress = "in_progress"
+	deletionCompleted  = "completed"
+	deletionFailed     = "failed"
+)
+
+// AccountDeletionStatus описывает ход удаления аккаунта по каждому потребителю события
+type AccountDeletionStatus struct {
+	UserID    int                 `json:"user_id"`
+	EventID   int64               `json:"event_id"`
+	Status    string              `json:"status"`
+	StartedAt time.Time           `json:"started_at"`
+	Consumers []database.Delivery `json:"consumers"`
+}
+
+// GetAccountDeletion возвращает ход удаления аккаунта
+func GetAccountDeletion(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		event, deliveries, err := database.GetAccountDeletion(db, userID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch deletion status")
+			return
+		}
+		if event == nil {
+			apierror.Error(w, r, http.StatusNotFound, "Account deletion not found")
+			return
+		}
+
+		status := deletionCompleted
+		for _, d := range deliveries {
+			if d.Status == database.DeliveryFailed {
+				status = deletionFailed
+				break
+			}
+			if d.Status == database.DeliveryPending {
+				status = deletionInProgress
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AccountDeletionStatus{
+			UserID:    userID,
+			EventID:   event.ID,
+			Status:    status,
+			StartedAt: event.CreatedAt,
+			Consumers: deliveries,
+		})
+	}
+}
+
+// RetryAccountDeletion возвращает в очередь доставки, исчерпавшие попытки (только для администраторов)
+func RetryAccountDeletion(db *sql.DB) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		event, _, err := database.GetAccountDeletion(db, userID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch deletion status")
+			return
+		}
+		if event == nil {
+			apierror.Error(w, r, http.StatusNotFound, "Account deletion not found")
+			return
+		}
+
+		rescheduled, err := database.RetryFailedDeliveries(db, event.ID)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to reschedule deliveries")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to retry account deletion")
+			return
+		}
+
+		logger.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"event_id":    event.ID,
+			"rescheduled": rescheduled,
+		}).Info("Users-Service: Account deletion retried")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     "Failed deliveries rescheduled",
+			"rescheduled": rescheduled,
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog/pkg/apierror"
+	"users_service/internal/avatar"
+	"users_service/internal/database"
+	"users_service/internal/storage"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// avatarKey возвращает ключ миниатюры в хранилище
+func avatarKey(userID, size int) string {
+	return fmt.Sprintf("avatars/%d/%d.jpg", userID, size)
+}
+
+// avatarURL возвращает ссылку на аватар пользователя или nil, если аватар не загружен.
+// Версия в query меняется при каждой загрузке, что позволяет кэшировать ответ надолго.
+func avatarURL(userID int, updatedAt *time.Time) *string {
+	if updatedAt == nil {
+		return nil
+	}
+	url := fmt.Sprintf("/api/users/%d/avatar?v=%d", userID, updatedAt.Unix())
+	return &url
+}
+
+// UploadAvatar принимает PNG/JPEG/WebP (в теле запроса или в поле "avatar" формы),
+// удаляет метаданные и сохраняет квадратные миниатюры
+func UploadAvatar(db *sql.DB, store storage.Storage) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		data, err := readAvatarUpload(w, r)
+		if err != nil {
+			if errors.Is(err, avatar.ErrTooLarge) {
+				apierror.Error(w, r, http.StatusRequestEntityTooLarge, "Avatar must be at most 5 MB")
+				return
+			}
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid avatar upload")
+			return
+		}
+
+		thumbs, err := avatar.Process(data)
+		if errors.Is(err, avatar.ErrUnsupportedFormat) {
+			apierror.Error(w, r, http.StatusUnsupportedMediaType, err.Error())
+			return
+		} else if errors.Is(err, avatar.ErrTooLarge) {
+			apierror.Error(w, r, http.StatusRequestEntityTooLarge, "Image dimensions are too large")
+			return
+		} else if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to process avatar")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to process avatar")
+			return
+		}
+
+		_, found, err := database.GetAvatarUpdatedAt(db, userID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch user")
+			return
+		}
+		if !found {
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+
+		for _, size := range avatar.Sizes {
+			if err := store.Put(r.Context(), avatarKey(userID, size), bytes.NewReader(thumbs[size])); err != nil {
+				logger.WithError(err).Error("Users-Service: Failed to store avatar")
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to store avatar")
+				return
+			}
+		}
+
+		updatedAt := time.Now().UTC()
+		if err := database.SetAvatarUpdatedAt(db, userID, updatedAt); err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to save avatar timestamp")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to store avatar")
+			return
+		}
+
+		logger.WithField("user_id", userID).Info("Users-Service: Avatar updated")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":    "Avatar updated successfully",
+			"avatar_url": avatarURL(userID, &updatedAt),
+		})
+	}
+}
+
+// readAvatarUpload читает файл из multipart-формы или напрямую из тела запроса
+func readAvatarUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, avatar.MaxUploadBytes+1<<20)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") {
+		file, _, err := r.FormFile("avatar")
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, avatar.ErrTooLarge
+			}
+			return nil, err
+		}
+		defer file.Close()
+		return avatar.ReadLimited(file)
+	}
+
+	data, err := avatar.ReadLimited(r.Body)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return nil, avatar.ErrTooLarge
+	}
+	return data, err
+}
+
+// GetAvatar отдаёт миниатюру аватара с заголовками кэширования
+func GetAvatar(db *sql.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		size := avatar.DefaultSize
+		if s := r.URL.Query().Get("size"); s != "" {
+			size, err = strconv.Atoi(s)
+			if err != nil || !avatar.IsValidSize(size) {
+				apierror.Error(w, r, http.StatusBadRequest, "Invalid size, allowed: 64, 128, 256")
+				return
+			}
+		}
+
+		updatedAt, found, err := database.GetAvatarUpdatedAt(db, userID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch avatar")
+			return
+		}
+		if !found || updatedAt == nil {
+			apierror.Error(w, r, http.StatusNotFound, "Avatar not found")
+			return
+		}
+
+		etag := fmt.Sprintf(`"%d-%d-%d"`, userID, updatedAt.Unix(), size)
+		if r.URL.Query().Get("v") == strconv.FormatInt(updatedAt.Unix(), 10) {
+			// Ссылка с текущей версией никогда не меняет содержимое. Устаревшая или выдуманная
+			// версия кэшируется как обычная ссылка, иначе браузер навсегда запомнит чужую картинку.
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age=300")
+		}
+		w.Header().Set("ETag", etag)
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		file, obj, err := store.Get(ctx, avatarKey(userID, size))
+		if errors.Is(err, storage.ErrNotFound) {
+			apierror.Error(w, r, http.StatusNotFound, "Avatar not found")
+			return
+		} else if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch avatar")
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+		w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+		io.Copy(w, file)
+	}
+}
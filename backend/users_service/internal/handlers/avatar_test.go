@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"users_service/internal/avatar"
+	"users_service/internal/storage"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+func TestGetAvatarCacheControl(t *testing.T) {
+	updatedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	current := fmt.Sprint(updatedAt.Unix())
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCache string
+	}{
+		{"ссылка с текущей версией", "?v=" + current, "public, max-age=31536000, immutable"},
+		{"устаревшая версия", "?v=" + fmt.Sprint(updatedAt.Unix()-60), "public, max-age=300"},
+		{"выдуманная версия", "?v=anything", "public, max-age=300"},
+		{"без версии", "", "public, max-age=300"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			mock.ExpectQuery("SELECT avatar_updated_at FROM users").
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"avatar_updated_at"}).AddRow(updatedAt))
+
+			store, err := storage.NewLocalDisk(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Put(context.Background(), avatarKey(1, avatar.DefaultSize), strings.NewReader("jpeg")); err != nil {
+				t.Fatal(err)
+			}
+
+			r := mux.NewRouter()
+			r.HandleFunc("/api/users/{id:[0-9]+}/avatar", GetAvatar(db, store)).Methods("GET")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1/avatar"+tt.query, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("ожидался код %d, получен %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("ожидался Cache-Control %q, получен %q", tt.wantCache, got)
+			}
+		})
+	}
+}
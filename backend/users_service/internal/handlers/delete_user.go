@@ -1,44 +1,77 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"blog/pkg/apierror"
+	"users_service/internal/avatar"
+	"users_service/internal/database"
+	"users_service/internal/storage"
+
 	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
-func DeleteUser(db *sql.DB) http.HandlerFunc {
+// DeleteUser запускает удаление аккаунта: данные users_service удаляются сразу,
+// а посты, лайки и уведомления удаляют потребители события user.deleted.
+// Ход удаления доступен по GET /api/users/{id}/deletion.
+func DeleteUser(db *sql.DB, store storage.Storage, consumers []string) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		userIDStr := vars["id"]
 		userID, err := strconv.Atoi(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
 			return
 		}
 
-		// Проверяем, существует ли пользователь
-		var exists bool
-		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
-		if err != nil {
-			http.Error(w, "Failed to check user existence", http.StatusInternalServerError)
+		eventID, found, err := database.StartAccountDeletion(db, userID, consumers)
+		if errors.Is(err, database.ErrDeletionInProgress) {
+			apierror.Error(w, r, http.StatusConflict, "Account deletion is already in progress")
+			return
+		} else if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to start account deletion")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to delete user")
 			return
 		}
-
-		if !exists {
-			http.Error(w, "User not found", http.StatusNotFound)
+		if !found {
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
 			return
 		}
 
-		_, err = db.Exec("DELETE FROM users WHERE id = $1", userID)
-		if err != nil {
-			http.Error(w, "Failed to delete user", http.StatusInternalServerError)
-			return
+		// Файлы аватара не участвуют в транзакции; ошибка не мешает удалению аккаунта
+		for _, size := range avatar.Sizes {
+			if err := store.Delete(context.Background(), avatarKey(userID, size)); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				logger.WithError(err).Warn("Users-Service: Failed to delete avatar")
+			}
 		}
 
+		// Если потребителей нет, аккаунт удаляется сразу
+		if len(consumers) == 0 {
+			if _, err := database.FinalizeAccountDeletion(db, eventID); err != nil {
+				logger.WithError(err).Error("Users-Service: Failed to finalize account deletion")
+			}
+		}
+
+		logger.WithFields(logrus.Fields{
+			"user_id":  userID,
+			"event_id": eventID,
+		}).Info("Users-Service: Account deletion started")
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"message":    "Account deletion started",
+			"status_url": fmt.Sprintf("/api/users/%d/deletion", userID),
+		})
 	}
 }
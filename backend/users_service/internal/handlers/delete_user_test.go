@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectAccountDeletion описывает транзакцию StartAccountDeletion для пользователя 1
+func expectAccountDeletion(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT deleted_at FROM users WHERE id = $1 FOR UPDATE")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(nil))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users")).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, table := range []string{"users_information", "user_recovery_codes", "user_totp", "personal_access_tokens", "user_relations"} {
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM " + table + " WHERE user_id = $1")).
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE data_exports")).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO event_outbox")).
+		WithArgs("user.deleted", 1, []byte(`{"user_id":1}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO event_deliveries (event_id, consumer) VALUES ($1, $2)")).
+		WithArgs(7, "posts-service").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestDeleteUserAuthorization(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     int
+		role       string
+		noToken    bool
+		expectSQL  bool
+		wantStatus int
+	}{
+		{name: "без токена", noToken: true, wantStatus: http.StatusUnauthorized},
+		{name: "чужой аккаунт", userID: 2, role: "user", wantStatus: http.StatusForbidden},
+		{name: "владелец", userID: 1, role: "user", expectSQL: true, wantStatus: http.StatusAccepted},
+		{name: "администратор", userID: 2, role: "admin", expectSQL: true, wantStatus: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.expectSQL {
+				expectAccountDeletion(s.mock)
+			}
+
+			token := ""
+			if !tt.noToken {
+				token = s.token(t, tt.userID, tt.role)
+			}
+			w := s.do(http.MethodDelete, "/api/users/1", token, "")
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDeleteUserAlreadyInProgress(t *testing.T) {
+	s := newTestServer(t)
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT deleted_at FROM users WHERE id = $1 FOR UPDATE")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(time.Now()))
+	s.mock.ExpectRollback()
+
+	w := s.do(http.MethodDelete, "/api/users/1", s.token(t, 1, "user"), "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("ожидался код %d, получен %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestGetAccountDeletionStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []string
+		wantStatus string
+	}{
+		{"все потребители обработали", []string{"done", "done"}, "completed"},
+		{"есть ожидающие", []string{"done", "pending"}, "in_progress"},
+		{"есть исчерпавшие попытки", []string{"failed", "pending"}, "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.mock.ExpectQuery(regexp.QuoteMeta("FROM event_outbox")).
+				WithArgs(1, "user.deleted").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "event_type", "user_id", "payload", "created_at"}).
+					AddRow(7, "user.deleted", 1, []byte(`{"user_id":1}`), time.Now()))
+			rows := sqlmock.NewRows([]string{"event_id", "consumer", "status", "attempts", "last_error", "next_attempt_at", "completed_at"})
+			for i, status := range tt.statuses {
+				rows.AddRow(7, []string{"notifications-service", "posts-service"}[i], status, 1, nil, time.Now(), nil)
+			}
+			s.mock.ExpectQuery(regexp.QuoteMeta("FROM event_deliveries")).WithArgs(7).WillReturnRows(rows)
+
+			w := s.do(http.MethodGet, "/api/users/1/deletion", s.token(t, 1, "user"), "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("ожидался код 200, получен %d: %s", w.Code, w.Body.String())
+			}
+			var resp AccountDeletionStatus
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.wantStatus || len(resp.Consumers) != len(tt.statuses) {
+				t.Errorf("получено %+v, ожидался статус %q", resp, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRetryAccountDeletionRequiresAdmin(t *testing.T) {
+	s := newTestServer(t)
+	w := s.do(http.MethodPost, "/api/users/1/deletion/retry", s.token(t, 1, "user"), "")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("ожидался код %d, получен %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+	"users_service/internal/export"
+	"users_service/internal/storage"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// DataExportStatus — состояние выгрузки; ссылка на скачивание есть только у готового архива
+type DataExportStatus struct {
+	*database.DataExport
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+// StartDataExport ставит в очередь сборку архива персональных данных пользователя
+func StartDataExport(db *sql.DB) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		raw := make([]byte, 16)
+		if _, err := rand.Read(raw); err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to start data export")
+			return
+		}
+
+		job, err := database.CreateDataExport(db, userID, hex.EncodeToString(raw))
+		if errors.Is(err, database.ErrExportInProgress) {
+			apierror.Error(w, r, http.StatusConflict, "A data export is already in progress")
+			return
+		} else if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to create data export")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to start data export")
+			return
+		}
+
+		logger.WithFields(logrus.Fields{"user_id": userID, "export_id": job.ID}).Info("Users-Service: Data export requested")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"id":         job.ID,
+			"status":     job.Status,
+			"status_url": fmt.Sprintf("/api/users/%d/export/%s", userID, job.ID),
+		})
+	}
+}
+
+// GetDataExport возвращает состояние выгрузки и, если архив готов, временную ссылку на скачивание
+func GetDataExport(db *sql.DB, links *export.LinkSigner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		userID, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		job, err := database.GetDataExport(db, userID, vars["exportId"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch data export")
+			return
+		}
+		if job == nil {
+			apierror.Error(w, r, http.StatusNotFound, "Data export not found")
+			return
+		}
+
+		status := DataExportStatus{DataExport: job}
+		now := time.Now()
+		if job.Status == database.ExportReady && job.ExpiresAt != nil && job.ExpiresAt.After(now) {
+			url, expiresAt := links.URL(userID, job.ID, now)
+			if expiresAt.After(*job.ExpiresAt) {
+				expiresAt = *job.ExpiresAt
+			}
+			status.DownloadURL = url
+			status.DownloadURLExpiresAt = &expiresAt
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+// DownloadDataExport отдаёт архив по подписанной ссылке; токен не требуется
+func DownloadDataExport(db *sql.DB, store storage.Storage, links *export.LinkSigner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		userID, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		exportID := vars["exportId"]
+
+		q := r.URL.Query()
+		if !links.Verify(userID, exportID, q.Get("expires"), q.Get("signature"), time.Now()) {
+			apierror.Error(w, r, http.StatusForbidden, "Download link is invalid or has expired")
+			return
+		}
+
+		job, err := database.GetDataExport(db, userID, exportID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch data export")
+			return
+		}
+		if job == nil || job.Status != database.ExportReady || job.StorageKey == nil ||
+			job.ExpiresAt == nil || job.ExpiresAt.Before(time.Now()) {
+			apierror.Error(w, r, http.StatusNotFound, "Data export not found")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+		defer cancel()
+		file, obj, err := store.Get(ctx, *job.StorageKey)
+		if errors.Is(err, storage.ErrNotFound) {
+			apierror.Error(w, r, http.StatusNotFound, "Data export not found")
+			return
+		} else if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch data export")
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="blog-export-%d-%s.zip"`, userID, job.ID[:8]))
+		w.Header().Set("Cache-Control", "private, no-store")
+		io.Copy(w, file)
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"users_service/internal/export"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var dataExportColumns = []string{"id", "user_id", "status", "error", "storage_key", "size_bytes", "created_at", "completed_at", "expires_at"}
+
+const testExportID = "0123456789abcdef0123456789abcdef"
+
+func TestStartDataExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		callerID   int
+		inProgress bool
+		expectSQL  bool
+		wantStatus int
+	}{
+		{name: "выгрузка поставлена в очередь", callerID: 1, expectSQL: true, wantStatus: http.StatusAccepted},
+		{name: "выгрузка уже выполняется", callerID: 1, expectSQL: true, inProgress: true, wantStatus: http.StatusConflict},
+		{name: "чужие данные", callerID: 2, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.expectSQL {
+				rows := sqlmock.NewRows(dataExportColumns)
+				if !tt.inProgress {
+					rows.AddRow(testExportID, 1, "pending", nil, nil, nil, time.Now(), nil, nil)
+				}
+				s.mock.ExpectQuery("INSERT INTO data_exports").WithArgs(sqlmock.AnyArg(), 1).WillReturnRows(rows)
+			}
+
+			w := s.do(http.MethodPost, "/api/users/1/export", s.token(t, tt.callerID, "user"), "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusAccepted {
+				var resp map[string]string
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp["status_url"] != "/api/users/1/export/"+testExportID {
+					t.Errorf("неверная ссылка на статус: %v", resp)
+				}
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGetDataExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		expiresAt  interface{}
+		found      bool
+		wantStatus int
+		wantLink   bool
+	}{
+		{name: "готовый архив со ссылкой", status: "ready", expiresAt: time.Now().Add(time.Hour), found: true, wantStatus: http.StatusOK, wantLink: true},
+		{name: "архив ещё собирается", status: "running", found: true, wantStatus: http.StatusOK},
+		{name: "срок хранения истёк", status: "ready", expiresAt: time.Now().Add(-time.Minute), found: true, wantStatus: http.StatusOK},
+		{name: "выгрузка не найдена", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			rows := sqlmock.NewRows(dataExportColumns)
+			if tt.found {
+				rows.AddRow(testExportID, 1, tt.status, nil, "1/"+testExportID+".zip", 42, time.Now(), nil, tt.expiresAt)
+			}
+			s.mock.ExpectQuery(regexp.QuoteMeta("FROM data_exports WHERE id = $1 AND user_id = $2")).WithArgs(testExportID, 1).WillReturnRows(rows)
+
+			w := s.do(http.MethodGet, "/api/users/1/export/"+testExportID, s.token(t, 1, "user"), "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			var resp DataExportStatus
+			json.NewDecoder(w.Body).Decode(&resp)
+			if tt.wantLink != (resp.DownloadURL != "") {
+				t.Errorf("ссылка на скачивание: %q", resp.DownloadURL)
+			}
+			if strings.Contains(w.Body.String(), "storage_key") {
+				t.Error("ключ хранилища не должен попадать в ответ")
+			}
+		})
+	}
+}
+
+func TestDownloadDataExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		link       func(s *testServer) string
+		status     string
+		expiresAt  time.Time
+		expectSQL  bool
+		wantStatus int
+	}{
+		{
+			name:       "скачивание по подписанной ссылке",
+			link:       func(s *testServer) string { link, _ := s.links.URL(1, testExportID, time.Now()); return link },
+			status:     "ready",
+			expiresAt:  time.Now().Add(time.Hour),
+			expectSQL:  true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "подделанная подпись",
+			link: func(s *testServer) string {
+				return "/api/users/1/export/" + testExportID + "/download?expires=9999999999&signature=" + strings.Repeat("0", 64)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "ссылка на чужую выгрузку",
+			link: func(s *testServer) string {
+				link, _ := s.links.URL(1, testExportID, time.Now())
+				return strings.Replace(link, "/api/users/1/", "/api/users/2/", 1)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "срок хранения истёк",
+			link:       func(s *testServer) string { link, _ := s.links.URL(1, testExportID, time.Now()); return link },
+			status:     "ready",
+			expiresAt:  time.Now().Add(-time.Minute),
+			expectSQL:  true,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "архив ещё не готов",
+			link:       func(s *testServer) string { link, _ := s.links.URL(1, testExportID, time.Now()); return link },
+			status:     "running",
+			expiresAt:  time.Now().Add(time.Hour),
+			expectSQL:  true,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			key := export.StorageKey(1, testExportID)
+			if err := s.store.Put(context.Background(), key, strings.NewReader("PK archive")); err != nil {
+				t.Fatal(err)
+			}
+			if tt.expectSQL {
+				s.mock.ExpectQuery(regexp.QuoteMeta("FROM data_exports WHERE id = $1 AND user_id = $2")).
+					WithArgs(testExportID, 1).
+					WillReturnRows(sqlmock.NewRows(dataExportColumns).AddRow(testExportID, 1, tt.status, nil, key, 10, time.Now(), nil, tt.expiresAt))
+			}
+
+			w := s.do(http.MethodGet, tt.link(s), "", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				if w.Body.String() != "PK archive" || w.Header().Get("Content-Type") != "application/zip" {
+					t.Errorf("неожиданный архив: %s %q", w.Header().Get("Content-Type"), w.Body.String())
+				}
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+
+	"blog/pkg/apierror"
 )
 
 func GetUserByID(db *sql.DB) http.HandlerFunc {
@@ -15,27 +18,30 @@ func GetUserByID(db *sql.DB) http.HandlerFunc {
 		userIDStr := vars["id"]
 		userID, err := strconv.Atoi(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
 			return
 		}
 
 		var user struct {
-			ID           int    `json:"id"`
-			Username     string `json:"username"`
-			Email        string `json:"email"`
-			PasswordHash string `json:"-"`
+			ID        int     `json:"id"`
+			Username  string  `json:"username"`
+			Email     string  `json:"email"`
+			AvatarURL *string `json:"avatar_url"`
 		}
+		var avatarUpdatedAt *time.Time
 
-		err = db.QueryRow("SELECT id, username, email, password_hash FROM users WHERE id = $1", userID).
-			Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash)
+		err = db.QueryRow("SELECT id, username, email, avatar_updated_at FROM users WHERE id = $1 AND deleted_at IS NULL", userID).
+			Scan(&user.ID, &user.Username, &user.Email, &avatarUpdatedAt)
 		if err == sql.ErrNoRows {
-			http.Error(w, "User not found", http.StatusNotFound)
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
 			return
 		} else if err != nil {
-			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch user")
 			return
 		}
 
+		user.AvatarURL = avatarURL(user.ID, avatarUpdatedAt)
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(user)
 	}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"blog/pkg/apierror"
 	"users_service/internal/database"
 
 	"github.com/sirupsen/logrus"
@@ -19,7 +20,7 @@ func GetUserByEmail(db *sql.DB) http.HandlerFunc {
 		email := r.URL.Query().Get("email")
 		if email == "" {
 			logger.Warn("Request missing 'email' parameter")
-			http.Error(w, "Email is required", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Email is required")
 			return
 		}
 
@@ -28,13 +29,13 @@ func GetUserByEmail(db *sql.DB) http.HandlerFunc {
 		user, err := database.GetUserByEmail(db, email)
 		if err != nil {
 			logger.WithError(err).Error("Database query failed")
-			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch user")
 			return
 		}
 
 		if user == nil {
 			logger.WithField("email", email).Warn("User not found")
-			http.Error(w, "User not found", http.StatusNotFound)
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
 			return
 		}
 
@@ -44,10 +45,15 @@ func GetUserByEmail(db *sql.DB) http.HandlerFunc {
 			"email":    user.Email,
 		}).Info("User found")
 
+		// Хэш пароля и служебные поля не возвращаются
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(user); err != nil {
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+		}); err != nil {
 			logger.WithError(err).Error("Failed to encode response")
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to encode response")
 		}
 	}
 }
@@ -4,30 +4,31 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+
+	"blog/pkg/apierror"
 )
 
 func GetUserByUsername(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		username := r.URL.Query().Get("username")
 		if username == "" {
-			http.Error(w, "Username is required", http.StatusBadRequest)
+			apierror.Error(w, r, http.StatusBadRequest, "Username is required")
 			return
 		}
 
 		var user struct {
-			ID           int    `json:"id"`
-			Username     string `json:"username"`
-			Email        string `json:"email"`
-			PasswordHash string `json:"-"`
+			ID       int    `json:"id"`
+			Username string `json:"username"`
+			Email    string `json:"email"`
 		}
 
-		err := db.QueryRow("SELECT id, username, email, password_hash FROM users WHERE username = $1", username).
-			Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash)
+		err := db.QueryRow("SELECT id, username, email FROM users WHERE username = $1 AND deleted_at IS NULL", username).
+			Scan(&user.ID, &user.Username, &user.Email)
 		if err == sql.ErrNoRows {
-			http.Error(w, "User not found", http.StatusNotFound)
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
 			return
 		} else if err != nil {
-			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch user")
 			return
 		}
 
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/pkg/jwks"
+	"blog/pkg/openapi"
+	"users_service/internal/apispec"
+	"users_service/internal/database"
+	"users_service/internal/export"
+	"users_service/internal/middlewares"
+	"users_service/internal/storage"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+type staticKeys map[string]*rsa.PublicKey
+
+func (s staticKeys) Key(kid string) (*rsa.PublicKey, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	return nil, jwks.ErrUnknownKey
+}
+
+// testServer собирает маршруты изменения аккаунта так же, как cmd/main.go.
+// Запросы и ответы проверяются по OpenAPI-документу, поэтому расхождение
+// обработчика с документом роняет тест кодом 500.
+type testServer struct {
+	router http.Handler
+	mock   sqlmock.Sqlmock
+	key    *rsa.PrivateKey
+	store  storage.Storage
+	links  *export.LinkSigner
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &jwks.Verifier{
+		Keys:     staticKeys{"test": &key.PublicKey},
+		Issuer:   "auth-service",
+		Audience: "blog-api",
+	}
+
+	store, err := storage.NewLocalDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := &export.LinkSigner{Secret: []byte(strings.Repeat("s", 32)), TTL: 15 * time.Minute}
+
+	r := mux.NewRouter()
+	registerAccountRoutes(r, db, store, links, middlewares.AuthMiddleware(verifier))
+
+	doc, err := apispec.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator.ValidateResponses = true
+
+	return &testServer{router: validator.Middleware(r), mock: mock, key: key, store: store, links: links}
+}
+
+func registerAccountRoutes(r *mux.Router, db *sql.DB, store storage.Storage, links *export.LinkSigner, authenticated func(http.Handler) http.Handler) {
+	// Подпись сервиса для регистрации проверяется в тестах serviceauth
+	r.HandleFunc("/api/users/register", RegisterUser(db)).Methods("POST")
+	r.Handle("/api/users/{id:[0-9]+}", authenticated(middlewares.RequireOwnerOrAdmin(UpdateUser(db)))).Methods("PATCH")
+	r.Handle("/api/users/{id:[0-9]+}/password", authenticated(middlewares.RequireOwnerOrAdmin(UpdateUserPassword(db)))).Methods("PATCH")
+	r.Handle("/api/users/{id:[0-9]+}", authenticated(middlewares.RequireOwnerOrAdmin(DeleteUser(db, store, []string{"posts-service"})))).Methods("DELETE")
+	r.Handle("/api/users/{id:[0-9]+}/deletion", authenticated(middlewares.RequireOwnerOrAdmin(GetAccountDeletion(db)))).Methods("GET")
+	r.Handle("/api/users/{id:[0-9]+}/deletion/retry", authenticated(middlewares.RequireAdmin(RetryAccountDeletion(db)))).Methods("POST")
+	r.Handle("/api/users/{id:[0-9]+}/profile", authenticated(middlewares.RequireOwnerOrAdmin(UpdateProfile(db)))).Methods("PUT")
+	r.Handle("/api/users/{id:[0-9]+}/blocks/{targetId:[0-9]+}", authenticated(middlewares.RequireOwner(AddRelation(db, database.RelationBlock)))).Methods("PUT")
+	r.Handle("/api/users/{id:[0-9]+}/blocks/{targetId:[0-9]+}", authenticated(middlewares.RequireOwner(RemoveRelation(db, database.RelationBlock)))).Methods("DELETE")
+	r.Handle("/api/users/{id:[0-9]+}/following/{targetId:[0-9]+}", authenticated(middlewares.RequireOwner(AddRelation(db, database.RelationFollow)))).Methods("PUT")
+	r.Handle("/api/users/{id:[0-9]+}/export", authenticated(middlewares.RequireOwner(StartDataExport(db)))).Methods("POST")
+	r.Handle("/api/users/{id:[0-9]+}/export/{exportId:[0-9a-f]{32}}", authenticated(middlewares.RequireOwner(GetDataExport(db, links)))).Methods("GET")
+	r.HandleFunc("/api/users/{id:[0-9]+}/export/{exportId:[0-9a-f]{32}}/download", DownloadDataExport(db, store, links)).Methods("GET")
+	r.Handle("/api/users/{id:[0-9]+}/tokens", authenticated(middlewares.RequireOwner(CreatePersonalAccessToken(db)))).Methods("POST")
+	r.Handle("/api/users/{id:[0-9]+}/tokens", authenticated(middlewares.RequireOwner(ListPersonalAccessTokens(db)))).Methods("GET")
+	r.Handle("/api/users/{id:[0-9]+}/tokens/{tokenId:[0-9]+}", authenticated(middlewares.RequireOwner(RevokePersonalAccessToken(db)))).Methods("DELETE")
+}
+
+// token выпускает JWT так же, как auth_service
+func (s *testServer) token(t *testing.T, userID int, role string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"user_id": userID,
+		"role":    role,
+		"iss":     "auth-service",
+		"aud":     "blog-api",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func (s *testServer) do(method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
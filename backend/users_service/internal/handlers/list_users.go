@@ -5,38 +5,33 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+
+	"blog/pkg/apierror"
+	"users_service/internal/database"
 )
 
+// ListUsers отдаёт список пользователей в той же публичной проекции, что и поиск: email не раскрывается
 func ListUsers(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		rows, err := db.Query("SELECT id, username, email FROM users ORDER BY id ASC")
+		users, err := database.ListUsers(db)
 		if err != nil {
-			log.Println("ListUsers: Query error:", err)
-			http.Error(w, "Failed to list users", http.StatusInternalServerError)
+			log.Println("ListUsers:", err)
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to list users")
 			return
 		}
-		defer rows.Close()
 
-		var users []map[string]interface{}
-		for rows.Next() {
-			var (
-				id       int
-				username string
-				email    string
-			)
-			if err := rows.Scan(&id, &username, &email); err != nil {
-				log.Println("ListUsers: Scan error:", err)
-				http.Error(w, "Failed to parse users", http.StatusInternalServerError)
-				return
-			}
-			users = append(users, map[string]interface{}{
-				"id":       id,
-				"username": username,
-				"email":    email,
+		items := make([]UserSearchItem, 0, len(users))
+		for _, u := range users {
+			items = append(items, UserSearchItem{
+				ID:         u.ID,
+				Username:   u.Username,
+				FirstName:  u.FirstName,
+				SecondName: u.SecondName,
+				AvatarURL:  avatarURL(u.ID, u.AvatarUpdatedAt),
 			})
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(users)
+		json.NewEncoder(w).Encode(items)
 	}
 }
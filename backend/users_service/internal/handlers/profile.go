@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+	"users_service/internal/validation"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Ограничения полей профиля
+const (
+	maxNameLength     = 100
+	maxBioLength      = 500
+	maxWebsiteLength  = 255
+	maxLocationLength = 100
+)
+
+// UpdateProfileRequest представляет запрос на обновление профиля
+type UpdateProfileRequest struct {
+	FirstName  string  `json:"first_name"`
+	SecondName string  `json:"second_name"`
+	Birthdate  *string `json:"birthdate"`
+	Bio        string  `json:"bio"`
+	Website    string  `json:"website"`
+	Location   string  `json:"location"`
+}
+
+// PublicProfile — данные профиля, доступные всем, вместе со статистикой постов
+type PublicProfile struct {
+	ID            int    `json:"id"`
+	Username      string `json:"username"`
+	FirstName     string `json:"first_name"`
+	SecondName    string `json:"second_name"`
+	Bio           string `json:"bio"`
+	Website       string `json:"website"`
+	Location      string `json:"location"`
+	PostCount     *int   `json:"post_count"`
+	LikesReceived *int   `json:"likes_received"`
+	LikesGiven    *int   `json:"likes_given"`
+}
+
+// validate очищает поля запроса и возвращает ошибки по каждому некорректному полю
+func (req *UpdateProfileRequest) validate() []apierror.FieldError {
+	req.FirstName = strings.TrimSpace(req.FirstName)
+	req.SecondName = strings.TrimSpace(req.SecondName)
+	req.Bio = strings.TrimSpace(req.Bio)
+	req.Website = strings.TrimSpace(req.Website)
+	req.Location = strings.TrimSpace(req.Location)
+
+	var v validation.Validator
+
+	names := []struct{ field, value string }{
+		{"first_name", req.FirstName},
+		{"second_name", req.SecondName},
+	}
+	for _, name := range names {
+		field, value := name.field, name.value
+		if utf8.RuneCountInString(value) > maxNameLength {
+			v.Add(field, "too_long", fmt.Sprintf("%s must be at most %d characters", field, maxNameLength))
+			continue
+		}
+		for _, r := range value {
+			if !unicode.IsLetter(r) && r != ' ' && r != '-' && r != '\'' {
+				v.Add(field, "invalid_format", fmt.Sprintf("%s may contain only letters, spaces, hyphens and apostrophes", field))
+				break
+			}
+		}
+	}
+
+	if req.Birthdate != nil {
+		trimmed := strings.TrimSpace(*req.Birthdate)
+		if trimmed == "" {
+			req.Birthdate = nil
+		} else {
+			date, err := time.Parse("2006-01-02", trimmed)
+			if err != nil {
+				v.Add("birthdate", "invalid_format", "birthdate must be in YYYY-MM-DD format")
+			} else if date.After(time.Now()) || date.Year() < 1900 {
+				v.Add("birthdate", "out_of_range", "birthdate is out of range")
+			}
+			req.Birthdate = &trimmed
+		}
+	}
+
+	if utf8.RuneCountInString(req.Bio) > maxBioLength {
+		v.Add("bio", "too_long", fmt.Sprintf("bio must be at most %d characters", maxBioLength))
+	}
+
+	if req.Website != "" {
+		u, err := url.Parse(req.Website)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Add("website", "invalid_format", "website must be an http or https URL")
+		} else if len(req.Website) > maxWebsiteLength {
+			v.Add("website", "too_long", fmt.Sprintf("website must be at most %d characters", maxWebsiteLength))
+		}
+	}
+
+	if utf8.RuneCountInString(req.Location) > maxLocationLength {
+		v.Add("location", "too_long", fmt.Sprintf("location must be at most %d characters", maxLocationLength))
+	}
+	return v.Errors()
+}
+
+// GetProfile возвращает полный профиль пользователя (включая дату рождения)
+func GetProfile(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		profile, err := database.GetProfile(db, userID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch profile")
+			return
+		}
+		if profile == nil {
+			// Профиль ещё не заполнен: возвращаем пустой
+			profile = &database.Profile{UserID: userID}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(profile)
+	}
+}
+
+// UpdateProfile проверяет и сохраняет профиль пользователя
+func UpdateProfile(db *sql.DB) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid user ID")
+			return
+		}
+
+		var req UpdateProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request payload")
+			return
+		}
+		if fields := req.validate(); len(fields) > 0 {
+			apierror.Validation(w, r, fields)
+			return
+		}
+
+		user, err := database.GetUserByID(db, userID)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to fetch user")
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch user")
+			return
+		}
+		if user == nil {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+			return
+		}
+
+		profile := &database.Profile{
+			UserID:     userID,
+			FirstName:  req.FirstName,
+			SecondName: req.SecondName,
+			Birthdate:  req.Birthdate,
+			Bio:        req.Bio,
+			Website:    req.Website,
+			Location:   req.Location,
+		}
+		if err := database.SaveProfile(db, profile); err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to save profile")
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save profile")
+			return
+		}
+
+		logger.WithField("user_id", userID).Info("Users-Service: Profile updated")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(profile)
+	}
+}
+
+// PostsService — адрес posts_service и клиент, подписывающий запросы к нему
+type PostsService struct {
+	URL    string
+	Client *http.Client
+}
+
+// GetPublicProfile возвращает публичный профиль пользователя по username
+// вместе с количеством постов и лайков из posts_service
+func GetPublicProfile(db *sql.DB, posts PostsService) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := mux.Vars(r)["username"]
+
+		var user struct {
+			ID       int
+			Username string
+		}
+		err := db.QueryRow("SELECT id, username FROM users WHERE username = $1 AND deleted_at IS NULL", username).
+			Scan(&user.ID, &user.Username)
+		if err == sql.ErrNoRows {
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
+			return
+		} else if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch user")
+			return
+		}
+
+		profile, err := database.GetProfile(db, user.ID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch profile")
+			return
+		}
+		if profile == nil {
+			profile = &database.Profile{}
+		}
+
+		resp := PublicProfile{
+			ID:         user.ID,
+			Username:   user.Username,
+			FirstName:  profile.FirstName,
+			SecondName: profile.SecondName,
+			Bio:        profile.Bio,
+			Website:    profile.Website,
+			Location:   profile.Location,
+		}
+
+		// Статистика не критична: при недоступности posts_service отдаём профиль без неё
+		if stats, err := fetchUserStats(posts, user.ID); err != nil {
+			logger.WithError(err).Warn("Users-Service: Failed to fetch user stats")
+		} else {
+			resp.PostCount = &stats.PostCount
+			resp.LikesReceived = &stats.LikesReceived
+			resp.LikesGiven = &stats.LikesGiven
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+type userStats struct {
+	PostCount     int `json:"post_count"`
+	LikesReceived int `json:"likes_received"`
+	LikesGiven    int `json:"likes_given"`
+}
+
+// fetchUserStats запрашивает статистику постов и лайков пользователя у posts_service
+func fetchUserStats(posts PostsService, userID int) (*userStats, error) {
+	if posts.URL == "" {
+		return nil, fmt.Errorf("posts service URL is not configured")
+	}
+
+	resp, err := posts.Client.Get(fmt.Sprintf("%s/internal/users/%d/stats", posts.URL, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch stats: status %d", resp.StatusCode)
+	}
+
+	var stats userStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to decode stats: %w", err)
+	}
+	return &stats, nil
+}
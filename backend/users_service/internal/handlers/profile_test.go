@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpdateProfileRequestValidate(t *testing.T) {
+	date := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		req     UpdateProfileRequest
+		wantErr bool
+	}{
+		{"пустой профиль", UpdateProfileRequest{}, false},
+		{"заполненный профиль", UpdateProfileRequest{FirstName: "Анна-Мария", SecondName: "O'Neil", Birthdate: date("1990-05-17"), Website: "https://example.com", Location: "Москва"}, false},
+		{"цифры в имени", UpdateProfileRequest{FirstName: "Anna1"}, true},
+		{"неверный формат даты", UpdateProfileRequest{Birthdate: date("17.05.1990")}, true},
+		{"дата в будущем", UpdateProfileRequest{Birthdate: date("2999-01-01")}, true},
+		{"сайт без схемы", UpdateProfileRequest{Website: "example.com"}, true},
+		{"сайт с javascript:", UpdateProfileRequest{Website: "javascript:alert(1)"}, true},
+		{"слишком длинное bio", UpdateProfileRequest{Bio: strings.Repeat("a", maxBioLength+1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := tt.req.validate()
+			if (len(fields) > 0) != tt.wantErr {
+				t.Errorf("validate() = %v, ожидалась ошибка: %v", fields, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileAuthorization(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     int
+		wantStatus int
+	}{
+		{"чужой профиль", 2, http.StatusForbidden},
+		{"свой профиль", 1, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.wantStatus == http.StatusOK {
+				s.mock.ExpectQuery("SELECT users.id, users.username").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "enabled"}).
+						AddRow(1, "user", "user@example.com", "hash", "user", false))
+				s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users_information")).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+
+			w := s.do(http.MethodPut, "/api/users/1/profile", s.token(t, tt.userID, "user"), `{"first_name":"Anna","bio":"hi"}`)
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"strings"
 
+	"blog/pkg/apierror"
 	"users_service/internal/database"
+	"users_service/internal/validation"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
@@ -31,7 +33,7 @@ func RegisterUser(db *sql.DB) http.HandlerFunc {
 		var req RegisterRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			logger.WithError(err).Warn("Users-Service: Failed to decode request payload")
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request payload")
 			return
 		}
 
@@ -41,9 +43,13 @@ func RegisterUser(db *sql.DB) http.HandlerFunc {
 		req.Password = strings.TrimSpace(req.Password)
 
 		// Валидация данных
-		if req.Username == "" || req.Email == "" || req.Password == "" {
-			logger.Warn("Users-Service: Validation error - all fields are required")
-			http.Error(w, "All fields are required", http.StatusBadRequest)
+		var v validation.Validator
+		v.Username("username", req.Username)
+		v.Email("email", req.Email)
+		v.Password("password", req.Password, req.Username, req.Email)
+		if !v.Valid() {
+			logger.WithField("fields", v.Errors()).Warn("Users-Service: Registration validation failed")
+			apierror.Validation(w, r, v.Errors())
 			return
 		}
 
@@ -51,19 +57,23 @@ func RegisterUser(db *sql.DB) http.HandlerFunc {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 		if err != nil {
 			logger.WithError(err).Error("Users-Service: Failed to hash password")
-			http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
 			return
 		}
 
 		// Сохранение пользователя в базе данных
 		err = database.SaveUser(db, req.Username, req.Email, string(hashedPassword))
-		if err != nil {
+		if field, ok := database.UniqueViolationField(err); ok {
+			logger.WithField("field", field).Warn("Users-Service: Registration conflict")
+			writeConflict(w, r, field)
+			return
+		} else if err != nil {
 			logger.WithFields(logrus.Fields{
 				"username": req.Username,
 				"email":    req.Email,
 				"error":    err.Error(),
 			}).Error("Users-Service: Failed to register user in the database")
-			http.Error(w, "Failed to register user", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to register user")
 			return
 		}
 
@@ -73,9 +83,22 @@ func RegisterUser(db *sql.DB) http.HandlerFunc {
 			"email":    req.Email,
 		}).Info("Users-Service: User successfully registered")
 
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		if err := json.NewEncoder(w).Encode(map[string]string{"message": "Registration successful"}); err != nil {
 			logger.WithError(err).Error("Users-Service: Failed to send response to client")
 		}
 	}
 }
+
+// writeConflict отвечает 409, указывая занятое поле
+func writeConflict(w http.ResponseWriter, r *http.Request, field string) {
+	switch field {
+	case "username":
+		apierror.Conflict(w, r, field, "Username is already taken")
+	case "email":
+		apierror.Conflict(w, r, field, "Email is already registered")
+	default:
+		apierror.Write(w, r, http.StatusConflict, apierror.CodeConflict, "User already exists")
+	}
+}
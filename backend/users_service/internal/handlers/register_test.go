@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"blog/pkg/apierror"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestRegisterUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		saveErr    error
+		expectSave bool
+		wantStatus int
+		wantCode   string
+		wantFields []string
+	}{
+		{
+			name:       "успешная регистрация",
+			body:       `{"username":"alice","email":"alice@example.com","password":"correct-horse"}`,
+			expectSave: true,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "некорректный JSON",
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidRequest,
+		},
+		{
+			name:       "все поля некорректны",
+			body:       `{"username":"1a","email":"not-an-email","password":"short"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeValidationFailed,
+			wantFields: []string{"username", "email", "password"},
+		},
+		{
+			name:       "зарезервированное имя",
+			body:       `{"username":"Admin","email":"admin@example.com","password":"correct-horse"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeValidationFailed,
+			wantFields: []string{"username"},
+		},
+		{
+			name:       "занятое имя",
+			body:       `{"username":"alice","email":"alice@example.com","password":"correct-horse"}`,
+			expectSave: true,
+			saveErr:    &pq.Error{Code: "23505", Constraint: "users_username_key"},
+			wantStatus: http.StatusConflict,
+			wantCode:   apierror.CodeConflict,
+			wantFields: []string{"username"},
+		},
+		{
+			name:       "занятая почта",
+			body:       `{"username":"alice","email":"alice@example.com","password":"correct-horse"}`,
+			expectSave: true,
+			saveErr:    &pq.Error{Code: "23505", Constraint: "users_email_key"},
+			wantStatus: http.StatusConflict,
+			wantCode:   apierror.CodeConflict,
+			wantFields: []string{"email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.expectSave {
+				exec := s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users (username, email, password_hash)")).
+					WithArgs("alice", "alice@example.com", sqlmock.AnyArg())
+				if tt.saveErr != nil {
+					exec.WillReturnError(tt.saveErr)
+				} else {
+					exec.WillReturnResult(sqlmock.NewResult(1, 1))
+				}
+			}
+
+			w := s.do(http.MethodPost, "/api/users/register", "", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+
+			var resp apierror.Problem
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("ответ не в формате JSON-ошибки: %v", err)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("ожидался код ошибки %q, получен %q", tt.wantCode, resp.Code)
+			}
+			if len(resp.Fields) != len(tt.wantFields) {
+				t.Fatalf("ожидались поля %v, получено %+v", tt.wantFields, resp.Fields)
+			}
+			for i, field := range tt.wantFields {
+				if resp.Fields[i].Field != field {
+					t.Errorf("поле %d: ожидалось %q, получено %q", i, field, resp.Fields[i].Field)
+				}
+			}
+		})
+	}
+}
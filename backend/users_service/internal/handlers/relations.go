@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// parseRelationVars извлекает ID владельца и цели отношения из маршрута
+func parseRelationVars(w http.ResponseWriter, r *http.Request) (userID, targetID int, ok bool) {
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+		return 0, 0, false
+	}
+	targetID, err = strconv.Atoi(vars["targetId"])
+	if err != nil {
+		apierror.Error(w, r, http.StatusBadRequest, "Invalid target user ID")
+		return 0, 0, false
+	}
+	if userID == targetID {
+		apierror.Error(w, r, http.StatusBadRequest, "You cannot block, mute or follow yourself")
+		return 0, 0, false
+	}
+	return userID, targetID, true
+}
+
+// AddRelation блокирует, скрывает или подписывается (kind) на другого пользователя
+func AddRelation(db *sql.DB, kind string) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, targetID, ok := parseRelationVars(w, r)
+		if !ok {
+			return
+		}
+
+		target, err := database.GetUserByID(db, targetID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch user")
+			return
+		}
+		if target == nil {
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+
+		// Подписаться нельзя, если кто-то из пользователей заблокировал другого
+		if kind == database.RelationFollow {
+			blocked, err := database.HasBlock(db, userID, targetID)
+			if err != nil {
+				logger.WithError(err).Error("Users-Service: Failed to check block")
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to update relation")
+				return
+			}
+			if blocked {
+				apierror.Error(w, r, http.StatusForbidden, "You cannot follow this user")
+				return
+			}
+		}
+
+		if err := database.AddRelation(db, userID, targetID, kind); err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to add relation")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to update relation")
+			return
+		}
+
+		logger.WithFields(logrus.Fields{
+			"user_id":   userID,
+			"target_id": targetID,
+			"kind":      kind,
+		}).Info("Users-Service: Relation added")
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RemoveRelation снимает блокировку, скрытие или подписку (kind)
+func RemoveRelation(db *sql.DB, kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, targetID, ok := parseRelationVars(w, r)
+		if !ok {
+			return
+		}
+
+		removed, err := database.RemoveRelation(db, userID, targetID, kind)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to update relation")
+			return
+		}
+		if !removed {
+			apierror.Error(w, r, http.StatusNotFound, "Relation not found")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListRelations возвращает заблокированных, скрытых пользователей или подписки (kind)
+func ListRelations(db *sql.DB, kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		users, err := database.ListRelations(db, userID, kind)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to list relations")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(users)
+	}
+}
+
+// ListFollowers возвращает подписчиков пользователя
+func ListFollowers(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		users, err := database.ListRelatedBy(db, userID, database.RelationFollow)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to list relations")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(users)
+	}
+}
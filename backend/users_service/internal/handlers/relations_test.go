@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBlockUser(t *testing.T) {
+	userColumns := []string{"id", "username", "email", "password_hash", "role", "enabled"}
+
+	tests := []struct {
+		name         string
+		callerID     int
+		path         string
+		targetExists bool
+		expectLookup bool
+		expectInsert bool
+		wantStatus   int
+	}{
+		{name: "блокировка", callerID: 1, path: "/api/users/1/blocks/2", expectLookup: true, targetExists: true, expectInsert: true, wantStatus: http.StatusNoContent},
+		{name: "несуществующий пользователь", callerID: 1, path: "/api/users/1/blocks/2", expectLookup: true, wantStatus: http.StatusNotFound},
+		{name: "блокировка самого себя", callerID: 1, path: "/api/users/1/blocks/1", wantStatus: http.StatusBadRequest},
+		{name: "чужой список", callerID: 3, path: "/api/users/1/blocks/2", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.expectLookup {
+				rows := sqlmock.NewRows(userColumns)
+				if tt.targetExists {
+					rows.AddRow(2, "bob", "bob@example.com", "hash", "user", false)
+				}
+				s.mock.ExpectQuery("SELECT users.id, users.username").WithArgs(2).WillReturnRows(rows)
+			}
+			if tt.expectInsert {
+				s.mock.ExpectBegin()
+				s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO user_relations (user_id, target_id, kind)")).
+					WithArgs(1, 2, "block").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				// Блокировка отменяет подписки в обе стороны
+				s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_relations")).
+					WithArgs(1, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				s.mock.ExpectCommit()
+			}
+
+			w := s.do(http.MethodPut, tt.path, s.token(t, tt.callerID, "user"), "")
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestUnblockUnknownRelation(t *testing.T) {
+	s := newTestServer(t)
+	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_relations")).
+		WithArgs(1, 2, "block").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	w := s.do(http.MethodDelete, "/api/users/1/blocks/2", s.token(t, 1, "user"), "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("ожидался код %d, получен %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestFollowUser(t *testing.T) {
+	userColumns := []string{"id", "username", "email", "password_hash", "role", "enabled"}
+
+	tests := []struct {
+		name         string
+		blocked      bool
+		expectInsert bool
+		wantStatus   int
+	}{
+		{name: "подписка", expectInsert: true, wantStatus: http.StatusNoContent},
+		{name: "блокировка между пользователями", blocked: true, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.mock.ExpectQuery("SELECT users.id, users.username").WithArgs(2).
+				WillReturnRows(sqlmock.NewRows(userColumns).AddRow(2, "bob", "bob@example.com", "hash", "user", false))
+			s.mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(")).WithArgs(1, 2).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.blocked))
+			if tt.expectInsert {
+				s.mock.ExpectBegin()
+				s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO user_relations (user_id, target_id, kind)")).
+					WithArgs(1, 2, "follow").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				s.mock.ExpectCommit()
+			}
+
+			w := s.do(http.MethodPut, "/api/users/1/following/2", s.token(t, 1, "user"), "")
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+	"users_service/internal/validation"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Ограничения поиска пользователей
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+)
+
+// UserSearchItem — элемент выдачи поиска; email не раскрывается
+type UserSearchItem struct {
+	ID         int     `json:"id"`
+	Username   string  `json:"username"`
+	FirstName  string  `json:"first_name"`
+	SecondName string  `json:"second_name"`
+	AvatarURL  *string `json:"avatar_url"`
+}
+
+// SearchUsers обрабатывает GET /api/users/search?q=&limit= для автодополнения @упоминаний и поиска людей
+func SearchUsers(db *sql.DB) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Допускаем ввод вида "@ali" из поля упоминания
+		q := strings.TrimPrefix(strings.TrimSpace(r.URL.Query().Get("q")), "@")
+		if q == "" {
+			apierror.Validation(w, r, []apierror.FieldError{{Field: "q", Code: "required", Message: "Search query is required"}})
+			return
+		}
+		if utf8.RuneCountInString(q) > validation.MaxUsernameLength {
+			apierror.Validation(w, r, []apierror.FieldError{{Field: "q", Code: "too_long", Message: "Search query must be at most 32 characters"}})
+			return
+		}
+
+		limit := defaultSearchLimit
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > maxSearchLimit {
+				apierror.Validation(w, r, []apierror.FieldError{{Field: "limit", Code: "out_of_range", Message: "limit must be between 1 and 50"}})
+				return
+			}
+			limit = n
+		}
+
+		users, err := database.SearchUsers(db, q, limit)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to search users")
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to search users")
+			return
+		}
+
+		items := make([]UserSearchItem, 0, len(users))
+		for _, u := range users {
+			items = append(items, UserSearchItem{
+				ID:         u.ID,
+				Username:   u.Username,
+				FirstName:  u.FirstName,
+				SecondName: u.SecondName,
+				AvatarURL:  avatarURL(u.ID, u.AvatarUpdatedAt),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=30")
+		json.NewEncoder(w).Encode(items)
+	}
+}
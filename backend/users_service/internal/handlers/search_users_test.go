@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSearchUsers(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantPrefix string
+		wantTerm   string
+		wantLimit  int
+		wantStatus int
+	}{
+		{name: "префикс", query: "?q=Ali", wantPrefix: "ali%", wantTerm: "ali", wantLimit: 10, wantStatus: http.StatusOK},
+		{name: "упоминание с @", query: "?q=@bob&limit=5", wantPrefix: "bob%", wantTerm: "bob", wantLimit: 5, wantStatus: http.StatusOK},
+		{name: "подчёркивание экранируется", query: "?q=a_b", wantPrefix: `a\_b%`, wantTerm: "a_b", wantLimit: 10, wantStatus: http.StatusOK},
+		{name: "пустой запрос", query: "?q=", wantStatus: http.StatusBadRequest},
+		{name: "слишком длинный запрос", query: "?q=" + strings.Repeat("a", 33), wantStatus: http.StatusBadRequest},
+		{name: "лимит вне диапазона", query: "?q=ali&limit=500", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			if tt.wantStatus == http.StatusOK {
+				mock.ExpectQuery("SELECT u.id, u.username").
+					WithArgs(tt.wantPrefix, tt.wantTerm, tt.wantLimit).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "first_name", "second_name", "avatar_updated_at"}).
+						AddRow(1, "alice", "Alice", "", nil))
+			}
+
+			w := httptest.NewRecorder()
+			SearchUsers(db)(w, httptest.NewRequest(http.MethodGet, "/api/users/search"+tt.query, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var items []map[string]interface{}
+			if err := json.NewDecoder(w.Body).Decode(&items); err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("ожидался один результат, получено %v", items)
+			}
+			if _, ok := items[0]["email"]; ok {
+				t.Error("результат поиска не должен содержать email")
+			}
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT u.id, u.username").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "first_name", "second_name", "avatar_updated_at"}).
+			AddRow(1, "alice", "Alice", "", nil).
+			AddRow(2, "bob", "", "", nil))
+
+	w := httptest.NewRecorder()
+	ListUsers(db)(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ожидался код %d, получен %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	var items []map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("ожидалось два пользователя, получено %v", items)
+	}
+	for _, item := range items {
+		if _, ok := item["email"]; ok {
+			t.Errorf("список пользователей не должен содержать email: %v", item)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// PersonalAccessTokenPrefix отличает персональные токены от JWT в заголовке Authorization
+const PersonalAccessTokenPrefix = "blog_pat_"
+
+// maxTokenNameLength — максимальная длина имени токена
+const maxTokenNameLength = 100
+
+// AllowedTokenScopes — права, которые можно выдать персональному токену
+var AllowedTokenScopes = map[string]bool{
+	"posts:read":  true,
+	"posts:write": true,
+	"likes:write": true,
+}
+
+// CreateTokenRequest представляет запрос на создание персонального токена
+type CreateTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreatePersonalAccessToken создаёт персональный токен. Секрет возвращается только в этом ответе.
+func CreatePersonalAccessToken(db *sql.DB) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		var req CreateTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Name) > maxTokenNameLength {
+			apierror.Error(w, r, http.StatusBadRequest, "Token name is required and must be at most 100 characters")
+			return
+		}
+
+		if len(req.Scopes) == 0 {
+			apierror.Error(w, r, http.StatusBadRequest, "At least one scope is required")
+			return
+		}
+		scopes := make([]string, 0, len(req.Scopes))
+		seen := map[string]bool{}
+		for _, scope := range req.Scopes {
+			if !AllowedTokenScopes[scope] {
+				apierror.Error(w, r, http.StatusBadRequest, "Unknown scope: "+scope)
+				return
+			}
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			apierror.Error(w, r, http.StatusBadRequest, "Expiration must be in the future")
+			return
+		}
+
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to generate token")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to generate token")
+			return
+		}
+		secret := PersonalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+		sum := sha256.Sum256([]byte(secret))
+		prefix := secret[:len(PersonalAccessTokenPrefix)+6]
+
+		token, err := database.CreatePersonalAccessToken(db, userID, req.Name, prefix, hex.EncodeToString(sum[:]), scopes, req.ExpiresAt)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to save personal access token")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to create token")
+			return
+		}
+
+		logger.WithFields(logrus.Fields{
+			"user_id":  userID,
+			"token_id": token.ID,
+			"scopes":   scopes,
+		}).Info("Users-Service: Personal access token created")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(struct {
+			*database.PersonalAccessToken
+			Token string `json:"token"`
+		}{token, secret})
+	}
+}
+
+// ListPersonalAccessTokens возвращает токены пользователя без секретов
+func ListPersonalAccessTokens(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		tokens, err := database.ListPersonalAccessTokens(db, userID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to list tokens")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	}
+}
+
+// RevokePersonalAccessToken отзывает персональный токен пользователя
+func RevokePersonalAccessToken(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		userID, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		tokenID, err := strconv.Atoi(vars["tokenId"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid token ID")
+			return
+		}
+
+		revoked, err := database.RevokePersonalAccessToken(db, userID, tokenID)
+		if err != nil {
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to revoke token")
+			return
+		}
+		if !revoked {
+			apierror.Error(w, r, http.StatusNotFound, "Token not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Token revoked successfully"})
+	}
+}
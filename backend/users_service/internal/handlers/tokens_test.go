@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var personalAccessTokenColumns = []string{"id", "user_id", "name", "token_prefix", "scopes", "created_at", "expires_at", "last_used_at", "revoked_at"}
+
+// capturedArg запоминает значение аргумента запроса, чтобы проверить, что именно попало в базу
+type capturedArg struct {
+	value string
+}
+
+func (c *capturedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	c.value = s
+	return ok
+}
+
+func TestCreatePersonalAccessToken(t *testing.T) {
+	s := newTestServer(t)
+	prefix, hash := &capturedArg{}, &capturedArg{}
+	s.mock.ExpectQuery("INSERT INTO personal_access_tokens").
+		WithArgs(1, "ci", prefix, hash, "posts:read posts:write", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+
+	w := s.do(http.MethodPost, "/api/users/1/tokens", s.token(t, 1, "user"), `{"name":"ci","scopes":["posts:read","posts:write"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("ожидался код 201, получен %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		ID     int    `json:"id"`
+		Prefix string `json:"prefix"`
+		Token  string `json:"token"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(resp.Token, PersonalAccessTokenPrefix) {
+		t.Errorf("токен должен начинаться с %q: %q", PersonalAccessTokenPrefix, resp.Token)
+	}
+	if !strings.HasPrefix(resp.Token, resp.Prefix) || resp.Prefix == PersonalAccessTokenPrefix {
+		t.Errorf("префикс %q должен быть началом токена и отличать его от других", resp.Prefix)
+	}
+	if prefix.value != resp.Prefix {
+		t.Errorf("в базе сохранён префикс %q, в ответе %q", prefix.value, resp.Prefix)
+	}
+	sum := sha256.Sum256([]byte(resp.Token))
+	if hash.value != hex.EncodeToString(sum[:]) {
+		t.Errorf("в базе должен храниться SHA-256 токена, сохранено %q", hash.value)
+	}
+	if strings.Contains(hash.value, resp.Token) {
+		t.Error("токен сохранён в базе в открытом виде")
+	}
+	if err := s.mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreatePersonalAccessTokenValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		callerID int
+		body     string
+		wantCode int
+	}{
+		{name: "без имени", callerID: 1, body: `{"scopes":["posts:read"]}`, wantCode: http.StatusBadRequest},
+		{name: "неизвестная область", callerID: 1, body: `{"name":"ci","scopes":["admin"]}`, wantCode: http.StatusBadRequest},
+		{name: "срок в прошлом", callerID: 1, body: `{"name":"ci","scopes":["posts:read"],"expires_at":"2000-01-01T00:00:00Z"}`, wantCode: http.StatusBadRequest},
+		{name: "токен для чужого аккаунта", callerID: 2, body: `{"name":"ci","scopes":["posts:read"]}`, wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			w := s.do(http.MethodPost, "/api/users/1/tokens", s.token(t, tt.callerID, "user"), tt.body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestListPersonalAccessTokens(t *testing.T) {
+	s := newTestServer(t)
+	s.mock.ExpectQuery("SELECT (.+) FROM personal_access_tokens").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(personalAccessTokenColumns).
+			AddRow(7, 1, "ci", "blog_pat_abcdef", "posts:read posts:write", time.Now(), nil, nil, nil))
+
+	w := s.do(http.MethodGet, "/api/users/1/tokens", s.token(t, 1, "user"), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("ожидался код 200, получен %d: %s", w.Code, w.Body.String())
+	}
+	var resp []map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) != 1 {
+		t.Fatalf("ожидался один токен, получено %d", len(resp))
+	}
+	if _, ok := resp[0]["token"]; ok {
+		t.Error("список не должен содержать секрет токена")
+	}
+	if scopes, _ := resp[0]["scopes"].([]interface{}); len(scopes) != 2 {
+		t.Errorf("ожидались две области, получено %v", resp[0]["scopes"])
+	}
+	if err := s.mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRevokePersonalAccessToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		callerID  int
+		path      string
+		tokenID   int
+		expectSQL bool
+		revoked   int64
+		wantCode  int
+	}{
+		{name: "свой токен", callerID: 1, path: "/api/users/1/tokens/7", tokenID: 7, expectSQL: true, revoked: 1, wantCode: http.StatusOK},
+		// Токен 8 принадлежит другому пользователю: запрос ограничен user_id, поэтому строка не обновляется
+		{name: "чужой токен по своему адресу", callerID: 1, path: "/api/users/1/tokens/8", tokenID: 8, expectSQL: true, wantCode: http.StatusNotFound},
+		{name: "токен чужого аккаунта", callerID: 1, path: "/api/users/2/tokens/8", wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.expectSQL {
+				s.mock.ExpectExec("UPDATE personal_access_tokens").
+					WithArgs(tt.tokenID, tt.callerID).
+					WillReturnResult(sqlmock.NewResult(0, tt.revoked))
+			}
+
+			w := s.do(http.MethodDelete, tt.path, s.token(t, tt.callerID, "user"), "")
+			if w.Code != tt.wantCode {
+				t.Fatalf("ожидался код %d, получен %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
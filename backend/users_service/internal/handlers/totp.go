@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+	"users_service/internal/totp"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recoveryCodesCount — количество кодов восстановления, выдаваемых при подключении 2FA
+const recoveryCodesCount = 10
+
+const (
+	// maxTOTPAttempts — сколько неверных кодов подряд допускается при входе
+	maxTOTPAttempts = 5
+	// totpLockout — на сколько блокируется проверка кодов после maxTOTPAttempts ошибок.
+	// Блокировка дольше жизни токена второго шага, поэтому текущий токен становится бесполезен
+	// и подбор приходится начинать со входа по паролю.
+	totpLockout = 15 * time.Minute
+)
+
+// TOTPCodeRequest представляет запрос с одноразовым кодом
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// EnrollTOTP генерирует новый секрет TOTP и возвращает otpauth:// ссылку с названием сервиса issuer
+func EnrollTOTP(db *sql.DB, issuer string) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		user, err := database.GetUserByID(db, userID)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to fetch user")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch user")
+			return
+		}
+		if user == nil {
+			apierror.Error(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+		if user.TOTPEnabled {
+			apierror.Error(w, r, http.StatusConflict, "Two-factor authentication is already enabled")
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to generate TOTP secret")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to generate secret")
+			return
+		}
+
+		if err := database.SaveTOTPSecret(db, userID, secret); err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to save TOTP secret")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to save secret")
+			return
+		}
+
+		logger.WithField("user_id", userID).Info("Users-Service: TOTP enrollment started")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"secret":      secret,
+			"otpauth_uri": totp.URI(issuer, user.Email, secret),
+		})
+	}
+}
+
+// ConfirmTOTP подтверждает подключение 2FA первым кодом и выдаёт коды восстановления
+func ConfirmTOTP(db *sql.DB) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		var req TOTPCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			apierror.Error(w, r, http.StatusBadRequest, "Code is required")
+			return
+		}
+
+		settings, err := database.GetTOTP(db, userID)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to fetch TOTP settings")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch TOTP settings")
+			return
+		}
+		if settings == nil {
+			apierror.Error(w, r, http.StatusNotFound, "Two-factor enrollment not started")
+			return
+		}
+		if settings.Enabled {
+			apierror.Error(w, r, http.StatusConflict, "Two-factor authentication is already enabled")
+			return
+		}
+
+		step, ok := totp.Validate(settings.Secret, req.Code, time.Now())
+		if !ok {
+			logger.WithField("user_id", userID).Warn("Users-Service: Invalid TOTP confirmation code")
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid code")
+			return
+		}
+
+		codes, err := totp.GenerateRecoveryCodes(recoveryCodesCount)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to generate recovery codes")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to generate recovery codes")
+			return
+		}
+
+		hashes := make([]string, 0, len(codes))
+		for _, code := range codes {
+			hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+			if err != nil {
+				logger.WithError(err).Error("Users-Service: Failed to hash recovery code")
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to generate recovery codes")
+				return
+			}
+			hashes = append(hashes, string(hash))
+		}
+
+		if err := database.EnableTOTP(db, userID, step, hashes); err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to enable TOTP")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+			return
+		}
+
+		logger.WithField("user_id", userID).Info("Users-Service: TOTP enabled")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":        "Two-factor authentication enabled",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// VerifyTOTP проверяет код TOTP или код восстановления при входе.
+// После maxTOTPAttempts неверных кодов подряд проверка блокируется на totpLockout.
+func VerifyTOTP(db *sql.DB) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		var req TOTPCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			apierror.Error(w, r, http.StatusBadRequest, "Code is required")
+			return
+		}
+
+		settings, err := database.GetTOTP(db, userID)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Failed to fetch TOTP settings")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to fetch TOTP settings")
+			return
+		}
+		if settings == nil || !settings.Enabled {
+			apierror.Error(w, r, http.StatusConflict, "Two-factor authentication is not enabled")
+			return
+		}
+		if settings.LockedUntil != nil && settings.LockedUntil.After(time.Now()) {
+			logger.WithField("user_id", userID).Warn("Users-Service: Second factor verification is locked")
+			tooManyAttempts(w, r, *settings.LockedUntil)
+			return
+		}
+
+		method := ""
+		if step, ok := totp.Validate(settings.Secret, req.Code, time.Now()); ok {
+			fresh, err := database.UpdateTOTPStep(db, userID, step)
+			if err != nil {
+				logger.WithError(err).Error("Users-Service: Failed to update TOTP step")
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to verify code")
+				return
+			}
+			if fresh {
+				method = "totp"
+			}
+		} else {
+			method, err = verifyRecoveryCode(db, userID, req.Code)
+			if err != nil {
+				logger.WithError(err).Error("Users-Service: Failed to verify recovery code")
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to verify code")
+				return
+			}
+		}
+
+		if method == "" {
+			lockUntil := time.Now().Add(totpLockout)
+			locked, err := database.RecordTOTPFailure(db, userID, maxTOTPAttempts, lockUntil)
+			if err != nil {
+				logger.WithError(err).Error("Users-Service: Failed to record invalid second factor")
+				apierror.Error(w, r, http.StatusInternalServerError, "Failed to verify code")
+				return
+			}
+			if locked {
+				logger.WithField("user_id", userID).Warn("Users-Service: Too many invalid second factor codes, verification locked")
+				tooManyAttempts(w, r, lockUntil)
+				return
+			}
+			logger.WithField("user_id", userID).Warn("Users-Service: Invalid second factor")
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid code")
+			return
+		}
+		if settings.FailedAttempts > 0 {
+			if err := database.ResetTOTPFailures(db, userID); err != nil {
+				logger.WithError(err).Error("Users-Service: Failed to reset second factor failures")
+			}
+		}
+
+		logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"method":  method,
+		}).Info("Users-Service: Second factor verified")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"valid":  true,
+			"method": method,
+		})
+	}
+}
+
+// tooManyAttempts отвечает 429 с временем, когда проверку кодов можно повторить
+func tooManyAttempts(w http.ResponseWriter, r *http.Request, lockedUntil time.Time) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(lockedUntil).Seconds()+0.999)))
+	apierror.Error(w, r, http.StatusTooManyRequests, "Too many invalid codes, try again later")
+}
+
+// verifyRecoveryCode ищет совпадающий неиспользованный код восстановления и гасит его
+func verifyRecoveryCode(db *sql.DB, userID int, code string) (string, error) {
+	code = totp.NormalizeRecoveryCode(code)
+
+	codes, err := database.GetUnusedRecoveryCodes(db, userID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, c := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(c.CodeHash), []byte(code)) != nil {
+			continue
+		}
+		used, err := database.MarkRecoveryCodeUsed(db, c.ID)
+		if err != nil {
+			return "", err
+		}
+		if used {
+			return "recovery_code", nil
+		}
+		return "", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+func TestVerifyTOTPAttemptLimit(t *testing.T) {
+	totpColumns := []string{"user_id", "secret", "enabled", "last_used_step", "failed_attempts", "locked_until"}
+
+	tests := []struct {
+		name        string
+		lockedUntil interface{}
+		attempts    int
+		wantLock    bool
+		wantStatus  int
+	}{
+		{name: "неверный код", attempts: 1, wantStatus: http.StatusUnauthorized},
+		{name: "последняя допустимая ошибка блокирует проверку", attempts: maxTOTPAttempts, wantLock: true, wantStatus: http.StatusTooManyRequests},
+		{name: "проверка заблокирована", lockedUntil: time.Now().Add(time.Minute), wantStatus: http.StatusTooManyRequests},
+		{name: "блокировка истекла", lockedUntil: time.Now().Add(-time.Minute), attempts: 1, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery("SELECT user_id, secret, enabled").WithArgs(1).
+				WillReturnRows(sqlmock.NewRows(totpColumns).AddRow(1, "JBSWY3DPEHPK3PXP", true, 0, 0, tt.lockedUntil))
+			if tt.attempts > 0 {
+				mock.ExpectQuery("SELECT id, code_hash").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "code_hash"}))
+				mock.ExpectQuery(regexp.QuoteMeta("SET failed_attempts = failed_attempts + 1")).WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"failed_attempts"}).AddRow(tt.attempts))
+			}
+			if tt.wantLock {
+				mock.ExpectExec(regexp.QuoteMeta("SET failed_attempts = 0, locked_until = $2")).
+					WithArgs(1, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			r := mux.NewRouter()
+			r.HandleFunc("/internal/users/{id:[0-9]+}/totp/verify", VerifyTOTP(db)).Methods("POST")
+			req := httptest.NewRequest(http.MethodPost, "/internal/users/1/totp/verify", strings.NewReader(`{"code":"wrong-code"}`))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Error("ожидался заголовок Retry-After")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
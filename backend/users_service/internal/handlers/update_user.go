@@ -7,6 +7,10 @@ import (
 	"strconv"
 	"strings"
 
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+	"users_service/internal/validation"
+
 	"github.com/gorilla/mux"
 )
 
@@ -21,35 +25,44 @@ func UpdateUser(db *sql.DB) http.HandlerFunc {
 		userIDStr := vars["id"]
 		userID, err := strconv.Atoi(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid user ID")
 			return
 		}
 
 		var req UpdateUserRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request payload")
 			return
 		}
 
-		// Собираем динамический запрос
+		// Собираем динамический запрос, проверяя каждое переданное поле
+		var v validation.Validator
 		var setParts []string
 		var args []interface{}
 		argIndex := 1
 
 		if req.Username != nil && strings.TrimSpace(*req.Username) != "" {
+			username := strings.TrimSpace(*req.Username)
+			v.Username("username", username)
 			setParts = append(setParts, "username = $"+strconv.Itoa(argIndex))
-			args = append(args, strings.TrimSpace(*req.Username))
+			args = append(args, username)
 			argIndex++
 		}
 
 		if req.Email != nil && strings.TrimSpace(*req.Email) != "" {
+			email := strings.TrimSpace(*req.Email)
+			v.Email("email", email)
 			setParts = append(setParts, "email = $"+strconv.Itoa(argIndex))
-			args = append(args, strings.TrimSpace(*req.Email))
+			args = append(args, email)
 			argIndex++
 		}
 
+		if !v.Valid() {
+			apierror.Validation(w, r, v.Errors())
+			return
+		}
 		if len(setParts) == 0 {
-			http.Error(w, "No fields to update", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "No fields to update")
 			return
 		}
 
@@ -57,8 +70,11 @@ func UpdateUser(db *sql.DB) http.HandlerFunc {
 		args = append(args, userID)
 
 		_, err = db.Exec(query, args...)
-		if err != nil {
-			http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		if field, ok := database.UniqueViolationField(err); ok {
+			writeConflict(w, r, field)
+			return
+		} else if err != nil {
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update user")
 			return
 		}
 
@@ -7,12 +7,20 @@ import (
 	"strconv"
 	"strings"
 
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+	"users_service/internal/middlewares"
+	"users_service/internal/validation"
+
 	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// UpdatePasswordRequest представляет запрос на смену пароля.
+// CurrentPassword обязателен, когда пользователь меняет собственный пароль.
 type UpdatePasswordRequest struct {
-	NewPassword string `json:"new_password"`
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
 }
 
 func UpdateUserPassword(db *sql.DB) http.HandlerFunc {
@@ -21,31 +29,60 @@ func UpdateUserPassword(db *sql.DB) http.HandlerFunc {
 		userIDStr := vars["id"]
 		userID, err := strconv.Atoi(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid user ID")
 			return
 		}
 
 		var req UpdatePasswordRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request payload")
 			return
 		}
 
 		req.NewPassword = strings.TrimSpace(req.NewPassword)
 		if req.NewPassword == "" {
-			http.Error(w, "New password is required", http.StatusBadRequest)
+			apierror.Validation(w, r, []apierror.FieldError{{Field: "new_password", Code: "required", Message: "New password is required"}})
+			return
+		}
+
+		user, err := database.GetUserByID(db, userID)
+		if err != nil {
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch user")
+			return
+		}
+		if user == nil {
+			apierror.Write(w, r, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+			return
+		}
+
+		var v validation.Validator
+		v.Password("new_password", req.NewPassword, user.Username, user.Email)
+		if !v.Valid() {
+			apierror.Validation(w, r, v.Errors())
 			return
 		}
 
+		// Администратор может сбросить чужой пароль, владелец обязан подтвердить текущий
+		if middlewares.IsOwner(r) || !middlewares.IsAdmin(r) {
+			if req.CurrentPassword == "" {
+				apierror.Validation(w, r, []apierror.FieldError{{Field: "current_password", Code: "required", Message: "Current password is required"}})
+				return
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+				apierror.Write(w, r, http.StatusForbidden, apierror.CodeInvalidCredentials, "Current password is incorrect")
+				return
+			}
+		}
+
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 		if err != nil {
-			http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to hash password")
 			return
 		}
 
 		_, err = db.Exec("UPDATE users SET password_hash = $1 WHERE id = $2", string(hashedPassword), userID)
 		if err != nil {
-			http.Error(w, "Failed to update password", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to update password")
 			return
 		}
 
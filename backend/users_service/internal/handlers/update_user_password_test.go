@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUpdateUserPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		userID     int
+		role       string
+		noToken    bool
+		body       string
+		fetchUser  bool
+		expectSave bool
+		wantStatus int
+	}{
+		{name: "без токена", noToken: true, body: `{"current_password":"old-secret","new_password":"new-secret"}`, wantStatus: http.StatusUnauthorized},
+		{name: "чужой аккаунт", userID: 2, role: "user", body: `{"current_password":"old-secret","new_password":"new-secret"}`, wantStatus: http.StatusForbidden},
+		{name: "без текущего пароля", userID: 1, role: "user", body: `{"new_password":"new-secret"}`, fetchUser: true, wantStatus: http.StatusBadRequest},
+		{name: "неверный текущий пароль", userID: 1, role: "user", body: `{"current_password":"wrong","new_password":"new-secret"}`, fetchUser: true, wantStatus: http.StatusForbidden},
+		{name: "владелец", userID: 1, role: "user", body: `{"current_password":"old-secret","new_password":"new-secret"}`, fetchUser: true, expectSave: true, wantStatus: http.StatusOK},
+		{name: "администратор без текущего пароля", userID: 2, role: "admin", body: `{"new_password":"new-secret"}`, fetchUser: true, expectSave: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.fetchUser {
+				s.mock.ExpectQuery("SELECT users.id, users.username").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "enabled"}).
+						AddRow(1, "user", "user@example.com", string(hash), "user", false))
+			}
+			if tt.expectSave {
+				s.mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET password_hash = $1 WHERE id = $2")).
+					WithArgs(sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			token := ""
+			if !tt.noToken {
+				token = s.token(t, tt.userID, tt.role)
+			}
+			w := s.do(http.MethodPatch, "/api/users/1/password", token, tt.body)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpdateUserAuthorization(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     int
+		role       string
+		noToken    bool
+		expectSQL  bool
+		wantStatus int
+	}{
+		{name: "без токена", noToken: true, wantStatus: http.StatusUnauthorized},
+		{name: "чужой аккаунт", userID: 2, role: "user", wantStatus: http.StatusForbidden},
+		{name: "владелец", userID: 1, role: "user", expectSQL: true, wantStatus: http.StatusOK},
+		{name: "администратор", userID: 2, role: "admin", expectSQL: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.expectSQL {
+				s.mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET username = $1 WHERE id = $2")).
+					WithArgs("new_name", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			token := ""
+			if !tt.noToken {
+				token = s.token(t, tt.userID, tt.role)
+			}
+			w := s.do(http.MethodPatch, "/api/users/1", token, `{"username":"new_name"}`)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if err := s.mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestUpdateUserRejectsForgedToken(t *testing.T) {
+	s := newTestServer(t)
+	other := newTestServer(t)
+
+	w := s.do(http.MethodPatch, "/api/users/1", other.token(t, 1, "admin"), `{"username":"x"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("ожидался код %d, получен %d", http.StatusUnauthorized, w.Code)
+	}
+}
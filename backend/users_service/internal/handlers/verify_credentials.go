@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"blog/pkg/apierror"
+	"users_service/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// VerifyCredentialsRequest представляет данные для проверки учётных данных
+type VerifyCredentialsRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// VerifiedUser — данные пользователя, возвращаемые после успешной проверки пароля
+type VerifiedUser struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	Role        string `json:"role"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+}
+
+// dummyPasswordHash сравнивается с паролем, когда пользователь не найден,
+// чтобы время ответа не выдавало существование email
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// VerifyCredentials проверяет email и пароль и возвращает только идентичность пользователя.
+// Внутренний эндпоинт для auth_service: хэш пароля никогда не покидает users_service.
+func VerifyCredentials(db *sql.DB) http.HandlerFunc {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req VerifyCredentialsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithError(err).Warn("Users-Service: Failed to decode credentials payload")
+			apierror.Error(w, r, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		req.Email = strings.TrimSpace(req.Email)
+		if req.Email == "" || req.Password == "" {
+			apierror.Error(w, r, http.StatusBadRequest, "Email and password are required")
+			return
+		}
+
+		user, err := database.GetUserByEmail(db, req.Email)
+		if err != nil {
+			logger.WithError(err).Error("Users-Service: Database query failed")
+			apierror.Error(w, r, http.StatusInternalServerError, "Failed to verify credentials")
+			return
+		}
+
+		hash := dummyPasswordHash
+		if user != nil {
+			hash = []byte(user.PasswordHash)
+		}
+		if err := bcrypt.CompareHashAndPassword(hash, []byte(req.Password)); err != nil || user == nil {
+			logger.WithField("email", req.Email).Warn("Users-Service: Invalid credentials")
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid email or password")
+			return
+		}
+
+		logger.WithField("user_id", user.ID).Info("Users-Service: Credentials verified")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(VerifiedUser{
+			ID:          user.ID,
+			Username:    user.Username,
+			Email:       user.Email,
+			Role:        user.Role,
+			TOTPEnabled: user.TOTPEnabled,
+		})
+	}
+}
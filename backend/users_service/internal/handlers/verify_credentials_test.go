@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyCredentials(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		userFound  bool
+		wantStatus int
+	}{
+		{"верный пароль", `{"email":"user@example.com","password":"secret"}`, true, http.StatusOK},
+		{"неверный пароль", `{"email":"user@example.com","password":"wrong"}`, true, http.StatusUnauthorized},
+		{"неизвестный email", `{"email":"nobody@example.com","password":"secret"}`, false, http.StatusUnauthorized},
+		{"пустой пароль", `{"email":"user@example.com"}`, false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			if tt.wantStatus != http.StatusBadRequest {
+				rows := sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "enabled"})
+				if tt.userFound {
+					rows.AddRow(1, "user", "user@example.com", string(hash), "user", false)
+				}
+				mock.ExpectQuery("SELECT users.id, users.username").WillReturnRows(rows)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/internal/credentials/verify", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			VerifyCredentials(db)(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ожидался код %d, получен %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), "$2a$") || strings.Contains(w.Body.String(), "password_hash") {
+				t.Errorf("ответ не должен содержать хэш пароля: %s", w.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
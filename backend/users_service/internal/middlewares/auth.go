@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"blog/pkg/apierror"
+	"blog/pkg/jwks"
+)
+
+type ContextKey string
+
+const (
+	UserIDKey ContextKey = "user_id"
+	RoleKey   ContextKey = "role"
+	TokenKey  ContextKey = "token"
+)
+
+// RoleAdmin — роль администратора, которому разрешено управлять чужими аккаунтами
+const RoleAdmin = "admin"
+
+// AuthMiddleware проверяет JWT пользователя и кладёт его ID и роль в контекст запроса
+func AuthMiddleware(verifier *jwks.Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if tokenString == "" {
+				apierror.Error(w, r, http.StatusUnauthorized, "Authorization token missing")
+				return
+			}
+
+			userID, role, err := verifier.Verify(tokenString)
+			if err != nil {
+				log.Printf("AuthMiddleware: %v", err)
+				apierror.Error(w, r, http.StatusUnauthorized, "Invalid token")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			ctx = context.WithValue(ctx, RoleKey, role)
+			ctx = context.WithValue(ctx, TokenKey, tokenString)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// IsAdmin проверяет, что запрос выполнен администратором
+func IsAdmin(r *http.Request) bool {
+	role, _ := r.Context().Value(RoleKey).(string)
+	return role == RoleAdmin
+}
+
+// IsOwner проверяет, что ID из маршрута совпадает с ID пользователя из токена
+func IsOwner(r *http.Request) bool {
+	userID, ok := r.Context().Value(UserIDKey).(int)
+	if !ok {
+		return false
+	}
+	targetID, err := strconv.Atoi(mux.Vars(r)["id"])
+	return err == nil && targetID == userID
+}
+
+// RequireOwner пропускает запрос, только если пользователь работает со своим аккаунтом
+func RequireOwner(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(UserIDKey).(int); !ok {
+			apierror.Error(w, r, http.StatusUnauthorized, "User not authorized")
+			return
+		}
+		if !IsOwner(r) {
+			apierror.Error(w, r, http.StatusForbidden, "You are not allowed to access this account")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequireOwnerOrAdmin пропускает запрос владельца аккаунта или администратора
+func RequireOwnerOrAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(UserIDKey).(int); !ok {
+			apierror.Error(w, r, http.StatusUnauthorized, "User not authorized")
+			return
+		}
+		if !IsOwner(r) && !IsAdmin(r) {
+			apierror.Error(w, r, http.StatusForbidden, "You are not allowed to modify this account")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequireAdmin пропускает запрос только администратора
+func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(UserIDKey).(int); !ok {
+			apierror.Error(w, r, http.StatusUnauthorized, "User not authorized")
+			return
+		}
+		if !IsAdmin(r) {
+			apierror.Error(w, r, http.StatusForbidden, "Administrator role required")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,112 @@
+// Package storage абстрагирует хранение бинарных файлов (аватары, архивы)
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotFound возвращается, если объект отсутствует в хранилище
+var ErrNotFound = errors.New("object not found")
+
+// Object описывает сохранённый объект
+type Object struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage — хранилище объектов по строковому ключу вида "avatars/1/64.jpg"
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalDisk хранит объекты в каталоге на локальном диске
+type LocalDisk struct {
+	root string
+}
+
+// NewLocalDisk создаёт хранилище в каталоге root, создавая его при необходимости
+func NewLocalDisk(root string) (*LocalDisk, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &LocalDisk{root: root}, nil
+}
+
+// path преобразует ключ в путь на диске, не позволяя выйти за пределы root
+func (d *LocalDisk) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(d.root, filepath.FromSlash(clean)), nil
+}
+
+// Put атомарно записывает объект: сначала во временный файл, затем переименовывает
+func (d *LocalDisk) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+	return nil
+}
+
+// Get открывает объект для чтения
+func (d *LocalDisk) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to open object: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return f, &Object{Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Delete удаляет объект. Отсутствующий объект не считается ошибкой.
+func (d *LocalDisk) Delete(ctx context.Context, key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
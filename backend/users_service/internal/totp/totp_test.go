@@ -0,0 +1,83 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Тестовые векторы из приложения B RFC 6238 (SHA1), усечённые до 6 цифр
+func TestGenerateCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := GenerateCode(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("GenerateCode(%d) вернул ошибку: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateCode(%d) = %s, ожидалось %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAllowsSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+
+	prev, _ := GenerateCode(secret, Step(now)-1)
+	if step, ok := Validate(secret, prev, now); !ok || step != Step(now)-1 {
+		t.Errorf("код предыдущего шага должен приниматься")
+	}
+
+	old, _ := GenerateCode(secret, Step(now)-3)
+	if _, ok := Validate(secret, old, now); ok {
+		t.Errorf("устаревший код не должен приниматься")
+	}
+
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Errorf("код неверной длины не должен приниматься")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Blog", "user@example.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Blog:user@example.com?") {
+		t.Errorf("неожиданный URI: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=Blog") {
+		t.Errorf("URI не содержит обязательных параметров: %s", uri)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' {
+			t.Errorf("неожиданный формат кода: %s", c)
+		}
+		if seen[c] {
+			t.Errorf("повторяющийся код: %s", c)
+		}
+		seen[c] = true
+	}
+}
@@ -0,0 +1,140 @@
+package validation
+
+import (
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"blog/pkg/apierror"
+)
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 32
+	MaxEmailLength    = 254
+	MinPasswordLength = 8
+	// bcrypt учитывает только первые 72 байта пароля
+	MaxPasswordBytes = 72
+)
+
+// reservedUsernames совпадают с маршрутами фронтенда и служебными ролями
+var reservedUsernames = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true, "support": true,
+	"moderator": true, "api": true, "auth": true, "login": true, "logout": true,
+	"register": true, "me": true, "settings": true, "profile": true, "profiles": true,
+	"users": true, "posts": true, "search": true, "help": true, "null": true, "undefined": true,
+}
+
+// Validator накапливает ошибки по полям, чтобы вернуть их клиенту одним ответом
+type Validator struct {
+	errors []apierror.FieldError
+}
+
+// Add добавляет ошибку поля
+func (v *Validator) Add(field, code, message string) {
+	v.errors = append(v.errors, apierror.FieldError{Field: field, Code: code, Message: message})
+}
+
+// Valid сообщает, что ошибок не найдено
+func (v *Validator) Valid() bool {
+	return len(v.errors) == 0
+}
+
+// Errors возвращает накопленные ошибки
+func (v *Validator) Errors() []apierror.FieldError {
+	return v.errors
+}
+
+// Username проверяет длину, набор символов и зарезервированные имена
+func (v *Validator) Username(field, value string) {
+	length := utf8.RuneCountInString(value)
+	switch {
+	case value == "":
+		v.Add(field, "required", "Username is required")
+		return
+	case length < MinUsernameLength:
+		v.Add(field, "too_short", "Username must be at least 3 characters")
+		return
+	case length > MaxUsernameLength:
+		v.Add(field, "too_long", "Username must be at most 32 characters")
+		return
+	}
+
+	for i, r := range value {
+		if i == 0 && !isASCIILetter(r) {
+			v.Add(field, "invalid_format", "Username must start with a Latin letter")
+			return
+		}
+		if !isASCIILetter(r) && !(r >= '0' && r <= '9') && r != '_' {
+			v.Add(field, "invalid_format", "Username may contain only Latin letters, digits and underscores")
+			return
+		}
+	}
+
+	if reservedUsernames[strings.ToLower(value)] {
+		v.Add(field, "reserved", "This username is reserved")
+	}
+}
+
+// Email проверяет синтаксис адреса (без отображаемого имени)
+func (v *Validator) Email(field, value string) {
+	if value == "" {
+		v.Add(field, "required", "Email is required")
+		return
+	}
+	if len(value) > MaxEmailLength {
+		v.Add(field, "too_long", "Email must be at most 254 characters")
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || addr.Name != "" {
+		v.Add(field, "invalid_format", "Email address is invalid")
+		return
+	}
+	domain := value[strings.LastIndex(value, "@")+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		v.Add(field, "invalid_format", "Email address is invalid")
+	}
+}
+
+// Password проверяет парольную политику: длина и хотя бы одна буква и один не-буквенный символ.
+// Пароль не должен совпадать с именем пользователя или адресом почты.
+func (v *Validator) Password(field, value string, personal ...string) {
+	if value == "" {
+		v.Add(field, "required", "Password is required")
+		return
+	}
+	if utf8.RuneCountInString(value) < MinPasswordLength {
+		v.Add(field, "too_short", "Password must be at least 8 characters")
+		return
+	}
+	if len(value) > MaxPasswordBytes {
+		v.Add(field, "too_long", "Password must be at most 72 bytes")
+		return
+	}
+
+	var hasLetter, hasOther bool
+	for _, r := range value {
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		} else {
+			hasOther = true
+		}
+	}
+	if !hasLetter || !hasOther {
+		v.Add(field, "too_weak", "Password must contain letters and at least one digit or symbol")
+		return
+	}
+
+	for _, p := range personal {
+		if p != "" && strings.EqualFold(value, p) {
+			v.Add(field, "too_weak", "Password must not match your username or email")
+			return
+		}
+	}
+}
+
+func isASCIILetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
@@ -0,0 +1,85 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUsername(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantCode string
+	}{
+		{"alice", ""},
+		{"Bob_42", ""},
+		{"", "required"},
+		{"ab", "too_short"},
+		{strings.Repeat("a", MaxUsernameLength+1), "too_long"},
+		{"1alice", "invalid_format"},
+		{"alice.smith", "invalid_format"},
+		{"алиса", "invalid_format"},
+		{"ADMIN", "reserved"},
+	}
+
+	for _, tt := range tests {
+		var v Validator
+		v.Username("username", tt.value)
+		assertCode(t, "Username", tt.value, v, tt.wantCode)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantCode string
+	}{
+		{"alice@example.com", ""},
+		{"", "required"},
+		{"alice", "invalid_format"},
+		{"alice@localhost", "invalid_format"},
+		{"Alice <alice@example.com>", "invalid_format"},
+		{"alice@example.com.", "invalid_format"},
+	}
+
+	for _, tt := range tests {
+		var v Validator
+		v.Email("email", tt.value)
+		assertCode(t, "Email", tt.value, v, tt.wantCode)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantCode string
+	}{
+		{"correct-horse", ""},
+		{"пароль-2024", ""},
+		{"", "required"},
+		{"a1b2", "too_short"},
+		{strings.Repeat("a1", 40), "too_long"},
+		{"onlyletters", "too_weak"},
+		{"1234567890", "too_weak"},
+		{"alice_2024", "too_weak"},
+	}
+
+	for _, tt := range tests {
+		var v Validator
+		v.Password("password", tt.value, "alice_2024", "alice@example.com")
+		assertCode(t, "Password", tt.value, v, tt.wantCode)
+	}
+}
+
+func assertCode(t *testing.T, rule, value string, v Validator, wantCode string) {
+	t.Helper()
+	errs := v.Errors()
+	if wantCode == "" {
+		if len(errs) != 0 {
+			t.Errorf("%s(%q): неожиданная ошибка %+v", rule, value, errs)
+		}
+		return
+	}
+	if len(errs) != 1 || errs[0].Code != wantCode {
+		t.Errorf("%s(%q) = %+v, ожидался код %q", rule, value, errs, wantCode)
+	}
+}
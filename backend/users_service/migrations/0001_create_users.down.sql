@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.users_information;
+DROP TABLE IF EXISTS public.users;
@@ -0,0 +1,19 @@
+--
+-- Исходная схема пользователей (как в sql/backup). IF NOT EXISTS позволяет применить
+-- миграцию к базе, восстановленной из дампа: существующие таблицы не трогаются.
+--
+
+CREATE TABLE IF NOT EXISTS public.users (
+    id serial PRIMARY KEY,
+    username character varying(255) NOT NULL CONSTRAINT users_username_key UNIQUE,
+    email character varying(255) NOT NULL CONSTRAINT users_email_key UNIQUE,
+    password_hash character varying(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS public.users_information (
+    id serial PRIMARY KEY,
+    user_id integer CONSTRAINT users_information_user_id_fkey REFERENCES public.users(id) ON DELETE CASCADE,
+    first_name character varying(255) NOT NULL,
+    second_name character varying(255) NOT NULL,
+    birthdate date NOT NULL
+);
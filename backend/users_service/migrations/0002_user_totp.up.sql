@@ -0,0 +1,21 @@
+--
+-- Двухфакторная аутентификация (TOTP, RFC 6238)
+--
+
+CREATE TABLE IF NOT EXISTS public.user_totp (
+    user_id integer PRIMARY KEY REFERENCES public.users(id) ON DELETE CASCADE,
+    secret character varying(64) NOT NULL,
+    enabled boolean DEFAULT false NOT NULL,
+    last_used_step bigint DEFAULT 0 NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    confirmed_at timestamp without time zone
+);
+
+CREATE TABLE IF NOT EXISTS public.user_recovery_codes (
+    id serial PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    code_hash character varying(255) NOT NULL,
+    used_at timestamp without time zone
+);
+
+CREATE INDEX IF NOT EXISTS user_recovery_codes_user_id_idx ON public.user_recovery_codes (user_id);
@@ -0,0 +1,18 @@
+--
+-- Персональные токены доступа для скриптов и API
+--
+
+CREATE TABLE IF NOT EXISTS public.personal_access_tokens (
+    id serial PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    name character varying(100) NOT NULL,
+    token_prefix character varying(16) NOT NULL,
+    token_hash character(64) NOT NULL UNIQUE,
+    scopes text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    expires_at timestamp with time zone,
+    last_used_at timestamp with time zone,
+    revoked_at timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS personal_access_tokens_user_id_idx ON public.personal_access_tokens (user_id);
@@ -0,0 +1,6 @@
+--
+-- Роли пользователей (user, admin)
+--
+
+ALTER TABLE public.users
+    ADD COLUMN IF NOT EXISTS role character varying(20) DEFAULT 'user' NOT NULL;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS public.users_information_user_id_key;
+
+-- Обязательность полей не восстанавливается: частично заполненные профили её нарушили бы
+
+ALTER TABLE public.users_information
+    DROP COLUMN IF EXISTS bio,
+    DROP COLUMN IF EXISTS website,
+    DROP COLUMN IF EXISTS location;
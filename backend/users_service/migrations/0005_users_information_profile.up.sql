@@ -0,0 +1,16 @@
+--
+-- Расширенный профиль пользователя: bio, сайт и местоположение
+--
+
+ALTER TABLE public.users_information
+    ADD COLUMN IF NOT EXISTS bio text DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS website character varying(255) DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS location character varying(100) DEFAULT '' NOT NULL;
+
+-- Профиль заполняется частично, поэтому поля из исходной схемы становятся необязательными
+ALTER TABLE public.users_information ALTER COLUMN first_name DROP NOT NULL;
+ALTER TABLE public.users_information ALTER COLUMN second_name DROP NOT NULL;
+ALTER TABLE public.users_information ALTER COLUMN birthdate DROP NOT NULL;
+
+-- Один профиль на пользователя (нужно для INSERT ... ON CONFLICT (user_id))
+CREATE UNIQUE INDEX IF NOT EXISTS users_information_user_id_key ON public.users_information (user_id);
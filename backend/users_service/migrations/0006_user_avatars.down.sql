@@ -0,0 +1 @@
+ALTER TABLE public.users DROP COLUMN IF EXISTS avatar_updated_at;
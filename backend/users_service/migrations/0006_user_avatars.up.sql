@@ -0,0 +1,2 @@
+-- Время загрузки аватара пользователя; используется как версия для URL и кэша
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS avatar_updated_at timestamptz;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS public.users_username_trgm_idx;
+DROP INDEX IF EXISTS public.users_username_lower_prefix_idx;
+
+-- Расширение pg_trgm не удаляется: им могут пользоваться другие объекты базы
@@ -0,0 +1,11 @@
+--
+-- Поиск пользователей по имени: префикс и триграммное сходство
+--
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Префиксный поиск по lower(username) LIKE 'abc%'
+CREATE INDEX IF NOT EXISTS users_username_lower_prefix_idx ON public.users (lower(username) text_pattern_ops);
+
+-- Нечёткий поиск по оператору % и similarity()
+CREATE INDEX IF NOT EXISTS users_username_trgm_idx ON public.users USING gin (lower(username) gin_trgm_ops);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.event_deliveries;
+DROP TABLE IF EXISTS public.event_outbox;
+ALTER TABLE public.users DROP COLUMN IF EXISTS deleted_at;
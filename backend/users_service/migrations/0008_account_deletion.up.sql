@@ -0,0 +1,31 @@
+--
+-- Удаление аккаунта через событие user.deleted (transactional outbox)
+--
+
+-- Аккаунт в процессе удаления: данные обезличены, строка удаляется после обработки события всеми потребителями
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS public.event_outbox (
+    id bigserial PRIMARY KEY,
+    event_type character varying(64) NOT NULL,
+    -- Без внешнего ключа: событие и ход его обработки переживают удаление пользователя
+    user_id integer NOT NULL,
+    payload jsonb NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS event_outbox_user_id_idx ON public.event_outbox (user_id, event_type);
+
+-- Доставка события каждому потребителю с повторами
+CREATE TABLE IF NOT EXISTS public.event_deliveries (
+    event_id bigint NOT NULL REFERENCES public.event_outbox(id) ON DELETE CASCADE,
+    consumer character varying(64) NOT NULL,
+    status character varying(16) DEFAULT 'pending' NOT NULL CHECK (status IN ('pending', 'done', 'failed')),
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text,
+    next_attempt_at timestamptz DEFAULT now() NOT NULL,
+    completed_at timestamptz,
+    PRIMARY KEY (event_id, consumer)
+);
+
+CREATE INDEX IF NOT EXISTS event_deliveries_due_idx ON public.event_deliveries (next_attempt_at) WHERE status = 'pending';
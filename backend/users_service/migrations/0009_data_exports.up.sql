@@ -0,0 +1,21 @@
+--
+-- Выгрузка персональных данных пользователя (ZIP-архив)
+--
+
+CREATE TABLE IF NOT EXISTS public.data_exports (
+    id character varying(32) PRIMARY KEY,
+    -- Без внешнего ключа: архивы удалённого аккаунта удаляет фоновая очистка
+    user_id integer NOT NULL,
+    status character varying(16) DEFAULT 'pending' NOT NULL CHECK (status IN ('pending', 'running', 'ready', 'failed')),
+    error text,
+    storage_key text,
+    size_bytes bigint,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    started_at timestamptz,
+    completed_at timestamptz,
+    -- После этого момента архив удаляется и ссылка на скачивание перестаёт работать
+    expires_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_id_idx ON public.data_exports (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS data_exports_pending_idx ON public.data_exports (created_at) WHERE status = 'pending';
@@ -0,0 +1,19 @@
+--
+-- Отношения между пользователями: блокировка, скрытие (mute) и подписка (follow)
+--
+
+CREATE TABLE IF NOT EXISTS public.user_relations (
+    user_id integer NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    target_id integer NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    kind character varying(16) NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    PRIMARY KEY (user_id, target_id, kind),
+    CONSTRAINT user_relations_not_self CHECK (user_id <> target_id)
+);
+
+-- Ограничение пересоздаётся, чтобы базы, где таблица создана до появления подписок, тоже приняли 'follow'
+ALTER TABLE public.user_relations DROP CONSTRAINT IF EXISTS user_relations_kind_check;
+ALTER TABLE public.user_relations ADD CONSTRAINT user_relations_kind_check CHECK (kind IN ('block', 'mute', 'follow'));
+
+-- Проверка «заблокировал ли автор читателя» в posts_service
+CREATE INDEX IF NOT EXISTS user_relations_target_idx ON public.user_relations (target_id, kind);
@@ -0,0 +1,2 @@
+ALTER TABLE public.user_totp DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE public.user_totp DROP COLUMN IF EXISTS failed_attempts;
@@ -0,0 +1,6 @@
+--
+-- Ограничение попыток ввода второго фактора: после серии неверных кодов проверка блокируется
+--
+
+ALTER TABLE public.user_totp ADD COLUMN IF NOT EXISTS failed_attempts integer DEFAULT 0 NOT NULL;
+ALTER TABLE public.user_totp ADD COLUMN IF NOT EXISTS locked_until timestamp without time zone;
@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"blog/pkg/migrate"
+	"users_service/internal/database"
+)
+
+// TestMigrations проверяет, что миграции сервиса собираются без пропусков версий
+func TestMigrations(t *testing.T) {
+	for name, fsys := range map[string]fs.FS{"postgres": FS, "sqlite": SQLite} {
+		t.Run(name, func(t *testing.T) {
+			list, err := migrate.Load(fsys)
+			if err != nil {
+				t.Fatalf("Миграции не загрузились: %v", err)
+			}
+			if len(list) == 0 {
+				t.Fatal("Миграций нет")
+			}
+			for i, m := range list {
+				if m.Version != int64(i+1) {
+					t.Fatalf("Ожидалась версия %d, получено %d (%s)", i+1, m.Version, m.Name)
+				}
+			}
+		})
+	}
+}
+
+// TestSQLiteMigrations применяет и откатывает миграции SQLite на настоящей базе
+func TestSQLiteMigrations(t *testing.T) {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "blog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	list, err := migrate.Load(SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &migrate.Migrator{DB: db, Service: "users-service", Migrations: list, SQLite: true}
+	ctx := context.Background()
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Миграции не применились: %v", err)
+	}
+	// Повторный запуск ничего не делает
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Повторный up завершился ошибкой: %v", err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("Миграция %04d не применена", s.Version)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO users (username, email, password_hash) VALUES ('alice', 'alice@example.com', 'x')"); err != nil {
+		t.Fatalf("Схема не принимает пользователя: %v", err)
+	}
+
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatalf("Миграции не откатились: %v", err)
+	}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&tables); err != nil || tables != 0 {
+		t.Errorf("Таблица users осталась после отката: %d, %v", tables, err)
+	}
+}
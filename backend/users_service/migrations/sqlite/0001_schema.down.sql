@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS user_relations;
+DROP TABLE IF EXISTS data_exports;
+DROP TABLE IF EXISTS event_deliveries;
+DROP TABLE IF EXISTS event_outbox;
+DROP TABLE IF EXISTS personal_access_tokens;
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
+DROP TABLE IF EXISTS users_information;
+DROP TABLE IF EXISTS users;
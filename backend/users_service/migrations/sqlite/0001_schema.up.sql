@@ -0,0 +1,117 @@
+--
+-- Схема users_service для SQLite (DB_DRIVER=sqlite): та же, что дают миграции Postgres 0001–0010.
+-- Время хранится строкой в UTC в формате драйвера ('2006-01-02 15:04:05.999+00:00'),
+-- поэтому значения по умолчанию задаются через strftime, а сравнения работают как строковые.
+-- Новая миграция Postgres добавляется и сюда, следующим номером.
+--
+
+-- AUTOINCREMENT не даёт повторно выдать ID удалённого пользователя, как serial в Postgres
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username VARCHAR(255) NOT NULL CONSTRAINT users_username_key UNIQUE,
+    email VARCHAR(255) NOT NULL CONSTRAINT users_email_key UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    role VARCHAR(20) DEFAULT 'user' NOT NULL,
+    avatar_updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+-- Поиск по префиксу lower(username); триграммного поиска в SQLite нет
+CREATE INDEX users_username_lower_idx ON users (lower(username));
+
+CREATE TABLE users_information (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER CONSTRAINT users_information_user_id_fkey REFERENCES users(id) ON DELETE CASCADE,
+    first_name VARCHAR(255),
+    second_name VARCHAR(255),
+    birthdate DATE,
+    bio TEXT DEFAULT '' NOT NULL,
+    website VARCHAR(255) DEFAULT '' NOT NULL,
+    location VARCHAR(100) DEFAULT '' NOT NULL
+);
+
+CREATE UNIQUE INDEX users_information_user_id_key ON users_information (user_id);
+
+CREATE TABLE user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN DEFAULT FALSE NOT NULL,
+    last_used_step BIGINT DEFAULT 0 NOT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')) NOT NULL,
+    confirmed_at TIMESTAMP
+);
+
+CREATE TABLE user_recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(255) NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX user_recovery_codes_user_id_idx ON user_recovery_codes (user_id);
+
+CREATE TABLE personal_access_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL,
+    token_hash CHARACTER(64) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')) NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX personal_access_tokens_user_id_idx ON personal_access_tokens (user_id);
+
+-- Без внешнего ключа: событие и ход его обработки переживают удаление пользователя
+CREATE TABLE event_outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_type VARCHAR(64) NOT NULL,
+    user_id INTEGER NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')) NOT NULL
+);
+
+CREATE INDEX event_outbox_user_id_idx ON event_outbox (user_id, event_type);
+
+CREATE TABLE event_deliveries (
+    event_id INTEGER NOT NULL REFERENCES event_outbox(id) ON DELETE CASCADE,
+    consumer VARCHAR(64) NOT NULL,
+    status VARCHAR(16) DEFAULT 'pending' NOT NULL CHECK (status IN ('pending', 'done', 'failed')),
+    attempts INTEGER DEFAULT 0 NOT NULL,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')) NOT NULL,
+    completed_at TIMESTAMP,
+    PRIMARY KEY (event_id, consumer)
+);
+
+CREATE INDEX event_deliveries_due_idx ON event_deliveries (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE data_exports (
+    id VARCHAR(32) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    status VARCHAR(16) DEFAULT 'pending' NOT NULL CHECK (status IN ('pending', 'running', 'ready', 'failed')),
+    error TEXT,
+    storage_key TEXT,
+    size_bytes BIGINT,
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')) NOT NULL,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id, created_at DESC);
+CREATE INDEX data_exports_pending_idx ON data_exports (created_at) WHERE status = 'pending';
+
+CREATE TABLE user_relations (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CONSTRAINT user_relations_kind_check CHECK (kind IN ('block', 'mute', 'follow')),
+    created_at TIMESTAMP DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')) NOT NULL,
+    PRIMARY KEY (user_id, target_id, kind),
+    CONSTRAINT user_relations_not_self CHECK (user_id <> target_id)
+);
+
+CREATE INDEX user_relations_target_idx ON user_relations (target_id, kind);
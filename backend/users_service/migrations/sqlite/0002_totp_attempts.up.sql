@@ -0,0 +1,6 @@
+--
+-- Ограничение попыток ввода второго фактора: после серии неверных кодов проверка блокируется
+--
+
+ALTER TABLE user_totp ADD COLUMN failed_attempts INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE user_totp ADD COLUMN locked_until TIMESTAMP;
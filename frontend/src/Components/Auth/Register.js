@@ -36,7 +36,16 @@ const Register = () => {
       // Перенаправляем на главную страницу
       navigate('/');
     } catch (error) {
-      setError('Не удалось зарегистрироваться или войти. Пожалуйста, попробуйте снова.');
+      // Сервер возвращает ошибки в формате application/problem+json:
+      // { type, title, status, detail, code, request_id, fields: [{ field, code, message }] }
+      const details = error.response?.data;
+      if (details?.fields?.length) {
+        setError(details.fields.map((f) => f.message).join('. '));
+      } else if (details?.detail || details?.title) {
+        setError(details.detail || details.title);
+      } else {
+        setError('Не удалось зарегистрироваться или войти. Пожалуйста, попробуйте снова.');
+      }
       console.error('Ошибка при регистрации или входе:', error);
     } finally {
       setLoading(false);
@@ -5,6 +5,7 @@ import { createPost } from '../../api/api'; // Импортируем функц
 const NewPost = ({ onPostCreated }) => {
   const [title, setTitle] = useState('');
   const [content, setContent] = useState('');
+  const [visibility, setVisibility] = useState('public');
   const [errorMessage, setErrorMessage] = useState('');
   const [successMessage, setSuccessMessage] = useState('');
 
@@ -16,12 +17,13 @@ const NewPost = ({ onPostCreated }) => {
     }
 
     try {
-      const response = await createPost(title, content); // Отправляем данные на сервер
+      const response = await createPost(title, content, visibility); // Отправляем данные на сервер
       setErrorMessage('');
       setSuccessMessage('Post created successfully!');
       onPostCreated(response.data); // Передаём созданный пост родительскому компоненту
       setTitle('');
       setContent('');
+      setVisibility('public');
     } catch (error) {
       console.error('Failed to create post:', error);
       setErrorMessage('Failed to create post. Please try again later.');
@@ -46,6 +48,16 @@ const NewPost = ({ onPostCreated }) => {
             value={content}
             onChange={(e) => setContent(e.target.value)}
           />
+          <select
+            className="new-post-input"
+            value={visibility}
+            onChange={(e) => setVisibility(e.target.value)}
+          >
+            <option value="public">Public</option>
+            <option value="unlisted">Unlisted (link only)</option>
+            <option value="followers">Followers only</option>
+            <option value="private">Only me</option>
+          </select>
           <button type="submit" className="new-post-button">
             Submit
           </button>
@@ -15,6 +15,13 @@ function getAuthHeaders() {
   return { Authorization: `Bearer ${token}` };
 }
 
+// Для маршрутов, доступных анонимно: без токена запрос уходит без заголовка
+function getOptionalAuthHeaders() {
+  const token = localStorage.getItem('token');
+
+  return token ? { Authorization: `Bearer ${token}` } : {};
+}
+
 export const login = async (email, password) => {
   return axios.post(`${AUTH_API_URL}/login`, { email, password });
 };
@@ -24,15 +31,15 @@ export const register = async (username, email, password) => {
 };
 
 export const fetchPosts = async () => {
-  const headers = getAuthHeaders();
+  const headers = getOptionalAuthHeaders();
 
   return axios.get(`${POSTS_API_URL}/posts`, { headers });
 };
 
-export const createPost = async (title, content) => {
+export const createPost = async (title, content, visibility = 'public') => {
   const headers = getAuthHeaders();
 
-  return axios.post(`${POSTS_API_URL}/posts`, { title, content }, { headers });
+  return axios.post(`${POSTS_API_URL}/posts`, { title, content, visibility }, { headers });
 };
 
 export const deletePost = async (postId) => {
@@ -42,13 +49,13 @@ export const deletePost = async (postId) => {
 };
 
 export const fetchPostById = async (postId) => {
-  const headers = getAuthHeaders();
+  const headers = getOptionalAuthHeaders();
 
   return axios.get(`${POSTS_API_URL}/posts/${postId}`, { headers });
 };
 
 export const fetchUserPosts = async (username) => {
-  const headers = getAuthHeaders();
+  const headers = getOptionalAuthHeaders();
 
   return axios.get(`${POSTS_API_URL}/profile/${username}/posts`, { headers });
 };
@@ -75,7 +82,7 @@ export const toggleLike = async (postId, userId, liked) => {
 };
 
 export const fetchLikes = async (postId) => {
-  const headers = getAuthHeaders();
+  const headers = getOptionalAuthHeaders();
 
   const response = await axios.get(`${POSTS_API_URL}/likes?postId=${postId}`, { headers });
 
@@ -3,6 +3,12 @@ import react from '@vitejs/plugin-react';
 
 export default defineConfig({
   plugins: [react()],
+  server: {
+    // Все запросы /api идут через gateway_service
+    proxy: {
+      '/api': process.env.GATEWAY_URL || 'http://localhost:8080',
+    },
+  },
   esbuild: {
     loader: 'jsx',
     include: /src\/.*\.js$/, // Позволяет JSX в .js файлах